@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"order-service/internal/kafka"
+	"order-service/pkg/config"
+	"os"
+
+	"github.com/sirupsen/logrus"
+)
+
+// runDLQ выполняет команды для работы с dead-letter топиком
+func runDLQ(ctx context.Context, cfg *config.Config, logger *logrus.Logger, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("не указана подкоманда: ordersctl dlq <list|show|replay>")
+	}
+	if cfg.Kafka.DLQTopic == "" {
+		return fmt.Errorf("dead-letter топик не настроен (KAFKA_DLQ_TOPIC)")
+	}
+
+	reader := kafka.NewDeadLetterReader(&cfg.Kafka)
+
+	switch args[0] {
+	case "list":
+		fs := flag.NewFlagSet("dlq list", flag.ExitOnError)
+		limit := fs.Int("limit", 50, "максимальное число сообщений из каждой партиции")
+		fs.Parse(args[1:])
+
+		letters, err := reader.List(ctx, *limit)
+		if err != nil {
+			return err
+		}
+		return printJSON(letters)
+
+	case "show":
+		fs := flag.NewFlagSet("dlq show", flag.ExitOnError)
+		partition := fs.Int("partition", 0, "партиция dead-letter топика")
+		offset := fs.Int64("offset", -1, "offset сообщения в dead-letter топике")
+		fs.Parse(args[1:])
+		if *offset < 0 {
+			return fmt.Errorf("необходимо указать -offset")
+		}
+
+		letter, err := reader.Get(ctx, *partition, *offset)
+		if err != nil {
+			return err
+		}
+		return printJSON(letter)
+
+	case "replay":
+		fs := flag.NewFlagSet("dlq replay", flag.ExitOnError)
+		partition := fs.Int("partition", 0, "партиция dead-letter топика")
+		offset := fs.Int64("offset", -1, "offset сообщения в dead-letter топике")
+		patchFile := fs.String("patch", "", "файл с JSON Merge Patch для исходного сообщения")
+		payloadFile := fs.String("payload", "", "файл с исправленным сообщением целиком")
+		dryRun := fs.Bool("dry-run", false, "только проверить сообщение, не сохраняя его")
+		fs.Parse(args[1:])
+		if *offset < 0 {
+			return fmt.Errorf("необходимо указать -offset")
+		}
+
+		req := kafka.ReplayRequest{DryRun: *dryRun}
+		if *patchFile != "" {
+			data, err := os.ReadFile(*patchFile)
+			if err != nil {
+				return fmt.Errorf("не удалось прочитать патч: %w", err)
+			}
+			req.Patch = data
+		}
+		if *payloadFile != "" {
+			data, err := os.ReadFile(*payloadFile)
+			if err != nil {
+				return fmt.Errorf("не удалось прочитать сообщение: %w", err)
+			}
+			req.Payload = data
+		}
+
+		processor, db, err := newOrderProcessor(cfg, logger)
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		replayer := kafka.NewReplayer(reader, processor, logger)
+
+		result, err := replayer.Replay(ctx, *partition, *offset, req)
+		if err != nil {
+			return err
+		}
+		return printJSON(result)
+
+	default:
+		return fmt.Errorf("неизвестная подкоманда dlq: %s", args[0])
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"order-service/internal/database"
+	"order-service/internal/export"
+	"order-service/pkg/config"
+	"os"
+
+	"github.com/sirupsen/logrus"
+)
+
+// runExport выгружает заказы в CSV, NDJSON или Parquet тем же путем, что и GET /api/v1/orders/export
+func runExport(ctx context.Context, cfg *config.Config, logger *logrus.Logger, args []string) error {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	formatStr := fs.String("format", string(export.FormatNDJSON), "формат выгрузки: csv, ndjson или parquet")
+	out := fs.String("out", "-", "файл для выгрузки (- для stdout)")
+	from := fs.String("from", "", "начало периода по date_created (RFC 3339 или YYYY-MM-DD)")
+	to := fs.String("to", "", "конец периода по date_created, не включается (дата YYYY-MM-DD включает весь день)")
+	customerID := fs.String("customer", "", "ID клиента")
+	deliveryService := fs.String("delivery-service", "", "служба доставки")
+	fs.Parse(args)
+
+	format, err := export.ParseFormat(*formatStr)
+	if err != nil {
+		return err
+	}
+	filter, err := database.ParseOrderFilter(*from, *to, *customerID, *deliveryService)
+	if err != nil {
+		return err
+	}
+
+	db, err := database.NewPostgresDB(&cfg.Database, logger)
+	if err != nil {
+		return fmt.Errorf("не удалось подключиться к базе данных: %w", err)
+	}
+	defer db.Close()
+
+	var output io.Writer = os.Stdout
+	if *out != "-" {
+		f, err := os.Create(*out)
+		if err != nil {
+			return fmt.Errorf("не удалось создать файл: %w", err)
+		}
+		defer f.Close()
+		output = f
+	}
+	buffered := bufio.NewWriter(output)
+
+	count, err := export.Export(ctx, db, filter, format, buffered)
+	if err != nil {
+		return err
+	}
+	if err := buffered.Flush(); err != nil {
+		return fmt.Errorf("не удалось записать выгрузку: %w", err)
+	}
+
+	fmt.Fprintf(os.Stderr, "Выгружено заказов: %d\n", count)
+	return nil
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"order-service/internal/kafka"
+	"order-service/pkg/config"
+	"os"
+	"strconv"
+
+	"github.com/sirupsen/logrus"
+)
+
+// runImport импортирует заказы из NDJSON файла тем же путем, что и POST /api/v1/orders/import.
+// Результат каждой строки выводится в stdout в формате NDJSON, итог - последней строкой
+func runImport(ctx context.Context, cfg *config.Config, logger *logrus.Logger, args []string) error {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	file := fs.String("file", "", "NDJSON файл с заказами (- для stdin)")
+	batchSize := fs.Int("batch-size", kafka.DefaultImportBatchSize, "число заказов в одной транзакции")
+	schemaVersion := fs.Int("schema-version", 0, "версия схемы заказов, если она не указана в самих заказах")
+	failedOnly := fs.Bool("failed-only", false, "выводить только строки, которые не удалось импортировать")
+	fs.Parse(args)
+	if *file == "" {
+		return fmt.Errorf("необходимо указать -file")
+	}
+
+	var input io.Reader = os.Stdin
+	if *file != "-" {
+		f, err := os.Open(*file)
+		if err != nil {
+			return fmt.Errorf("не удалось открыть файл: %w", err)
+		}
+		defer f.Close()
+		input = f
+	}
+
+	headers := map[string]string{}
+	if *schemaVersion > 0 {
+		headers[kafka.HeaderSchemaVersion] = strconv.Itoa(*schemaVersion)
+	}
+
+	processor, db, err := newOrderProcessor(cfg, logger)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	encoder := json.NewEncoder(os.Stdout)
+	importer := kafka.NewImporter(processor, *batchSize, logger)
+	summary, err := importer.Import(ctx, input, headers, func(result kafka.ImportLineResult) error {
+		if *failedOnly && !result.Failed() {
+			return nil
+		}
+		return encoder.Encode(result)
+	})
+	if err != nil {
+		return err
+	}
+	return encoder.Encode(map[string]interface{}{"summary": summary})
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"order-service/internal/cache"
+	"order-service/internal/database"
+	"order-service/internal/kafka"
+	"order-service/internal/validation"
+	"order-service/pkg/config"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/joho/godotenv"
+	"github.com/sirupsen/logrus"
+)
+
+const usage = `ordersctl - утилита администрирования Order Service
+
+Использование:
+  ordersctl <команда> [аргументы]
+
+Команды:
+  dlq list          Показать последние сообщения из dead-letter топика
+  dlq show          Показать сообщение из dead-letter топика
+  dlq replay        Повторно обработать сообщение из dead-letter топика
+  import            Импортировать заказы из NDJSON файла
+  export            Выгрузить заказы в CSV, NDJSON или Parquet
+  rollups backfill  Пересчитать дневные агрегаты аналитики по истории заказов
+
+Подробнее о флагах: ordersctl <команда> -h
+`
+
+func main() {
+	// Загружаем .env файл (игнорируем ошибку если файл не найден)
+	_ = godotenv.Load()
+
+	// Логи пишем в stderr, чтобы не смешивать их с результатом команды
+	logger := logrus.New()
+	logger.SetOutput(os.Stderr)
+	logger.SetLevel(logrus.WarnLevel)
+	if os.Getenv("DEBUG") == "true" {
+		logger.SetLevel(logrus.DebugLevel)
+	}
+
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Ошибка конфигурации: %v\n", err)
+		os.Exit(1)
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	switch os.Args[1] {
+	case "dlq":
+		err = runDLQ(ctx, cfg, logger, os.Args[2:])
+	case "import":
+		err = runImport(ctx, cfg, logger, os.Args[2:])
+	case "export":
+		err = runExport(ctx, cfg, logger, os.Args[2:])
+	case "rollups":
+		err = runRollups(ctx, cfg, logger, os.Args[2:])
+	case "help", "-h", "--help":
+		fmt.Fprint(os.Stdout, usage)
+		return
+	default:
+		fmt.Fprintf(os.Stderr, "Неизвестная команда: %s\n\n%s", os.Args[1], usage)
+		os.Exit(2)
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Ошибка: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// printJSON выводит результат команды в stdout в формате JSON
+func printJSON(data interface{}) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(data)
+}
+
+// newOrderProcessor подключается к БД и создает обработчик заказов с настройками сервиса.
+// Кеш CLI живет только в рамках команды, сервис подтянет заказ из БД при первом запросе.
+// Соединение с БД закрывает вызывающий код
+func newOrderProcessor(cfg *config.Config, logger *logrus.Logger) (*kafka.OrderProcessor, *database.PostgresDB, error) {
+	conflictPolicy, err := database.ParseConflictPolicy(cfg.Ingest.ConflictPolicy)
+	if err != nil {
+		return nil, nil, err
+	}
+	schemas, err := kafka.NewSchemaRegistry(cfg.Kafka.ContentType)
+	if err != nil {
+		return nil, nil, err
+	}
+	validator, err := validation.NewValidator(cfg.Validation.DefaultPolicy, cfg.Validation.Rules)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	db, err := database.NewPostgresDB(&cfg.Database, logger)
+	if err != nil {
+		return nil, nil, fmt.Errorf("не удалось подключиться к базе данных: %w", err)
+	}
+
+	processor := kafka.NewOrderProcessor(db, cache.NewMemoryCache(cfg.Cache.MaxSize, logger), schemas, validator, conflictPolicy, logger)
+	return processor, db, nil
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"order-service/internal/database"
+	"order-service/pkg/config"
+	"os"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// runRollups управляет дневными агрегатами аналитики
+func runRollups(ctx context.Context, cfg *config.Config, logger *logrus.Logger, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("не указана подкоманда: ordersctl rollups <backfill>")
+	}
+
+	switch args[0] {
+	case "backfill":
+		return runRollupsBackfill(ctx, cfg, logger, args[1:])
+	default:
+		return fmt.Errorf("неизвестная подкоманда rollups: %s", args[0])
+	}
+}
+
+// runRollupsBackfill пересчитывает дневные агрегаты по сохраненным заказам
+func runRollupsBackfill(ctx context.Context, cfg *config.Config, logger *logrus.Logger, args []string) error {
+	fs := flag.NewFlagSet("rollups backfill", flag.ExitOnError)
+	from := fs.String("from", "", "первый пересчитываемый день (YYYY-MM-DD); по умолчанию - вся история")
+	to := fs.String("to", "", "последний пересчитываемый день (YYYY-MM-DD), включается")
+	fs.Parse(args)
+
+	period, err := database.ParseOrderFilter(*from, *to, "", "")
+	if err != nil {
+		return err
+	}
+
+	db, err := database.NewPostgresDB(&cfg.Database, logger)
+	if err != nil {
+		return fmt.Errorf("не удалось подключиться к базе данных: %w", err)
+	}
+	defer db.Close()
+
+	started := time.Now()
+	if err := db.RebuildRollups(ctx, period.DateFrom, period.DateTo); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "Агрегаты пересчитаны за %s\n", time.Since(started).Round(time.Millisecond))
+	return nil
+}
@@ -5,9 +5,11 @@ import (
 	"fmt"
 	"net/http"
 	"order-service/internal/cache"
+	"order-service/internal/currency"
 	"order-service/internal/database"
 	"order-service/internal/handlers"
 	"order-service/internal/kafka"
+	"order-service/internal/validation"
 	"order-service/pkg/config"
 	"os"
 	"os/signal"
@@ -34,7 +36,10 @@ func main() {
 
 	logger.Info("Starting Order Service")
 
-	cfg := config.LoadConfig()
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		logger.WithError(err).Fatal("Invalid configuration")
+	}
 	logger.WithFields(logrus.Fields{
 		"server_port": cfg.Server.Port,
 		"db_host":     cfg.Database.Host,
@@ -57,8 +62,52 @@ func main() {
 		// Не прерываем запуск, кеш будет заполняться по мере поступления запросов
 	}
 
+	conflictPolicy, err := database.ParseConflictPolicy(cfg.Ingest.ConflictPolicy)
+	if err != nil {
+		logger.WithError(err).Fatal("Invalid ORDER_CONFLICT_POLICY")
+	}
+
+	if err := cfg.Kafka.ValidateRetry(); err != nil {
+		logger.WithError(err).Fatal("Invalid Kafka retry configuration")
+	}
+
+	schemas, err := kafka.NewSchemaRegistry(cfg.Kafka.ContentType)
+	if err != nil {
+		logger.WithError(err).Fatal("Invalid KAFKA_CONTENT_TYPE")
+	}
+
+	validator, err := validation.NewValidator(cfg.Validation.DefaultPolicy, cfg.Validation.Rules)
+	if err != nil {
+		logger.WithError(err).Fatal("Invalid validation policy configuration")
+	}
+
+	// Общий путь валидации и сохранения заказов
+	processor := kafka.NewOrderProcessor(db, orderCache, schemas, validator, conflictPolicy, logger)
+
+	kafkaEnabled := os.Getenv("DISABLE_KAFKA") != "true"
+
+	// Повторная обработка сообщений из DLQ доступна только при включенной Kafka
+	var replayer *kafka.Replayer
+	if kafkaEnabled && cfg.Kafka.DLQTopic != "" {
+		replayer = kafka.NewReplayer(kafka.NewDeadLetterReader(&cfg.Kafka), processor, logger)
+	}
+
+	// Курсы валют для отчетов: из файла, если он задан, иначе из таблицы exchange_rates
+	var rateSource currency.RateSource = db
+	if cfg.Currency.RatesFile != "" {
+		rateSource = currency.NewFileSource(cfg.Currency.RatesFile)
+	}
+	rates, err := currency.NewRates(cfg.Currency.Base, rateSource, logger)
+	if err != nil {
+		logger.WithError(err).Fatal("Invalid EXCHANGE_RATES_BASE")
+	}
+	if err := rates.Load(context.Background()); err != nil {
+		// Не прерываем запуск: отчеты без перевода валют работают, курсы подтянутся при следующем обновлении
+		logger.WithError(err).Error("Failed to load exchange rates")
+	}
+
 	// Создаем HTTP handler
-	httpHandler := handlers.NewHTTPHandler(db, orderCache, logger)
+	httpHandler := handlers.NewHTTPHandler(db, orderCache, processor, replayer, rates, logger)
 	router := httpHandler.SetupRoutes()
 
 	// Создаем и запускаем Kafka consumer (если не отключен)
@@ -66,8 +115,10 @@ func main() {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	if os.Getenv("DISABLE_KAFKA") != "true" {
-		consumer = kafka.NewConsumer(&cfg.Kafka, db, orderCache, logger)
+	go rates.Run(ctx, cfg.Currency.RefreshInterval)
+
+	if kafkaEnabled {
+		consumer = kafka.NewConsumer(&cfg.Kafka, processor, logger)
 		if err := consumer.Start(ctx); err != nil {
 			logger.WithError(err).Error("Failed to start Kafka consumer - continuing without Kafka")
 		}
@@ -92,6 +143,26 @@ func main() {
 		}
 	}()
 
+	// Административный API слушает отдельный адрес, чтобы его не было на публичном порту с CORS
+	var adminServer *http.Server
+	if cfg.Server.AdminAddr != "" {
+		adminServer = &http.Server{
+			Addr:         cfg.Server.AdminAddr,
+			Handler:      httpHandler.SetupAdminRoutes(),
+			ReadTimeout:  15 * time.Second,
+			WriteTimeout: 15 * time.Second,
+			IdleTimeout:  60 * time.Second,
+		}
+		go func() {
+			logger.WithField("address", adminServer.Addr).Info("Starting admin HTTP server")
+			if err := adminServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				logger.WithError(err).Fatal("Admin HTTP server failed")
+			}
+		}()
+	} else {
+		logger.Info("Admin API disabled by empty ADMIN_ADDR")
+	}
+
 	// Ожидание сигнала для graceful shutdown
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
@@ -113,6 +184,11 @@ func main() {
 	if err := server.Shutdown(shutdownCtx); err != nil {
 		logger.WithError(err).Error("Failed to shutdown HTTP server gracefully")
 	}
+	if adminServer != nil {
+		if err := adminServer.Shutdown(shutdownCtx); err != nil {
+			logger.WithError(err).Error("Failed to shutdown admin HTTP server gracefully")
+		}
+	}
 
 	logger.Info("Order Service stopped")
 }
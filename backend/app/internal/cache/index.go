@@ -0,0 +1,119 @@
+package cache
+
+import (
+	"order-service/internal/models"
+	"time"
+)
+
+// trackCompleteTTL - сколько набор заказов трек-номера считается полным после загрузки из БД.
+// Заказы, сохраненные другими процессами (ordersctl import, ordersctl dlq replay, другая реплика
+// сервиса), в этот кеш не попадают, поэтому набор периодически перечитывается из БД
+const trackCompleteTTL = time.Minute
+
+// completeTrack - трек-номер, все заказы которого находятся в кеше
+type completeTrack struct {
+	limit   int       // Сколько заказов максимум возвращает БД: набор такого размера может быть неполным
+	expires time.Time // Когда набор нужно перечитать из БД
+}
+
+// secondaryIndex хранит вторичные ключи заказов, находящихся в кеше.
+// Не потокобезопасен, используется под мьютексом MemoryCache
+type secondaryIndex struct {
+	byTrack       map[string]map[string]struct{} // track_number -> order_uid
+	byTransaction map[string]string              // payment.transaction -> order_uid
+	// Трек-номера, все заказы которых находятся в кеше
+	completeTracks map[string]completeTrack
+	now            func() time.Time
+}
+
+func newSecondaryIndex() *secondaryIndex {
+	return &secondaryIndex{
+		byTrack:        make(map[string]map[string]struct{}),
+		byTransaction:  make(map[string]string),
+		completeTracks: make(map[string]completeTrack),
+		now:            time.Now,
+	}
+}
+
+// add индексирует заказ. Новый заказ с "полным" трек-номером сохраняет полноту,
+// так как тоже попадает в кеш, пока заказов меньше, чем возвращает БД
+func (s *secondaryIndex) add(order *models.OrderFull) {
+	if order == nil {
+		return
+	}
+	if order.TrackNumber != "" {
+		uids, ok := s.byTrack[order.TrackNumber]
+		if !ok {
+			uids = make(map[string]struct{})
+			s.byTrack[order.TrackNumber] = uids
+		}
+		uids[order.OrderUID] = struct{}{}
+		if track, ok := s.completeTracks[order.TrackNumber]; ok && len(uids) >= track.limit {
+			delete(s.completeTracks, order.TrackNumber)
+		}
+	}
+	if order.Payment != nil && order.Payment.Transaction != "" {
+		s.byTransaction[order.Payment.Transaction] = order.OrderUID
+	}
+}
+
+// remove убирает заказ из индекса. Трек-номер, потерявший заказ, перестает быть полным
+func (s *secondaryIndex) remove(order *models.OrderFull) {
+	if order == nil {
+		return
+	}
+	if uids, ok := s.byTrack[order.TrackNumber]; ok {
+		delete(uids, order.OrderUID)
+		if len(uids) == 0 {
+			delete(s.byTrack, order.TrackNumber)
+		}
+		delete(s.completeTracks, order.TrackNumber)
+	}
+	if order.Payment != nil && s.byTransaction[order.Payment.Transaction] == order.OrderUID {
+		delete(s.byTransaction, order.Payment.Transaction)
+	}
+}
+
+// replace переиндексирует обновленный заказ. Полнота трек-номера сохраняется, если он не изменился
+func (s *secondaryIndex) replace(old, order *models.OrderFull) {
+	var track completeTrack
+	complete := false
+	if old != nil && order != nil && old.TrackNumber == order.TrackNumber {
+		track, complete = s.completeTracks[old.TrackNumber]
+	}
+	s.remove(old)
+	s.add(order)
+	if complete {
+		s.completeTracks[order.TrackNumber] = track
+	}
+}
+
+// markComplete отмечает, что в кеше все заказы трек-номера. limit - сколько заказов
+// максимум возвращает БД: набор из limit заказов и больше может быть обрезан
+func (s *secondaryIndex) markComplete(trackNumber string, limit int) {
+	if uids, ok := s.byTrack[trackNumber]; ok && len(uids) < limit {
+		s.completeTracks[trackNumber] = completeTrack{limit: limit, expires: s.now().Add(trackCompleteTTL)}
+	}
+}
+
+// trackNumber возвращает UID заказов с трек-номером и признак того, что в кеше все такие заказы
+func (s *secondaryIndex) trackNumber(trackNumber string) ([]string, bool) {
+	track, ok := s.completeTracks[trackNumber]
+	if !ok {
+		return nil, false
+	}
+	if !s.now().Before(track.expires) {
+		delete(s.completeTracks, trackNumber)
+		return nil, false
+	}
+	uids := make([]string, 0, len(s.byTrack[trackNumber]))
+	for orderUID := range s.byTrack[trackNumber] {
+		uids = append(uids, orderUID)
+	}
+	return uids, true
+}
+
+func (s *secondaryIndex) transaction(transaction string) (string, bool) {
+	orderUID, ok := s.byTransaction[transaction]
+	return orderUID, ok
+}
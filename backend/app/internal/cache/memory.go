@@ -3,6 +3,7 @@ package cache
 import (
 	"container/list"
 	"order-service/internal/models"
+	"sort"
 	"sync"
 
 	"github.com/sirupsen/logrus"
@@ -14,6 +15,7 @@ type MemoryCache struct {
 	capacity int
 	cache    map[string]*list.Element
 	lru      *list.List
+	index    *secondaryIndex // Поиск по трек-номеру и транзакции платежа
 	logger   *logrus.Logger
 }
 
@@ -25,6 +27,10 @@ type cacheItem struct {
 type OrderCache interface {
 	Get(orderUID string) (*models.OrderFull, bool)
 	Set(orderUID string, order *models.OrderFull)
+	Delete(orderUID string)
+	GetByTrackNumber(trackNumber string) ([]*models.OrderFull, bool)
+	SetTrackNumberOrders(trackNumber string, orders []*models.OrderFull, limit int)
+	GetByTransaction(transaction string) (*models.OrderFull, bool)
 	GetStats() CacheStats
 	Clear()
 	LoadFromDB(orders []models.OrderFull)
@@ -43,6 +49,7 @@ func NewMemoryCache(capacity int, logger *logrus.Logger) *MemoryCache {
 		capacity: capacity,
 		cache:    make(map[string]*list.Element),
 		lru:      list.New(),
+		index:    newSecondaryIndex(),
 		logger:   logger,
 	}
 }
@@ -69,10 +76,16 @@ func (c *MemoryCache) Set(orderUID string, order *models.OrderFull) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
+	c.setLocked(orderUID, order)
+}
+
+// setLocked добавляет или обновляет заказ и его вторичные ключи. Вызывается под c.mu
+func (c *MemoryCache) setLocked(orderUID string, order *models.OrderFull) {
 	// Если элемент уже существует, обновляем его
 	if elem, exists := c.cache[orderUID]; exists {
 		c.lru.MoveToFront(elem)
 		item := elem.Value.(*cacheItem)
+		c.index.replace(item.order, order)
 		item.order = order
 		c.logger.WithField("order_uid", orderUID).Debug("Cache updated")
 		return
@@ -85,6 +98,7 @@ func (c *MemoryCache) Set(orderUID string, order *models.OrderFull) {
 	}
 	elem := c.lru.PushFront(item)
 	c.cache[orderUID] = elem
+	c.index.add(order)
 
 	// Если превышена емкость, удаляем самый старый элемент
 	if c.lru.Len() > c.capacity {
@@ -94,6 +108,83 @@ func (c *MemoryCache) Set(orderUID string, order *models.OrderFull) {
 	c.logger.WithField("order_uid", orderUID).Debug("Cache set")
 }
 
+// Delete удаляет заказ из кеша
+func (c *MemoryCache) Delete(orderUID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, exists := c.cache[orderUID]; exists {
+		c.lru.Remove(elem)
+		delete(c.cache, orderUID)
+		c.index.remove(elem.Value.(*cacheItem).order)
+		c.logger.WithField("order_uid", orderUID).Debug("Cache deleted")
+	}
+}
+
+// GetByTrackNumber возвращает заказы с трек-номером, если в кеше есть все такие заказы.
+// Полнота известна только для трек-номеров, загруженных через SetTrackNumberOrders не раньше
+// trackCompleteTTL назад и с тех пор не потерявших ни одного заказа из-за вытеснения или удаления
+func (c *MemoryCache) GetByTrackNumber(trackNumber string) ([]*models.OrderFull, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	uids, complete := c.index.trackNumber(trackNumber)
+	if !complete {
+		c.logger.WithField("track_number", trackNumber).Debug("Cache miss")
+		return nil, false
+	}
+
+	orders := make([]*models.OrderFull, 0, len(uids))
+	for _, orderUID := range uids {
+		elem := c.cache[orderUID]
+		c.lru.MoveToFront(elem)
+		orders = append(orders, elem.Value.(*cacheItem).order)
+	}
+	// Тот же порядок, что и при чтении из БД: от новых к старым
+	sort.Slice(orders, func(i, j int) bool {
+		if !orders[i].CreatedAt.Equal(orders[j].CreatedAt) {
+			return orders[i].CreatedAt.After(orders[j].CreatedAt)
+		}
+		return orders[i].OrderUID > orders[j].OrderUID
+	})
+	c.logger.WithField("track_number", trackNumber).Debug("Cache hit")
+	return orders, true
+}
+
+// SetTrackNumberOrders кеширует заказы с трек-номером, найденные в БД, чтобы следующие запросы
+// по этому трек-номеру обслуживались из кеша. limit - сколько заказов максимум возвращает БД:
+// если найдено столько же, набор мог быть обрезан и полным не считается
+func (c *MemoryCache) SetTrackNumberOrders(trackNumber string, orders []*models.OrderFull, limit int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Больше заказов, чем помещается в кеш, полным набором не закешировать
+	if len(orders) == 0 || len(orders) > c.capacity {
+		return
+	}
+	for _, order := range orders {
+		c.setLocked(order.OrderUID, order)
+	}
+	c.index.markComplete(trackNumber, limit)
+}
+
+// GetByTransaction возвращает заказ по ID транзакции платежа
+func (c *MemoryCache) GetByTransaction(transaction string) (*models.OrderFull, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	orderUID, ok := c.index.transaction(transaction)
+	if !ok {
+		c.logger.WithField("transaction", transaction).Debug("Cache miss")
+		return nil, false
+	}
+
+	elem := c.cache[orderUID]
+	c.lru.MoveToFront(elem)
+	c.logger.WithField("transaction", transaction).Debug("Cache hit")
+	return elem.Value.(*cacheItem).order, true
+}
+
 // evictOldest удаляет самый старый элемент из кеша
 func (c *MemoryCache) evictOldest() {
 	elem := c.lru.Back()
@@ -101,6 +192,7 @@ func (c *MemoryCache) evictOldest() {
 		c.lru.Remove(elem)
 		item := elem.Value.(*cacheItem)
 		delete(c.cache, item.key)
+		c.index.remove(item.order)
 		c.logger.WithField("evicted_order_uid", item.key).Debug("Cache evicted oldest")
 	}
 }
@@ -123,6 +215,7 @@ func (c *MemoryCache) Clear() {
 
 	c.cache = make(map[string]*list.Element)
 	c.lru = list.New()
+	c.index = newSecondaryIndex()
 	c.logger.Info("Cache cleared")
 }
 
@@ -132,17 +225,13 @@ func (c *MemoryCache) LoadFromDB(orders []models.OrderFull) {
 	defer c.mu.Unlock()
 
 	count := 0
-	for _, order := range orders {
+	for i := range orders {
 		if count >= c.capacity {
 			break
 		}
 
-		item := &cacheItem{
-			key:   order.OrderUID,
-			order: &order,
-		}
-		elem := c.lru.PushFront(item)
-		c.cache[order.OrderUID] = elem
+		// Берем адрес элемента среза, а не переменной цикла, общей для всех итераций
+		c.setLocked(orders[i].OrderUID, &orders[i])
 		count++
 	}
 
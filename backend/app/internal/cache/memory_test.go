@@ -0,0 +1,216 @@
+package cache
+
+import (
+	"io"
+	"testing"
+	"time"
+
+	"order-service/internal/models"
+
+	"github.com/sirupsen/logrus"
+)
+
+// testTrackLimit - лимит заказов по трек-номеру, с которым читает БД
+const testTrackLimit = 100
+
+func newTestCache(capacity int) *MemoryCache {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	return NewMemoryCache(capacity, logger)
+}
+
+func testOrder(orderUID, trackNumber, transaction string, createdAt time.Time) *models.OrderFull {
+	return &models.OrderFull{
+		Order:   models.Order{OrderUID: orderUID, TrackNumber: trackNumber, CreatedAt: createdAt},
+		Payment: &models.Payment{Transaction: transaction},
+	}
+}
+
+func trackUIDs(t *testing.T, c *MemoryCache, trackNumber string) []string {
+	t.Helper()
+	orders, ok := c.GetByTrackNumber(trackNumber)
+	if !ok {
+		return nil
+	}
+	uids := make([]string, len(orders))
+	for i, order := range orders {
+		uids[i] = order.OrderUID
+	}
+	return uids
+}
+
+func equalUIDs(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestGetByTrackNumberRequiresCompleteTrack(t *testing.T) {
+	c := newTestCache(10)
+	base := time.Date(2021, 11, 26, 10, 0, 0, 0, time.UTC)
+
+	// Заказы, попавшие в кеш по одному, не дают полного набора по трек-номеру
+	c.Set("a", testOrder("a", "WB1", "tx-a", base))
+	if _, ok := c.GetByTrackNumber("WB1"); ok {
+		t.Fatal("GetByTrackNumber() hit before SetTrackNumberOrders")
+	}
+
+	c.SetTrackNumberOrders("WB1", []*models.OrderFull{
+		testOrder("a", "WB1", "tx-a", base),
+		testOrder("b", "WB1", "tx-b", base.Add(time.Hour)),
+	}, testTrackLimit)
+	if got, want := trackUIDs(t, c, "WB1"), []string{"b", "a"}; !equalUIDs(got, want) {
+		t.Errorf("GetByTrackNumber() = %v, want %v", got, want)
+	}
+}
+
+func TestGetByTrackNumberKeepsNewOrderFirst(t *testing.T) {
+	c := newTestCache(10)
+	base := time.Date(2021, 11, 26, 10, 0, 0, 0, time.UTC)
+	c.SetTrackNumberOrders("WB1", []*models.OrderFull{
+		testOrder("b", "WB1", "tx-b", base.Add(time.Hour)),
+		testOrder("a", "WB1", "tx-a", base),
+	}, testTrackLimit)
+
+	// Новый заказ с тем же трек-номером, сохраненный после загрузки, сохраняет полноту
+	// и, как и в БД, идет первым по времени записи
+	c.Set("c", testOrder("c", "WB1", "tx-c", base.Add(2*time.Hour)))
+	if got, want := trackUIDs(t, c, "WB1"), []string{"c", "b", "a"}; !equalUIDs(got, want) {
+		t.Errorf("GetByTrackNumber() = %v, want %v", got, want)
+	}
+
+	// Одинаковое время упорядочивается по order_uid по убыванию
+	c.Set("d", testOrder("d", "WB1", "tx-d", base.Add(2*time.Hour)))
+	if got, want := trackUIDs(t, c, "WB1"), []string{"d", "c", "b", "a"}; !equalUIDs(got, want) {
+		t.Errorf("GetByTrackNumber() = %v, want %v", got, want)
+	}
+}
+
+func TestTrackNumberLosesCompleteness(t *testing.T) {
+	base := time.Date(2021, 11, 26, 10, 0, 0, 0, time.UTC)
+	load := func(c *MemoryCache) {
+		c.SetTrackNumberOrders("WB1", []*models.OrderFull{
+			testOrder("a", "WB1", "tx-a", base),
+			testOrder("b", "WB1", "tx-b", base.Add(time.Hour)),
+		}, testTrackLimit)
+	}
+
+	t.Run("delete", func(t *testing.T) {
+		c := newTestCache(10)
+		load(c)
+		c.Delete("a")
+		if _, ok := c.GetByTrackNumber("WB1"); ok {
+			t.Error("GetByTrackNumber() hit after deleting one of the orders")
+		}
+	})
+
+	t.Run("eviction", func(t *testing.T) {
+		c := newTestCache(2)
+		load(c)
+		c.Set("x", testOrder("x", "WB2", "tx-x", base))
+		if _, ok := c.GetByTrackNumber("WB1"); ok {
+			t.Error("GetByTrackNumber() hit after one of the orders was evicted")
+		}
+	})
+
+	t.Run("track number changed", func(t *testing.T) {
+		c := newTestCache(10)
+		load(c)
+		c.Set("a", testOrder("a", "WB2", "tx-a", base))
+		if _, ok := c.GetByTrackNumber("WB1"); ok {
+			t.Error("GetByTrackNumber() hit after an order moved to another track number")
+		}
+		if _, ok := c.GetByTrackNumber("WB2"); ok {
+			t.Error("GetByTrackNumber() hit for a track number that was never loaded completely")
+		}
+	})
+
+	t.Run("same track number updated", func(t *testing.T) {
+		c := newTestCache(10)
+		load(c)
+		c.Set("a", testOrder("a", "WB1", "tx-a2", base))
+		if got, want := trackUIDs(t, c, "WB1"), []string{"b", "a"}; !equalUIDs(got, want) {
+			t.Errorf("GetByTrackNumber() = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("too many orders", func(t *testing.T) {
+		c := newTestCache(1)
+		load(c)
+		if _, ok := c.GetByTrackNumber("WB1"); ok {
+			t.Error("GetByTrackNumber() hit for a track number larger than the cache")
+		}
+	})
+}
+
+func TestTrackNumberLimit(t *testing.T) {
+	base := time.Date(2021, 11, 26, 10, 0, 0, 0, time.UTC)
+	orders := []*models.OrderFull{
+		testOrder("a", "WB1", "tx-a", base),
+		testOrder("b", "WB1", "tx-b", base.Add(time.Hour)),
+	}
+
+	// БД вернула столько заказов, сколько позволяет лимит: остальные могли не попасть в ответ
+	c := newTestCache(10)
+	c.SetTrackNumberOrders("WB1", orders, 2)
+	if _, ok := c.GetByTrackNumber("WB1"); ok {
+		t.Error("GetByTrackNumber() hit for a track number truncated by the limit")
+	}
+
+	// Набор, выросший до лимита, из кеша уже не совпадет с ответом БД
+	c = newTestCache(10)
+	c.SetTrackNumberOrders("WB1", orders, 3)
+	if _, ok := c.GetByTrackNumber("WB1"); !ok {
+		t.Fatal("GetByTrackNumber() missed a track number below the limit")
+	}
+	c.Set("c", testOrder("c", "WB1", "tx-c", base.Add(2*time.Hour)))
+	if _, ok := c.GetByTrackNumber("WB1"); ok {
+		t.Error("GetByTrackNumber() hit after the track number reached the limit")
+	}
+}
+
+func TestTrackNumberCompletenessExpires(t *testing.T) {
+	base := time.Date(2021, 11, 26, 10, 0, 0, 0, time.UTC)
+	now := base
+	c := newTestCache(10)
+	c.index.now = func() time.Time { return now }
+
+	c.SetTrackNumberOrders("WB1", []*models.OrderFull{testOrder("a", "WB1", "tx-a", base)}, testTrackLimit)
+	now = base.Add(trackCompleteTTL - time.Second)
+	if _, ok := c.GetByTrackNumber("WB1"); !ok {
+		t.Fatal("GetByTrackNumber() missed before the TTL expired")
+	}
+
+	// Заказы, сохраненные другими процессами, в кеш не попадают: набор перечитывается из БД
+	now = base.Add(trackCompleteTTL)
+	if _, ok := c.GetByTrackNumber("WB1"); ok {
+		t.Error("GetByTrackNumber() hit after the TTL expired")
+	}
+	if _, ok := c.Get("a"); !ok {
+		t.Error("Get() missed the order after the track number expired")
+	}
+}
+
+func TestGetByTransaction(t *testing.T) {
+	c := newTestCache(10)
+	c.Set("a", testOrder("a", "WB1", "tx-a", time.Now()))
+
+	if order, ok := c.GetByTransaction("tx-a"); !ok || order.OrderUID != "a" {
+		t.Errorf("GetByTransaction() = %v, %v, want order a", order, ok)
+	}
+
+	// Перезаписанный заказ со сменой транзакции больше не находится по старой
+	c.Set("a", testOrder("a", "WB1", "tx-a2", time.Now()))
+	if _, ok := c.GetByTransaction("tx-a"); ok {
+		t.Error("GetByTransaction() found order by stale transaction")
+	}
+	if _, ok := c.GetByTransaction("tx-a2"); !ok {
+		t.Error("GetByTransaction() missed order by new transaction")
+	}
+}
@@ -0,0 +1,41 @@
+package currency
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestExponent(t *testing.T) {
+	tests := []struct {
+		code    string
+		want    int
+		wantErr error
+	}{
+		{code: "RUB", want: 2},
+		{code: "USD", want: 2},
+		{code: "JPY", want: 0},
+		{code: "KRW", want: 0},
+		{code: "KWD", want: 3},
+		{code: "CLF", want: 4},
+		{code: "XAU", want: 0},
+		{code: "usd", wantErr: ErrUnknownCurrency},
+		{code: "XYZ", wantErr: ErrUnknownCurrency},
+		{code: "", wantErr: ErrUnknownCurrency},
+	}
+	for _, tt := range tests {
+		got, err := Exponent(tt.code)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("Exponent(%q) error = %v, want %v", tt.code, err, tt.wantErr)
+			continue
+		}
+		if err == nil && got != tt.want {
+			t.Errorf("Exponent(%q) = %d, want %d", tt.code, got, tt.want)
+		}
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	if got := Normalize(" usd "); got != "USD" || !Known(got) {
+		t.Errorf("Normalize(%q) = %q, want known USD", " usd ", got)
+	}
+}
@@ -0,0 +1,244 @@
+package currency
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"time"
+)
+
+// ErrNoRate возвращается, если на нужную дату для валюты нет курса
+var ErrNoRate = errors.New("no exchange rate")
+
+// Rate - курс валюты к базовой валюте таблицы, действующий с даты EffectiveFrom
+// до следующего курса той же валюты: 1 единица Currency = Value единиц базовой валюты
+// (в основных единицах, например 1 USD = 92.5 RUB)
+type Rate struct {
+	Currency      string
+	Value         *big.Rat
+	EffectiveFrom time.Time
+}
+
+// RateTable - неизменяемая таблица курсов с датами начала действия
+type RateTable struct {
+	base  string
+	rates map[string][]Rate // по валюте, в порядке EffectiveFrom
+}
+
+// NewRateTable проверяет курсы и строит таблицу. Курс базовой валюты к самой себе
+// всегда равен 1 и в таблице не нужен
+func NewRateTable(base string, rates []Rate) (*RateTable, error) {
+	base = Normalize(base)
+	if !Known(base) {
+		return nil, fmt.Errorf("base currency: %w: %q", ErrUnknownCurrency, base)
+	}
+
+	table := &RateTable{base: base, rates: make(map[string][]Rate)}
+	for _, rate := range rates {
+		rate.Currency = Normalize(rate.Currency)
+		if !Known(rate.Currency) {
+			return nil, fmt.Errorf("%w: %q", ErrUnknownCurrency, rate.Currency)
+		}
+		if rate.Currency == base {
+			return nil, fmt.Errorf("rate for base currency %s is always 1", base)
+		}
+		if rate.Value == nil || rate.Value.Sign() <= 0 {
+			return nil, fmt.Errorf("rate for %s from %s must be positive", rate.Currency, rate.EffectiveFrom.Format(dateLayout))
+		}
+		rate.EffectiveFrom = day(rate.EffectiveFrom)
+		table.rates[rate.Currency] = append(table.rates[rate.Currency], rate)
+	}
+
+	for code, list := range table.rates {
+		sort.Slice(list, func(i, j int) bool { return list[i].EffectiveFrom.Before(list[j].EffectiveFrom) })
+		for i := 1; i < len(list); i++ {
+			if list[i].EffectiveFrom.Equal(list[i-1].EffectiveFrom) {
+				return nil, fmt.Errorf("duplicate rate for %s from %s", code, list[i].EffectiveFrom.Format(dateLayout))
+			}
+		}
+	}
+	return table, nil
+}
+
+// Base возвращает базовую валюту таблицы
+func (t *RateTable) Base() string {
+	return t.base
+}
+
+// Rates возвращает все курсы таблицы по валютам и датам
+func (t *RateTable) Rates() []Rate {
+	codes := make([]string, 0, len(t.rates))
+	for code := range t.rates {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+
+	var rates []Rate
+	for _, code := range codes {
+		rates = append(rates, t.rates[code]...)
+	}
+	return rates
+}
+
+// Factor возвращает множитель, переводящий сумму в минимальных единицах from
+// в минимальные единицы to по курсам, действующим в день date
+func (t *RateTable) Factor(from, to string, date time.Time) (*big.Rat, error) {
+	fromExponent, err := Exponent(from)
+	if err != nil {
+		return nil, err
+	}
+	toExponent, err := Exponent(to)
+	if err != nil {
+		return nil, err
+	}
+	if from == to {
+		return big.NewRat(1, 1), nil
+	}
+
+	fromRate, err := t.rate(from, date)
+	if err != nil {
+		return nil, err
+	}
+	toRate, err := t.rate(to, date)
+	if err != nil {
+		return nil, err
+	}
+
+	// amount / 10^fromExponent * fromRate / toRate * 10^toExponent
+	factor := new(big.Rat).Quo(fromRate, toRate)
+	factor.Mul(factor, new(big.Rat).SetFrac(pow10(toExponent), pow10(fromExponent)))
+	return factor, nil
+}
+
+// Convert переводит сумму в минимальных единицах from в минимальные единицы to
+// по курсам дня date с округлением половины от нуля
+func (t *RateTable) Convert(amount int64, from, to string, date time.Time) (int64, error) {
+	factor, err := t.Factor(from, to, date)
+	if err != nil {
+		return 0, err
+	}
+	converted := new(big.Rat).Mul(new(big.Rat).SetInt64(amount), factor)
+	result := Round(converted)
+	if !result.IsInt64() {
+		return 0, fmt.Errorf("converted amount %s %s overflows int64", result, to)
+	}
+	return result.Int64(), nil
+}
+
+// Segment - период [From, To), в котором множитель перевода валюты постоянен.
+// Нулевой From означает "с начала", нулевой To - "без конца"
+type Segment struct {
+	Currency string
+	From     time.Time
+	To       time.Time
+	Factor   *big.Rat
+}
+
+// Segments разбивает время с дня since на периоды с постоянным множителем перевода from в to.
+// Возвращает ErrNoRate, если в день since для одной из валют еще нет курса
+func (t *RateTable) Segments(from, to string, since time.Time) ([]Segment, error) {
+	if from == to {
+		if _, err := Exponent(from); err != nil {
+			return nil, err
+		}
+		return []Segment{{Currency: from, Factor: big.NewRat(1, 1)}}, nil
+	}
+
+	since = day(since)
+	start, err := t.coveredSince(from)
+	if err != nil {
+		return nil, err
+	}
+	toStart, err := t.coveredSince(to)
+	if err != nil {
+		return nil, err
+	}
+	if toStart.After(start) {
+		start = toStart
+	}
+	if start.After(since) {
+		return nil, fmt.Errorf("%w for %s to %s on %s", ErrNoRate, from, to, since.Format(dateLayout))
+	}
+
+	// Множитель меняется в дни начала действия курсов обеих валют
+	var bounds []time.Time
+	for _, code := range []string{from, to} {
+		for _, rate := range t.rates[code] {
+			if rate.EffectiveFrom.After(start) {
+				bounds = append(bounds, rate.EffectiveFrom)
+			}
+		}
+	}
+	sort.Slice(bounds, func(i, j int) bool { return bounds[i].Before(bounds[j]) })
+
+	var segments []Segment
+	segmentStart := start
+	for _, bound := range append(bounds, time.Time{}) {
+		if !bound.IsZero() && !bound.After(segmentStart) {
+			continue
+		}
+		factor, err := t.Factor(from, to, segmentStart)
+		if err != nil {
+			return nil, err
+		}
+		segments = append(segments, Segment{Currency: from, From: segmentStart, To: bound, Factor: factor})
+		segmentStart = bound
+	}
+	return segments, nil
+}
+
+// coveredSince возвращает первый день, с которого у валюты есть курс (нулевое время - всегда)
+func (t *RateTable) coveredSince(code string) (time.Time, error) {
+	if _, err := Exponent(code); err != nil {
+		return time.Time{}, err
+	}
+	if code == t.base {
+		return time.Time{}, nil
+	}
+	rates := t.rates[code]
+	if len(rates) == 0 {
+		return time.Time{}, fmt.Errorf("%w for %s", ErrNoRate, code)
+	}
+	return rates[0].EffectiveFrom, nil
+}
+
+// rate возвращает курс валюты к базовой, действующий в день date
+func (t *RateTable) rate(code string, date time.Time) (*big.Rat, error) {
+	if code == t.base {
+		return big.NewRat(1, 1), nil
+	}
+	date = day(date)
+	rates := t.rates[code]
+	// Первый курс, начинающий действовать позже date; нужен предыдущий
+	i := sort.Search(len(rates), func(i int) bool { return rates[i].EffectiveFrom.After(date) })
+	if i == 0 {
+		return nil, fmt.Errorf("%w for %s on %s", ErrNoRate, code, date.Format(dateLayout))
+	}
+	return rates[i-1].Value, nil
+}
+
+// Round округляет до целого, половина округляется от нуля
+func Round(value *big.Rat) *big.Int {
+	num := new(big.Int).Abs(value.Num())
+	quo, rem := new(big.Int).QuoRem(num, value.Denom(), new(big.Int))
+	if rem.Mul(rem, big.NewInt(2)).Cmp(value.Denom()) >= 0 {
+		quo.Add(quo, big.NewInt(1))
+	}
+	if value.Sign() < 0 {
+		quo.Neg(quo)
+	}
+	return quo
+}
+
+const dateLayout = "2006-01-02"
+
+// day отбрасывает время: курсы действуют целыми днями (UTC)
+func day(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func pow10(exponent int) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(exponent)), nil)
+}
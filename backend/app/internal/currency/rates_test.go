@@ -0,0 +1,237 @@
+package currency
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+)
+
+func date(s string) time.Time {
+	t, err := time.Parse(dateLayout, s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func testRate(code, value, effectiveFrom string) Rate {
+	rate, err := ParseRate(code, value, effectiveFrom)
+	if err != nil {
+		panic(err)
+	}
+	return rate
+}
+
+func testTable(t *testing.T) *RateTable {
+	t.Helper()
+	table, err := NewRateTable("RUB", []Rate{
+		testRate("USD", "74.5", "2021-01-01"),
+		testRate("USD", "90", "2022-03-01"),
+		testRate("EUR", "84", "2021-06-01"),
+		testRate("JPY", "0.68", "2021-01-01"),
+		testRate("KWD", "245", "2021-01-01"),
+	})
+	if err != nil {
+		t.Fatalf("NewRateTable() error = %v", err)
+	}
+	return table
+}
+
+func TestNewRateTableRejectsInvalidRates(t *testing.T) {
+	tests := []struct {
+		name  string
+		base  string
+		rates []Rate
+	}{
+		{name: "unknown base", base: "XYZ"},
+		{name: "unknown currency", base: "RUB", rates: []Rate{testRate("XYZ", "1", "2021-01-01")}},
+		{name: "base currency rate", base: "RUB", rates: []Rate{testRate("RUB", "1", "2021-01-01")}},
+		{name: "zero rate", base: "RUB", rates: []Rate{testRate("USD", "0", "2021-01-01")}},
+		{name: "duplicate date", base: "RUB", rates: []Rate{
+			testRate("USD", "74.5", "2021-01-01"), testRate("USD", "75", "2021-01-01"),
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewRateTable(tt.base, tt.rates); err == nil {
+				t.Error("NewRateTable() succeeded, want error")
+			}
+		})
+	}
+}
+
+func TestFactor(t *testing.T) {
+	table := testTable(t)
+	tests := []struct {
+		name     string
+		from, to string
+		date     string
+		want     string
+		wantErr  error
+	}{
+		{name: "same currency", from: "USD", to: "USD", date: "2020-01-01", want: "1"},
+		// 1 цент = 0.745 рубля = 74.5 копейки
+		{name: "to base", from: "USD", to: "RUB", date: "2021-11-26", want: "149/2"},
+		{name: "later rate", from: "USD", to: "RUB", date: "2022-03-01", want: "90"},
+		// 1 иена (без дробной части) = 0.68 рубля = 68 копеек
+		{name: "zero exponent", from: "JPY", to: "RUB", date: "2021-11-26", want: "68"},
+		// 1 цент = 0.745 / 0.68 иены
+		{name: "cross rate to zero exponent", from: "USD", to: "JPY", date: "2021-11-26", want: "149/136"},
+		// 1 филс (0.001 KWD) = 0.245 рубля = 24.5 копейки
+		{name: "three digit exponent", from: "KWD", to: "RUB", date: "2021-11-26", want: "49/2"},
+		{name: "before first rate", from: "EUR", to: "RUB", date: "2021-05-31", wantErr: ErrNoRate},
+		{name: "unknown currency", from: "XYZ", to: "RUB", date: "2021-11-26", wantErr: ErrUnknownCurrency},
+		{name: "no rates for currency", from: "GBP", to: "RUB", date: "2021-11-26", wantErr: ErrNoRate},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := table.Factor(tt.from, tt.to, date(tt.date))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Factor() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			want, _ := new(big.Rat).SetString(tt.want)
+			if got.Cmp(want) != 0 {
+				t.Errorf("Factor() = %s, want %s", got.RatString(), tt.want)
+			}
+		})
+	}
+}
+
+func TestConvert(t *testing.T) {
+	table := testTable(t)
+	day := date("2021-11-26")
+	tests := []struct {
+		name     string
+		amount   int64
+		from, to string
+		want     int64
+	}{
+		// 18.17 USD = 1353.665 RUB
+		{name: "half rounded away from zero", amount: 1817, from: "USD", to: "RUB", want: 135367},
+		{name: "negative half rounded away from zero", amount: -1817, from: "USD", to: "RUB", want: -135367},
+		// 1 USD = 109.56 JPY
+		{name: "to zero exponent", amount: 100, from: "USD", to: "JPY", want: 110},
+		// 1.50 RUB = 0.0201 USD
+		{name: "less than half a minor unit", amount: 150, from: "RUB", to: "USD", want: 2},
+		{name: "from three digit exponent", amount: 1500, from: "KWD", to: "RUB", want: 36750},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := table.Convert(tt.amount, tt.from, tt.to, day)
+			if err != nil {
+				t.Fatalf("Convert() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Convert(%d %s -> %s) = %d, want %d", tt.amount, tt.from, tt.to, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRound(t *testing.T) {
+	tests := []struct {
+		value string
+		want  int64
+	}{
+		{"5/2", 3},
+		{"-5/2", -3},
+		{"7/3", 2},
+		{"-7/3", -2},
+		{"8/3", 3},
+		{"-8/3", -3},
+		{"1/2", 1},
+		{"-1/2", -1},
+		{"49/100", 0},
+		{"0", 0},
+		{"42", 42},
+	}
+	for _, tt := range tests {
+		value, _ := new(big.Rat).SetString(tt.value)
+		if got := Round(value); got.Int64() != tt.want {
+			t.Errorf("Round(%s) = %s, want %d", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestSegments(t *testing.T) {
+	table := testTable(t)
+
+	type segment struct {
+		from, to string // пустая строка - открытая граница
+		factor   string
+	}
+	tests := []struct {
+		name     string
+		from, to string
+		since    string
+		want     []segment
+		wantErr  error
+	}{
+		{
+			name: "identity", from: "RUB", to: "RUB", since: "2020-01-01",
+			want: []segment{{factor: "1"}},
+		},
+		{
+			name: "split at rate change", from: "USD", to: "RUB", since: "2021-06-15",
+			want: []segment{
+				{from: "2021-01-01", to: "2022-03-01", factor: "149/2"},
+				{from: "2022-03-01", factor: "90"},
+			},
+		},
+		{
+			// Курсы обеих валют меняются в разные дни: EUR начинается позже USD
+			name: "cross rate", from: "EUR", to: "USD", since: "2021-07-01",
+			want: []segment{
+				{from: "2021-06-01", to: "2022-03-01", factor: "168/149"},
+				{from: "2022-03-01", factor: "14/15"},
+			},
+		},
+		{name: "before coverage", from: "EUR", to: "USD", since: "2021-02-01", wantErr: ErrNoRate},
+		{name: "currency without rates", from: "GBP", to: "RUB", since: "2021-02-01", wantErr: ErrNoRate},
+		{name: "unknown currency", from: "XYZ", to: "RUB", since: "2021-02-01", wantErr: ErrUnknownCurrency},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := table.Segments(tt.from, tt.to, date(tt.since))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Segments() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("Segments() returned %d segments, want %d", len(got), len(tt.want))
+			}
+			for i, want := range tt.want {
+				s := got[i]
+				if s.Currency != tt.from {
+					t.Errorf("segment %d currency = %s, want %s", i, s.Currency, tt.from)
+				}
+				if bound := formatBound(s.From); bound != want.from {
+					t.Errorf("segment %d from = %q, want %q", i, bound, want.from)
+				}
+				if bound := formatBound(s.To); bound != want.to {
+					t.Errorf("segment %d to = %q, want %q", i, bound, want.to)
+				}
+				factor, _ := new(big.Rat).SetString(want.factor)
+				if s.Factor.Cmp(factor) != 0 {
+					t.Errorf("segment %d factor = %s, want %s", i, s.Factor.RatString(), want.factor)
+				}
+			}
+		})
+	}
+}
+
+func formatBound(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(dateLayout)
+}
@@ -0,0 +1,159 @@
+package currency
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// RateSource загружает курсы валют (из файла или таблицы exchange_rates)
+type RateSource interface {
+	LoadRates(ctx context.Context) ([]Rate, error)
+}
+
+// ParseRate разбирает курс из строковых значений: код валюты, десятичный курс и дату YYYY-MM-DD
+func ParseRate(code, value, effectiveFrom string) (Rate, error) {
+	rate := Rate{Currency: Normalize(code)}
+
+	var ok bool
+	if rate.Value, ok = new(big.Rat).SetString(strings.TrimSpace(value)); !ok {
+		return rate, fmt.Errorf("invalid rate %q for %s", value, rate.Currency)
+	}
+
+	var err error
+	if rate.EffectiveFrom, err = time.Parse(dateLayout, strings.TrimSpace(effectiveFrom)); err != nil {
+		return rate, fmt.Errorf("invalid effective date %q for %s, expected YYYY-MM-DD", effectiveFrom, rate.Currency)
+	}
+	return rate, nil
+}
+
+// FileSource читает курсы из CSV файла со строками currency,rate,effective_from.
+// Строка заголовка и строки, начинающиеся с #, пропускаются
+type FileSource struct {
+	path string
+}
+
+// NewFileSource создает источник курсов из файла
+func NewFileSource(path string) *FileSource {
+	return &FileSource{path: path}
+}
+
+// LoadRates читает файл целиком; файл перечитывается при каждом обновлении курсов
+func (s *FileSource) LoadRates(ctx context.Context) ([]Rate, error) {
+	f, err := os.Open(s.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open rates file: %w", err)
+	}
+	defer f.Close()
+
+	reader := csv.NewReader(f)
+	reader.Comment = '#'
+	reader.FieldsPerRecord = 3
+	reader.TrimLeadingSpace = true
+
+	var rates []Rate
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read rates file %s: %w", s.path, err)
+		}
+		if len(rates) == 0 && strings.EqualFold(record[0], "currency") {
+			continue
+		}
+
+		rate, err := ParseRate(record[0], record[1], record[2])
+		if err != nil {
+			line, _ := reader.FieldPos(0)
+			return nil, fmt.Errorf("rates file %s, line %d: %w", s.path, line, err)
+		}
+		rates = append(rates, rate)
+	}
+	return rates, nil
+}
+
+// Rates хранит актуальную таблицу курсов и периодически перечитывает ее из источника.
+// Безопасен для параллельного использования
+type Rates struct {
+	base   string
+	source RateSource
+	logger *logrus.Logger
+
+	mu    sync.RWMutex
+	table *RateTable
+}
+
+// NewRates создает хранилище курсов к базовой валюте base. Таблица пуста до первого Load:
+// в ней есть только базовая валюта
+func NewRates(base string, source RateSource, logger *logrus.Logger) (*Rates, error) {
+	table, err := NewRateTable(base, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &Rates{
+		base:   table.Base(),
+		source: source,
+		logger: logger,
+		table:  table,
+	}, nil
+}
+
+// Table возвращает текущую таблицу курсов
+func (r *Rates) Table() *RateTable {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.table
+}
+
+// Load перечитывает курсы из источника. При ошибке остается прежняя таблица
+func (r *Rates) Load(ctx context.Context) error {
+	rates, err := r.source.LoadRates(ctx)
+	if err != nil {
+		return err
+	}
+	table, err := NewRateTable(r.base, rates)
+	if err != nil {
+		return fmt.Errorf("invalid exchange rates: %w", err)
+	}
+
+	r.mu.Lock()
+	r.table = table
+	r.mu.Unlock()
+
+	r.logger.WithFields(logrus.Fields{
+		"base":  r.base,
+		"rates": len(rates),
+	}).Debug("Exchange rates loaded")
+	return nil
+}
+
+// Run перечитывает курсы с интервалом interval до отмены ctx. Интервал 0 отключает обновление
+func (r *Rates) Run(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := r.Load(ctx); err != nil {
+				r.logger.WithError(err).Error("Failed to reload exchange rates, keeping previous ones")
+			}
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package currency
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func writeRatesFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "rates.csv")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestFileSourceLoadRates(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []string // currency@effective_from=rate
+		wantErr string
+	}{
+		{
+			name:    "header and comments",
+			content: "currency,rate,effective_from\n# курсы ЦБ\nUSD,74.5,2021-01-01\n\n# евро\neur, 84.0, 2021-06-01\n",
+			want:    []string{"USD@2021-01-01=149/2", "EUR@2021-06-01=84"},
+		},
+		{
+			name:    "without header",
+			content: "USD,74.5,2021-01-01\n",
+			want:    []string{"USD@2021-01-01=149/2"},
+		},
+		{
+			name:    "empty file",
+			content: "# только комментарий\n",
+		},
+		{
+			// Строка заголовка допускается только в начале файла
+			name:    "header after rates",
+			content: "USD,74.5,2021-01-01\ncurrency,rate,effective_from\n",
+			wantErr: "line 2",
+		},
+		{
+			name:    "invalid rate",
+			content: "currency,rate,effective_from\nUSD,abc,2021-01-01\n",
+			wantErr: `invalid rate "abc"`,
+		},
+		{
+			name:    "invalid date",
+			content: "USD,74.5,01.01.2021\n",
+			wantErr: "expected YYYY-MM-DD",
+		},
+		{
+			name:    "wrong number of fields",
+			content: "USD,74.5\n",
+			wantErr: "wrong number of fields",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rates, err := NewFileSource(writeRatesFile(t, tt.content)).LoadRates(context.Background())
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("LoadRates() error = %v, want error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadRates() error = %v", err)
+			}
+
+			if len(rates) != len(tt.want) {
+				t.Fatalf("LoadRates() returned %d rates, want %d", len(rates), len(tt.want))
+			}
+			for i, rate := range rates {
+				got := rate.Currency + "@" + rate.EffectiveFrom.Format(dateLayout) + "=" + rate.Value.RatString()
+				if got != tt.want[i] {
+					t.Errorf("rate %d = %s, want %s", i, got, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestFileSourceMissingFile(t *testing.T) {
+	_, err := NewFileSource(filepath.Join(t.TempDir(), "missing.csv")).LoadRates(context.Background())
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("LoadRates() error = %v, want %v", err, os.ErrNotExist)
+	}
+}
+
+func TestRatesLoadKeepsPreviousTableOnError(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	path := writeRatesFile(t, "USD,74.5,2021-01-01\n")
+	rates, err := NewRates("RUB", NewFileSource(path), logger)
+	if err != nil {
+		t.Fatalf("NewRates() error = %v", err)
+	}
+	if err := rates.Load(context.Background()); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	if err := os.WriteFile(path, []byte("USD,-1,2021-01-01\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := rates.Load(context.Background()); err == nil {
+		t.Fatal("Load() of invalid rates succeeded, want error")
+	}
+	if got := len(rates.Table().Rates()); got != 1 {
+		t.Errorf("table has %d rates after failed reload, want previous 1", got)
+	}
+}
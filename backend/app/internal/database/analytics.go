@@ -0,0 +1,461 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"order-service/internal/currency"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Interval - шаг группировки выручки по времени
+type Interval string
+
+const (
+	IntervalDay   Interval = "day"
+	IntervalWeek  Interval = "week" // неделя начинается с понедельника
+	IntervalMonth Interval = "month"
+)
+
+// ParseInterval разбирает шаг группировки
+func ParseInterval(value string) (Interval, error) {
+	switch interval := Interval(strings.ToLower(strings.TrimSpace(value))); interval {
+	case IntervalDay, IntervalWeek, IntervalMonth:
+		return interval, nil
+	default:
+		return "", fmt.Errorf("unknown interval %q, expected day, week or month", value)
+	}
+}
+
+// TopMetric - показатель, по которому выбираются лидеры продаж
+type TopMetric string
+
+const (
+	TopByUnits   TopMetric = "units"
+	TopByRevenue TopMetric = "revenue"
+)
+
+// ParseTopMetric разбирает показатель рейтинга
+func ParseTopMetric(value string) (TopMetric, error) {
+	switch metric := TopMetric(strings.ToLower(strings.TrimSpace(value))); metric {
+	case TopByUnits, TopByRevenue:
+		return metric, nil
+	default:
+		return "", fmt.Errorf("unknown metric %q, expected units or revenue", value)
+	}
+}
+
+// AnalyticsFilter - условия отбора для отчетов. Пустые поля не ограничивают выборку.
+// Отчеты строятся по дневным агрегатам, поэтому границы периода округляются до дней (UTC):
+// день, в который попадает DateTo со временем, учитывается целиком.
+// Суммы разных валют не складываются: без ReportingCurrency отчеты группируются по валюте платежа,
+// с ней - суммы каждого дня переводятся в ReportingCurrency по курсам этого дня из Rates
+type AnalyticsFilter struct {
+	DateFrom          time.Time // день date_created >= DateFrom
+	DateTo            time.Time // день date_created < DateTo
+	Currency          string    // payments.currency
+	ReportingCurrency string
+	Rates             *currency.RateTable // обязательна, если задана ReportingCurrency
+}
+
+// conditions возвращает условие WHERE для таблицы дневных агрегатов с псевдонимом r
+func (f AnalyticsFilter) conditions() (string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+	add := func(condition string, value interface{}) {
+		args = append(args, value)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	if !f.DateFrom.IsZero() {
+		add("r.day >= $%d", rollupDate(f.DateFrom, false))
+	}
+	if !f.DateTo.IsZero() {
+		add("r.day < $%d", rollupDate(f.DateTo, true))
+	}
+	if f.Currency != "" {
+		add("r.currency = $%d", f.Currency)
+	}
+	if len(conditions) == 0 {
+		return "", nil
+	}
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// RevenuePoint - выручка за период в одной валюте. Суммы в минимальных единицах валюты
+type RevenuePoint struct {
+	Period       string `json:"period"` // начало периода, YYYY-MM-DD (UTC)
+	Currency     string `json:"currency"`
+	Orders       int64  `json:"orders"`
+	Revenue      int64  `json:"revenue"` // payments.amount
+	GoodsTotal   int64  `json:"goods_total"`
+	DeliveryCost int64  `json:"delivery_cost"`
+}
+
+// DeliveryServiceStats - заказы службы доставки в одной валюте
+type DeliveryServiceStats struct {
+	DeliveryService string `json:"delivery_service"`
+	Currency        string `json:"currency"`
+	Orders          int64  `json:"orders"`
+	Revenue         int64  `json:"revenue"`
+}
+
+// BrandStats - продажи бренда в одной валюте. Каждая строка order_items - одна единица товара
+type BrandStats struct {
+	Brand    string `json:"brand"`
+	Currency string `json:"currency"`
+	Units    int64  `json:"units"`
+	Revenue  int64  `json:"revenue"` // сумма total_price
+	Orders   int64  `json:"orders"`
+}
+
+// ProductStats - продажи товара (nm_id) в одной валюте
+type ProductStats struct {
+	NmID     int    `json:"nm_id"`
+	Name     string `json:"name"`
+	Brand    string `json:"brand"`
+	Currency string `json:"currency"`
+	Units    int64  `json:"units"`
+	Revenue  int64  `json:"revenue"`
+	Orders   int64  `json:"orders"`
+}
+
+// BasketStats - средний размер корзины в одной валюте
+type BasketStats struct {
+	Currency      string  `json:"currency"`
+	Orders        int64   `json:"orders"`
+	AvgItems      float64 `json:"avg_items"`
+	AvgGoodsTotal float64 `json:"avg_goods_total"`
+	AvgAmount     float64 `json:"avg_amount"`
+}
+
+// SaleBucket - товары со скидкой (sale, %) в диапазоне [SaleFrom, SaleTo] в одной валюте
+type SaleBucket struct {
+	SaleFrom int    `json:"sale_from"`
+	SaleTo   int    `json:"sale_to"`
+	Currency string `json:"currency"`
+	Units    int64  `json:"units"`
+	Revenue  int64  `json:"revenue"`  // сумма total_price
+	Discount int64  `json:"discount"` // сумма price - total_price
+}
+
+// Отчеты читают дневные агрегаты (rollup.go), а не order_items и payments: объем чтения
+// зависит от длины периода, а не от числа заказов. Строки, обнуленные перезаписью заказов, пропускаются
+
+// RevenueByPeriod возвращает выручку по дням, неделям или месяцам в хронологическом порядке
+func (p *PostgresDB) RevenueByPeriod(ctx context.Context, filter AnalyticsFilter, interval Interval) ([]RevenuePoint, error) {
+	from, args, err := p.analyticsSource(ctx, "order_daily_rollups", filter)
+	if err != nil {
+		return nil, err
+	}
+	args = append(args, string(interval))
+	query := fmt.Sprintf(`
+		SELECT date_trunc($%d, day::timestamp)::date AS period, currency, SUM(orders)::bigint,
+			   SUM(amount)::bigint, SUM(goods_total)::bigint, SUM(delivery_cost)::bigint
+		`+from+`
+		GROUP BY period, currency
+		HAVING SUM(orders) > 0
+		ORDER BY period, currency
+	`, len(args))
+
+	var points []RevenuePoint
+	err = p.queryAnalytics(ctx, query, args, func(rows *sql.Rows) error {
+		var point RevenuePoint
+		var period time.Time
+		if err := rows.Scan(&period, &point.Currency, &point.Orders, &point.Revenue, &point.GoodsTotal, &point.DeliveryCost); err != nil {
+			return err
+		}
+		point.Period = period.Format("2006-01-02")
+		points = append(points, point)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get revenue: %w", err)
+	}
+	return points, nil
+}
+
+// OrdersByDeliveryService возвращает число заказов и выручку по службам доставки
+func (p *PostgresDB) OrdersByDeliveryService(ctx context.Context, filter AnalyticsFilter) ([]DeliveryServiceStats, error) {
+	from, args, err := p.analyticsSource(ctx, "order_daily_rollups", filter)
+	if err != nil {
+		return nil, err
+	}
+	query := `
+		SELECT delivery_service, currency, SUM(orders)::bigint, SUM(amount)::bigint
+		` + from + `
+		GROUP BY delivery_service, currency
+		HAVING SUM(orders) > 0
+		ORDER BY SUM(orders) DESC, delivery_service, currency
+	`
+
+	var stats []DeliveryServiceStats
+	err = p.queryAnalytics(ctx, query, args, func(rows *sql.Rows) error {
+		var s DeliveryServiceStats
+		if err := rows.Scan(&s.DeliveryService, &s.Currency, &s.Orders, &s.Revenue); err != nil {
+			return err
+		}
+		stats = append(stats, s)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get delivery service stats: %w", err)
+	}
+	return stats, nil
+}
+
+// TopBrands возвращает limit брендов с наибольшими продажами в каждой валюте
+func (p *PostgresDB) TopBrands(ctx context.Context, filter AnalyticsFilter, metric TopMetric, limit int) ([]BrandStats, error) {
+	from, args, err := p.analyticsSource(ctx, "brand_daily_rollups", filter)
+	if err != nil {
+		return nil, err
+	}
+	query := topQuery(`
+		SELECT brand, currency, SUM(units)::bigint AS units, SUM(revenue)::bigint AS revenue,
+			   SUM(orders)::bigint AS orders
+		`+from+`
+		GROUP BY brand, currency
+		HAVING SUM(units) > 0
+	`, "brand, currency, units, revenue, orders", "brand", metric, limit)
+
+	var stats []BrandStats
+	err = p.queryAnalytics(ctx, query, args, func(rows *sql.Rows) error {
+		var s BrandStats
+		if err := rows.Scan(&s.Brand, &s.Currency, &s.Units, &s.Revenue, &s.Orders); err != nil {
+			return err
+		}
+		stats = append(stats, s)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get top brands: %w", err)
+	}
+	return stats, nil
+}
+
+// TopProducts возвращает limit товаров (nm_id) с наибольшими продажами в каждой валюте
+func (p *PostgresDB) TopProducts(ctx context.Context, filter AnalyticsFilter, metric TopMetric, limit int) ([]ProductStats, error) {
+	from, args, err := p.analyticsSource(ctx, "product_daily_rollups", filter)
+	if err != nil {
+		return nil, err
+	}
+	query := topQuery(`
+		SELECT nm_id, MAX(name) AS name, MAX(brand) AS brand, currency, SUM(units)::bigint AS units,
+			   SUM(revenue)::bigint AS revenue, SUM(orders)::bigint AS orders
+		`+from+`
+		GROUP BY nm_id, currency
+		HAVING SUM(units) > 0
+	`, "nm_id, name, brand, currency, units, revenue, orders", "nm_id", metric, limit)
+
+	var stats []ProductStats
+	err = p.queryAnalytics(ctx, query, args, func(rows *sql.Rows) error {
+		var s ProductStats
+		if err := rows.Scan(&s.NmID, &s.Name, &s.Brand, &s.Currency, &s.Units, &s.Revenue, &s.Orders); err != nil {
+			return err
+		}
+		stats = append(stats, s)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get top products: %w", err)
+	}
+	return stats, nil
+}
+
+// BasketStats возвращает среднее число товаров и средние суммы заказа
+func (p *PostgresDB) BasketStats(ctx context.Context, filter AnalyticsFilter) ([]BasketStats, error) {
+	from, args, err := p.analyticsSource(ctx, "order_daily_rollups", filter)
+	if err != nil {
+		return nil, err
+	}
+	query := `
+		SELECT currency, SUM(orders)::bigint, SUM(items)::float8 / SUM(orders),
+			   SUM(goods_total)::float8 / SUM(orders), SUM(amount)::float8 / SUM(orders)
+		` + from + `
+		GROUP BY currency
+		HAVING SUM(orders) > 0
+		ORDER BY currency
+	`
+
+	var stats []BasketStats
+	err = p.queryAnalytics(ctx, query, args, func(rows *sql.Rows) error {
+		var s BasketStats
+		if err := rows.Scan(&s.Currency, &s.Orders, &s.AvgItems, &s.AvgGoodsTotal, &s.AvgAmount); err != nil {
+			return err
+		}
+		stats = append(stats, s)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get basket stats: %w", err)
+	}
+	return stats, nil
+}
+
+// SaleDistribution возвращает распределение товаров по размеру скидки:
+// без скидки, 1-10%, 11-20%, ..., 91-100% (скидки больше 100% попадают в последний диапазон)
+func (p *PostgresDB) SaleDistribution(ctx context.Context, filter AnalyticsFilter) ([]SaleBucket, error) {
+	from, args, err := p.analyticsSource(ctx, "sale_daily_rollups", filter)
+	if err != nil {
+		return nil, err
+	}
+	query := `
+		SELECT sale_from, currency, SUM(units)::bigint, SUM(revenue)::bigint, SUM(discount)::bigint
+		` + from + `
+		GROUP BY sale_from, currency
+		HAVING SUM(units) > 0
+		ORDER BY sale_from, currency
+	`
+
+	var buckets []SaleBucket
+	err = p.queryAnalytics(ctx, query, args, func(rows *sql.Rows) error {
+		var b SaleBucket
+		if err := rows.Scan(&b.SaleFrom, &b.Currency, &b.Units, &b.Revenue, &b.Discount); err != nil {
+			return err
+		}
+		if b.SaleFrom > 0 {
+			b.SaleTo = b.SaleFrom + 9
+		}
+		buckets = append(buckets, b)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sale distribution: %w", err)
+	}
+	return buckets, nil
+}
+
+// rollupColumns - колонки таблиц агрегатов, кроме currency: money переводятся в валюту отчета,
+// остальные копируются как есть
+var rollupColumns = map[string]struct{ plain, money []string }{
+	"order_daily_rollups": {
+		plain: []string{"day", "delivery_service", "region", "orders", "items"},
+		money: []string{"amount", "goods_total", "delivery_cost"},
+	},
+	"brand_daily_rollups": {
+		plain: []string{"day", "delivery_service", "region", "brand", "units", "orders"},
+		money: []string{"revenue", "discount"},
+	},
+	"product_daily_rollups": {
+		plain: []string{"day", "nm_id", "name", "brand", "units", "orders"},
+		money: []string{"revenue"},
+	},
+	"sale_daily_rollups": {
+		plain: []string{"day", "sale_from", "units"},
+		money: []string{"revenue", "discount"},
+	},
+}
+
+// conversionScale - число знаков после запятой у множителей перевода, передаваемых в запрос
+const conversionScale = 18
+
+// analyticsSource возвращает FROM для отчета по таблице агрегатов с учетом фильтра.
+// Если задана валюта отчета, суммы каждой строки переводятся в нее множителем, действующим
+// в день строки, и округляются до минимальных единиц; currency всех строк - валюта отчета
+func (p *PostgresDB) analyticsSource(ctx context.Context, table string, filter AnalyticsFilter) (string, []interface{}, error) {
+	where, args := filter.conditions()
+	if filter.ReportingCurrency == "" {
+		return "FROM " + table + " r" + where, args, nil
+	}
+	if filter.Rates == nil {
+		return "", nil, fmt.Errorf("exchange rates are required to report in %s", filter.ReportingCurrency)
+	}
+
+	segments, err := p.conversionSegments(ctx, table, where, args, filter)
+	if err != nil {
+		return "", nil, err
+	}
+	var codes, validFrom, validTo, factors []string
+	for _, segment := range segments {
+		codes = append(codes, segment.Currency)
+		validFrom = append(validFrom, segmentBound(segment.From, "-infinity"))
+		validTo = append(validTo, segmentBound(segment.To, "infinity"))
+		factors = append(factors, segment.Factor.FloatString(conversionScale))
+	}
+
+	columns := rollupColumns[table]
+	selectList := make([]string, 0, len(columns.plain)+len(columns.money)+1)
+	for _, column := range columns.plain {
+		selectList = append(selectList, "r."+column)
+	}
+	for _, column := range columns.money {
+		selectList = append(selectList, fmt.Sprintf("ROUND(r.%[1]s * f.factor)::bigint AS %[1]s", column))
+	}
+
+	args = append(args, pq.Array(codes), pq.Array(validFrom), pq.Array(validTo), pq.Array(factors), filter.ReportingCurrency)
+	n := len(args)
+	selectList = append(selectList, fmt.Sprintf("$%d::varchar AS currency", n))
+
+	return fmt.Sprintf(`FROM (
+			SELECT %s
+			FROM %s r
+			JOIN unnest($%d::text[], $%d::date[], $%d::date[], $%d::numeric[]) AS f(currency, valid_from, valid_to, factor)
+				ON f.currency = r.currency AND r.day >= f.valid_from AND r.day < f.valid_to%s
+		) r`, strings.Join(selectList, ", "), table, n-4, n-3, n-2, n-1, where), args, nil
+}
+
+// conversionSegments возвращает множители перевода в валюту отчета для всех валют,
+// встречающихся в отобранных строках агрегатов. Если для какой-то валюты нет курса
+// на первый день ее строк, возвращается ошибка currency.ErrNoRate
+func (p *PostgresDB) conversionSegments(ctx context.Context, table, where string, args []interface{}, filter AnalyticsFilter) ([]currency.Segment, error) {
+	query := "SELECT r.currency, MIN(r.day) FROM " + table + " r" + where + " GROUP BY r.currency"
+
+	var segments []currency.Segment
+	err := p.queryAnalytics(ctx, query, args, func(rows *sql.Rows) error {
+		var code string
+		var since time.Time
+		if err := rows.Scan(&code, &since); err != nil {
+			return err
+		}
+		currencySegments, err := filter.Rates.Segments(code, filter.ReportingCurrency, since)
+		if err != nil {
+			return err
+		}
+		segments = append(segments, currencySegments...)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert to %s: %w", filter.ReportingCurrency, err)
+	}
+	return segments, nil
+}
+
+// segmentBound возвращает границу периода для параметра date[]; нулевая граница - бесконечность
+func segmentBound(t time.Time, infinity string) string {
+	if t.IsZero() {
+		return infinity
+	}
+	return t.Format("2006-01-02")
+}
+
+// topQuery оставляет limit первых строк сгруппированного запроса в каждой валюте
+// (выручку в разных валютах нельзя сравнивать между собой) и выбирает из них columns
+func topQuery(grouped, columns, key string, metric TopMetric, limit int) string {
+	return fmt.Sprintf(`
+		SELECT %[2]s FROM (
+			SELECT g.*, ROW_NUMBER() OVER (PARTITION BY g.currency ORDER BY g.%[3]s DESC, g.%[4]s) AS position
+			FROM (%[1]s) g
+		) ranked
+		WHERE position <= %[5]d
+		ORDER BY currency, position
+	`, grouped, columns, metric, key, limit)
+}
+
+// queryAnalytics выполняет запрос отчета и вызывает scan для каждой строки
+func (p *PostgresDB) queryAnalytics(ctx context.Context, query string, args []interface{}, scan func(*sql.Rows) error) error {
+	rows, err := p.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		if err := scan(rows); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
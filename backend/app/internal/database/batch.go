@@ -0,0 +1,204 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"order-service/internal/models"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// maxQueryParams - ограничение PostgreSQL на число параметров в одном запросе
+const maxQueryParams = 65535
+
+var (
+	orderColumns = []string{"order_uid", "track_number", "entry", "locale", "internal_signature",
+		"customer_id", "delivery_service", "shardkey", "sm_id", "date_created", "oof_shard", "content_hash",
+		"validation_warnings"}
+	deliveryColumns = []string{"order_uid", "name", "phone", "zip", "city", "address", "region", "email"}
+	paymentColumns  = []string{"order_uid", "transaction", "request_id", "currency", "provider",
+		"amount", "payment_dt", "bank", "delivery_cost", "goods_total", "custom_fee"}
+	itemColumns = []string{"order_uid", "chrt_id", "track_number", "price", "rid", "name",
+		"sale", "size", "total_price", "nm_id", "brand", "status"}
+)
+
+// CreateOrders сохраняет пачку заказов в одной транзакции многострочными INSERT'ами.
+// Заказы, которые уже есть в базе (или повторяются в пачке), пропускаются.
+// Возвращает UID заказов, которые были сохранены; для остальных вызывающий код
+// может применить политику конфликтов через UpsertOrder
+func (p *PostgresDB) CreateOrders(batch []*models.OrderFull) ([]string, error) {
+	if len(batch) == 0 {
+		return nil, nil
+	}
+
+	tx, err := p.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// 1. Вставляем основные заказы, уже существующие пропускаются
+	seen := make(map[string]bool, len(batch))
+	orderRows := make([][]interface{}, 0, len(batch))
+	for _, orderFull := range batch {
+		if seen[orderFull.OrderUID] {
+			continue
+		}
+		seen[orderFull.OrderUID] = true
+		warnings, err := validationWarningsValue(orderFull.ValidationWarnings)
+		if err != nil {
+			return nil, err
+		}
+		orderRows = append(orderRows, []interface{}{
+			orderFull.OrderUID, orderFull.TrackNumber, orderFull.Entry, orderFull.Locale,
+			orderFull.InternalSignature, orderFull.CustomerID, orderFull.DeliveryService,
+			orderFull.Shardkey, orderFull.SmID, orderFull.DateCreated, orderFull.OofShard,
+			orderFull.ContentHash(), warnings,
+		})
+	}
+
+	// Время записи нужно моделям, которые дальше попадают в кеш
+	type insertedOrder struct{ createdAt, updatedAt time.Time }
+	inserted := make(map[string]insertedOrder, len(orderRows))
+	err = insertRows(tx, "orders", orderColumns, orderRows,
+		"ON CONFLICT (order_uid) DO NOTHING RETURNING order_uid, created_at, updated_at",
+		func(rows *sql.Rows) error {
+			var orderUID string
+			var row insertedOrder
+			if err := rows.Scan(&orderUID, &row.createdAt, &row.updatedAt); err != nil {
+				return err
+			}
+			inserted[orderUID] = row
+			return nil
+		})
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert orders: %w", err)
+	}
+
+	// 2-5. Вставляем доставку, платежи, товары и начальные статусы только для новых заказов
+	var deliveryRows, paymentRows, itemRows, historyRows [][]interface{}
+	created := make([]string, 0, len(inserted))
+	for _, orderFull := range batch {
+		row, ok := inserted[orderFull.OrderUID]
+		if !ok {
+			continue
+		}
+		// Повторное вхождение того же UID в пачке считается уже существующим заказом
+		delete(inserted, orderFull.OrderUID)
+		created = append(created, orderFull.OrderUID)
+		orderFull.CreatedAt, orderFull.UpdatedAt = row.createdAt, row.updatedAt
+
+		if d := orderFull.Delivery; d != nil {
+			deliveryRows = append(deliveryRows, []interface{}{
+				orderFull.OrderUID, d.Name, d.Phone, d.Zip, d.City, d.Address, d.Region, d.Email,
+			})
+		}
+		if pm := orderFull.Payment; pm != nil {
+			paymentRows = append(paymentRows, []interface{}{
+				orderFull.OrderUID, pm.Transaction, pm.RequestID, pm.Currency, pm.Provider,
+				pm.Amount, pm.PaymentDt, pm.Bank, pm.DeliveryCost, pm.GoodsTotal, pm.CustomFee,
+			})
+		}
+		for _, item := range orderFull.Items {
+			itemRows = append(itemRows, []interface{}{
+				orderFull.OrderUID, item.ChrtID, item.TrackNumber, item.Price, item.Rid,
+				item.Name, item.Sale, item.Size, item.TotalPrice, item.NmID, item.Brand, item.Status,
+			})
+		}
+		historyRows = append(historyRows, itemStatusRows(orderFull, nil, StatusSourceCreated, orderFull.DateCreated)...)
+	}
+
+	if err := insertRows(tx, "deliveries", deliveryColumns, deliveryRows, "", nil); err != nil {
+		return nil, fmt.Errorf("failed to insert deliveries: %w", err)
+	}
+	if err := insertRows(tx, "payments", paymentColumns, paymentRows, "", nil); err != nil {
+		return nil, fmt.Errorf("failed to insert payments: %w", err)
+	}
+	if err := insertRows(tx, "order_items", itemColumns, itemRows, "", nil); err != nil {
+		return nil, fmt.Errorf("failed to insert order items: %w", err)
+	}
+	if err := insertStatusHistory(tx, historyRows); err != nil {
+		return nil, err
+	}
+	if err := addRollups(tx, created, 1); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	p.logger.WithFields(logrus.Fields{
+		"batch_size": len(batch),
+		"created":    len(created),
+	}).Info("Order batch saved successfully")
+	return created, nil
+}
+
+// insertRows выполняет многострочный INSERT, разбивая строки на части так,
+// чтобы не превысить лимит параметров. suffix добавляется к каждому запросу
+// (например, ON CONFLICT ... RETURNING), scan вызывается для каждой возвращенной строки
+func insertRows(tx *sql.Tx, table string, columns []string, rows [][]interface{}, suffix string, scan func(*sql.Rows) error) error {
+	chunkSize := maxQueryParams / len(columns)
+
+	for start := 0; start < len(rows); start += chunkSize {
+		end := start + chunkSize
+		if end > len(rows) {
+			end = len(rows)
+		}
+		chunk := rows[start:end]
+
+		var query strings.Builder
+		fmt.Fprintf(&query, "INSERT INTO %s (%s) VALUES ", table, strings.Join(columns, ", "))
+
+		args := make([]interface{}, 0, len(chunk)*len(columns))
+		for i, row := range chunk {
+			if i > 0 {
+				query.WriteString(", ")
+			}
+			query.WriteString("(")
+			for j := range row {
+				if j > 0 {
+					query.WriteString(", ")
+				}
+				fmt.Fprintf(&query, "$%d", len(args)+j+1)
+			}
+			query.WriteString(")")
+			args = append(args, row...)
+		}
+		if suffix != "" {
+			query.WriteString(" ")
+			query.WriteString(suffix)
+		}
+
+		if scan == nil {
+			if _, err := tx.Exec(query.String(), args...); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := queryRows(tx, query.String(), args, scan); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func queryRows(tx *sql.Tx, query string, args []interface{}, scan func(*sql.Rows) error) error {
+	rows, err := tx.Query(query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		if err := scan(rows); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
@@ -0,0 +1,54 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"net"
+	"syscall"
+
+	"github.com/lib/pq"
+)
+
+// Классы и коды ошибок PostgreSQL, после которых имеет смысл повторить операцию
+var (
+	transientErrorClasses = map[pq.ErrorClass]bool{
+		"08": true, // connection_exception
+		"53": true, // insufficient_resources
+		"58": true, // system_error
+	}
+	transientErrorCodes = map[pq.ErrorCode]bool{
+		"40001": true, // serialization_failure
+		"40P01": true, // deadlock_detected
+		"55P03": true, // lock_not_available
+		"57P01": true, // admin_shutdown
+		"57P02": true, // crash_shutdown
+		"57P03": true, // cannot_connect_now
+	}
+)
+
+// IsTransientError определяет, является ли ошибка временной (сбой соединения,
+// конфликт сериализации и т.п.), после которой операцию можно повторить.
+// Нарушения ограничений и прочие ошибки данных считаются постоянными
+func IsTransientError(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return transientErrorClasses[pqErr.Code.Class()] || transientErrorCodes[pqErr.Code]
+	}
+
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, sql.ErrConnDone) ||
+		errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, context.DeadlineExceeded) ||
+		errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNRESET) {
+		return true
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
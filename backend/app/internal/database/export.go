@@ -0,0 +1,74 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"order-service/internal/models"
+	"strings"
+	"time"
+)
+
+// exportChunkSize - число заказов, которые StreamOrders держит в памяти одновременно
+const exportChunkSize = 500
+
+// StreamOrders передает в fn заказы, подходящие под фильтр, в порядке date_created.
+// Заказы читаются частями по exportChunkSize (keyset-пагинация по (date_created, order_uid)),
+// поэтому в памяти не держится вся выборка. Каждая часть читается своей короткой read-only
+// транзакцией, а fn вызывается уже после ее завершения: медленный получатель выгрузки не держит
+// соединение пула и старый снимок данных, мешающий VACUUM. Поэтому выгрузка не соответствует
+// одному снимку: заказ, сохраненный во время выгрузки, попадет в нее, только если он идет после
+// уже выгруженной части. Ошибка fn прерывает выгрузку и возвращается как есть
+func (p *PostgresDB) StreamOrders(ctx context.Context, filter OrderFilter, fn func(*models.OrderFull) error) error {
+	var lastDate time.Time
+	var lastUID string
+	for {
+		conditions, args := filter.conditions(nil)
+		if lastUID != "" {
+			args = append(args, lastDate, lastUID)
+			conditions = append(conditions, fmt.Sprintf("(o.date_created, o.order_uid) > ($%d, $%d)", len(args)-1, len(args)))
+		}
+
+		query := fullOrderSelect
+		if len(conditions) > 0 {
+			query += " WHERE " + strings.Join(conditions, " AND ")
+		}
+		query += fmt.Sprintf(" ORDER BY o.date_created, o.order_uid LIMIT %d", exportChunkSize)
+
+		orders, err := p.queryExportChunk(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+
+		for _, orderFull := range orders {
+			if err := fn(orderFull); err != nil {
+				return err
+			}
+		}
+
+		if len(orders) < exportChunkSize {
+			return nil
+		}
+		last := orders[len(orders)-1]
+		lastDate, lastUID = last.DateCreated, last.OrderUID
+	}
+}
+
+// queryExportChunk читает часть выгрузки в read-only транзакции REPEATABLE READ, чтобы заказы
+// и их товары, которые читаются разными запросами, соответствовали одному снимку
+func (p *PostgresDB) queryExportChunk(ctx context.Context, query string, args ...interface{}) ([]*models.OrderFull, error) {
+	tx, err := p.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	orders, err := queryFullOrders(ctx, tx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return orders, nil
+}
@@ -0,0 +1,103 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// OrderFilter - условия отбора заказов. Пустые поля не ограничивают выборку.
+// Каждому условию соответствует индекс из 001_create_orders_tables.sql
+type OrderFilter struct {
+	DateFrom        time.Time // date_created >= DateFrom
+	DateTo          time.Time // date_created < DateTo
+	CustomerID      string
+	TrackNumber     string
+	DeliveryService string
+	City            string // deliveries.city
+	Region          string // deliveries.region
+	Provider        string // payments.provider
+	Brand           string // хотя бы один товар заказа этого бренда
+}
+
+// dateLayout - формат даты без времени в границах периода
+const dateLayout = "2006-01-02"
+
+// ParseOrderFilter собирает фильтр из строковых параметров (HTTP query или флагов CLI).
+// from и to принимают RFC 3339 или дату YYYY-MM-DD; дата в to включает весь день
+func ParseOrderFilter(from, to, customerID, deliveryService string) (OrderFilter, error) {
+	filter := OrderFilter{
+		CustomerID:      strings.TrimSpace(customerID),
+		DeliveryService: strings.TrimSpace(deliveryService),
+	}
+
+	var err error
+	if filter.DateFrom, err = parseBound(from, false); err != nil {
+		return filter, fmt.Errorf("invalid from: %w", err)
+	}
+	if filter.DateTo, err = parseBound(to, true); err != nil {
+		return filter, fmt.Errorf("invalid to: %w", err)
+	}
+	if !filter.DateFrom.IsZero() && !filter.DateTo.IsZero() && !filter.DateFrom.Before(filter.DateTo) {
+		return filter, fmt.Errorf("from must be before to")
+	}
+	return filter, nil
+}
+
+// parseBound разбирает границу диапазона дат. Для верхней границы дата без времени
+// означает начало следующего дня, так как DateTo не включается в выборку
+func parseBound(value string, upper bool) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(dateLayout, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("expected RFC 3339 time or YYYY-MM-DD date, got %q", value)
+	}
+	if upper {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, nil
+}
+
+// conditions возвращает условия WHERE для фильтра. Параметры нумеруются после уже переданных args
+func (f OrderFilter) conditions(args []interface{}) ([]string, []interface{}) {
+	var conditions []string
+	add := func(condition string, value interface{}) {
+		args = append(args, value)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	if !f.DateFrom.IsZero() {
+		add("o.date_created >= $%d", f.DateFrom)
+	}
+	if !f.DateTo.IsZero() {
+		add("o.date_created < $%d", f.DateTo)
+	}
+	if f.CustomerID != "" {
+		add("o.customer_id = $%d", f.CustomerID)
+	}
+	if f.TrackNumber != "" {
+		add("o.track_number = $%d", f.TrackNumber)
+	}
+	if f.DeliveryService != "" {
+		add("o.delivery_service = $%d", f.DeliveryService)
+	}
+	if f.City != "" {
+		add("d.city = $%d", f.City)
+	}
+	if f.Region != "" {
+		add("d.region = $%d", f.Region)
+	}
+	if f.Provider != "" {
+		add("p.provider = $%d", f.Provider)
+	}
+	if f.Brand != "" {
+		add("EXISTS (SELECT 1 FROM order_items i WHERE i.order_uid = o.order_uid AND i.brand = $%d)", f.Brand)
+	}
+	return conditions, args
+}
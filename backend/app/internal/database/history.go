@@ -0,0 +1,90 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"order-service/internal/models"
+	"time"
+)
+
+// Источники изменения статуса в order_status_history
+const (
+	StatusSourceCreated     = "created"      // Начальный статус при создании заказа
+	StatusSourceOverwrite   = "overwrite"    // Заказ перезаписан политикой ConflictOverwrite
+	StatusSourceStatusEvent = "status_event" // Событие order_status_changed
+)
+
+var historyColumns = []string{"order_uid", "chrt_id", "old_status", "new_status", "source", "changed_at"}
+
+// historyRow формирует строку order_status_history для insertRows.
+// oldStatus равен nil, если у товара не было предыдущего статуса
+func historyRow(orderUID string, chrtID int64, oldStatus *int, newStatus int, source string, changedAt time.Time) []interface{} {
+	var old sql.NullInt64
+	if oldStatus != nil {
+		old = sql.NullInt64{Int64: int64(*oldStatus), Valid: true}
+	}
+	return []interface{}{orderUID, chrtID, old, newStatus, source, changedAt}
+}
+
+// itemStatusRows формирует записи истории для товаров сохраняемого заказа.
+// previous содержит статусы товаров до перезаписи (nil для нового заказа);
+// товары, статус которых не изменился, пропускаются
+func itemStatusRows(orderFull *models.OrderFull, previous map[int64]int, source string, changedAt time.Time) [][]interface{} {
+	rows := make([][]interface{}, 0, len(orderFull.Items))
+	for _, item := range orderFull.Items {
+		var oldStatus *int
+		if status, ok := previous[item.ChrtID]; ok {
+			if status == item.Status {
+				continue
+			}
+			oldStatus = &status
+		}
+		rows = append(rows, historyRow(orderFull.OrderUID, item.ChrtID, oldStatus, item.Status, source, changedAt))
+	}
+	return rows
+}
+
+// GetOrderStatusHistory возвращает историю статусов заказа в хронологическом порядке
+func (p *PostgresDB) GetOrderStatusHistory(orderUID string) ([]models.OrderStatusHistory, error) {
+	query := `
+		SELECT id, order_uid, chrt_id, old_status, new_status, source, changed_at
+		FROM order_status_history
+		WHERE order_uid = $1
+		ORDER BY changed_at, id
+	`
+	rows, err := p.db.Query(query, orderUID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get order status history: %w", err)
+	}
+	defer rows.Close()
+
+	history := make([]models.OrderStatusHistory, 0)
+	for rows.Next() {
+		var entry models.OrderStatusHistory
+		var oldStatus sql.NullInt64
+		err := rows.Scan(&entry.ID, &entry.OrderUID, &entry.ChrtID, &oldStatus,
+			&entry.NewStatus, &entry.Source, &entry.ChangedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan order status history: %w", err)
+		}
+		if oldStatus.Valid {
+			status := int(oldStatus.Int64)
+			entry.OldStatus = &status
+		}
+		history = append(history, entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate order status history: %w", err)
+	}
+
+	return history, nil
+}
+
+// insertStatusHistory добавляет записи истории статусов в рамках транзакции
+func insertStatusHistory(tx *sql.Tx, rows [][]interface{}) error {
+	if err := insertRows(tx, "order_status_history", historyColumns, rows, "", nil); err != nil {
+		return fmt.Errorf("failed to insert order status history: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package database
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"order-service/internal/models"
+	"strings"
+	"time"
+)
+
+// ErrInvalidCursor возвращается, если курсор пагинации не удалось разобрать
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// OrderCursor - позиция в списке заказов, упорядоченном по (created_at, order_uid) от новых к старым.
+// Клиенту передается в непрозрачном виде (Encode)
+type OrderCursor struct {
+	CreatedAt time.Time `json:"c"`
+	OrderUID  string    `json:"u"`
+}
+
+// Encode возвращает курсор в виде непрозрачной строки
+func (c OrderCursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeOrderCursor разбирает курсор, полученный от клиента
+func DecodeOrderCursor(value string) (*OrderCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var cursor OrderCursor
+	if err := json.Unmarshal(data, &cursor); err != nil || cursor.OrderUID == "" {
+		return nil, ErrInvalidCursor
+	}
+	return &cursor, nil
+}
+
+// ListOrders возвращает страницу заказов, подходящих под фильтр, от новых к старым.
+// Пагинация keyset по (created_at, order_uid): after - курсор последнего заказа предыдущей
+// страницы (nil для первой). Возвращает курсор следующей страницы или nil, если страница последняя
+func (p *PostgresDB) ListOrders(ctx context.Context, filter OrderFilter, limit int, after *OrderCursor) ([]*models.OrderFull, *OrderCursor, error) {
+	conditions, args := filter.conditions(nil)
+	if after != nil {
+		args = append(args, after.CreatedAt, after.OrderUID)
+		conditions = append(conditions, fmt.Sprintf("(o.created_at, o.order_uid) < ($%d, $%d)", len(args)-1, len(args)))
+	}
+
+	query := fullOrderSelect
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	// Лишняя строка показывает, есть ли следующая страница
+	query += fmt.Sprintf(" ORDER BY o.created_at DESC, o.order_uid DESC LIMIT %d", limit+1)
+
+	orders, err := queryFullOrders(ctx, p.db, query, args...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(orders) <= limit {
+		return orders, nil, nil
+	}
+	orders = orders[:limit]
+	last := orders[limit-1]
+	return orders, &OrderCursor{CreatedAt: last.CreatedAt, OrderUID: last.OrderUID}, nil
+}
@@ -0,0 +1,148 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"order-service/internal/models"
+
+	"github.com/lib/pq"
+)
+
+// queryer - общий интерфейс *sql.DB и *sql.Tx для чтения заказов
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// fullOrderSelect выбирает заказ вместе с доставкой и платежом одним запросом.
+// Поля, которые могут быть NULL, приводятся к пустым значениям, как их видит API
+const fullOrderSelect = `
+	SELECT o.order_uid, o.track_number, o.entry, COALESCE(o.locale, ''), COALESCE(o.internal_signature, ''),
+		   o.customer_id, o.delivery_service, o.shardkey, o.sm_id, o.date_created, o.oof_shard,
+		   o.created_at, o.updated_at, o.validation_warnings,
+		   d.id, COALESCE(d.name, ''), COALESCE(d.phone, ''), COALESCE(d.zip, ''), COALESCE(d.city, ''),
+		   COALESCE(d.address, ''), COALESCE(d.region, ''), COALESCE(d.email, ''), d.created_at,
+		   p.id, COALESCE(p.transaction, ''), COALESCE(p.request_id, ''), COALESCE(p.currency, ''),
+		   COALESCE(p.provider, ''), COALESCE(p.amount, 0), COALESCE(p.payment_dt, 0), COALESCE(p.bank, ''),
+		   COALESCE(p.delivery_cost, 0), COALESCE(p.goods_total, 0), COALESCE(p.custom_fee, 0), p.created_at
+	FROM orders o
+	LEFT JOIN deliveries d ON d.order_uid = o.order_uid
+	LEFT JOIN payments p ON p.order_uid = o.order_uid
+`
+
+// queryFullOrders выполняет запрос на основе fullOrderSelect и загружает товары
+// найденных заказов одним дополнительным запросом
+func queryFullOrders(ctx context.Context, q queryer, query string, args ...interface{}) ([]*models.OrderFull, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query orders: %w", err)
+	}
+	defer rows.Close()
+
+	var orders []*models.OrderFull
+	for rows.Next() {
+		orderFull, err := scanFullOrder(rows)
+		if err != nil {
+			return nil, err
+		}
+		orders = append(orders, orderFull)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate orders: %w", err)
+	}
+
+	if err := loadOrderItems(ctx, q, orders); err != nil {
+		return nil, err
+	}
+	// Суммы хранятся без валюты, ее задает платеж заказа
+	for _, orderFull := range orders {
+		if orderFull.Payment != nil {
+			orderFull.SetCurrency(orderFull.Payment.Currency)
+		}
+	}
+	return orders, nil
+}
+
+// scanFullOrder читает строку fullOrderSelect
+func scanFullOrder(rows *sql.Rows) (*models.OrderFull, error) {
+	orderFull := &models.OrderFull{}
+	delivery := &models.Delivery{}
+	payment := &models.Payment{}
+
+	var warnings []byte
+	var deliveryID, paymentID sql.NullInt64
+	var deliveryCreatedAt, paymentCreatedAt sql.NullTime
+	err := rows.Scan(
+		&orderFull.OrderUID, &orderFull.TrackNumber, &orderFull.Entry, &orderFull.Locale,
+		&orderFull.InternalSignature, &orderFull.CustomerID, &orderFull.DeliveryService,
+		&orderFull.Shardkey, &orderFull.SmID, &orderFull.DateCreated, &orderFull.OofShard,
+		&orderFull.CreatedAt, &orderFull.UpdatedAt, &warnings,
+		&deliveryID, &delivery.Name, &delivery.Phone, &delivery.Zip, &delivery.City,
+		&delivery.Address, &delivery.Region, &delivery.Email, &deliveryCreatedAt,
+		&paymentID, &payment.Transaction, &payment.RequestID, &payment.Currency,
+		&payment.Provider, &payment.Amount, &payment.PaymentDt, &payment.Bank,
+		&payment.DeliveryCost, &payment.GoodsTotal, &payment.CustomFee, &paymentCreatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan order: %w", err)
+	}
+	if orderFull.ValidationWarnings, err = parseValidationWarnings(warnings); err != nil {
+		return nil, err
+	}
+
+	if deliveryID.Valid {
+		delivery.ID = int(deliveryID.Int64)
+		delivery.OrderUID = orderFull.OrderUID
+		delivery.CreatedAt = deliveryCreatedAt.Time
+		orderFull.Delivery = delivery
+	}
+	if paymentID.Valid {
+		payment.ID = int(paymentID.Int64)
+		payment.OrderUID = orderFull.OrderUID
+		payment.CreatedAt = paymentCreatedAt.Time
+		orderFull.Payment = payment
+	}
+
+	return orderFull, nil
+}
+
+// loadOrderItems загружает товары всех заказов одним запросом
+func loadOrderItems(ctx context.Context, q queryer, orders []*models.OrderFull) error {
+	if len(orders) == 0 {
+		return nil
+	}
+
+	byUID := make(map[string]*models.OrderFull, len(orders))
+	uids := make([]string, 0, len(orders))
+	for _, orderFull := range orders {
+		byUID[orderFull.OrderUID] = orderFull
+		uids = append(uids, orderFull.OrderUID)
+	}
+
+	itemsQuery := `
+		SELECT id, order_uid, chrt_id, track_number, price, rid, name, COALESCE(sale, 0), size,
+			   total_price, nm_id, brand, status, created_at
+		FROM order_items WHERE order_uid = ANY($1) ORDER BY id
+	`
+	rows, err := q.QueryContext(ctx, itemsQuery, pq.Array(uids))
+	if err != nil {
+		return fmt.Errorf("failed to get order items: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var item models.OrderItem
+		err := rows.Scan(
+			&item.ID, &item.OrderUID, &item.ChrtID, &item.TrackNumber, &item.Price,
+			&item.Rid, &item.Name, &item.Sale, &item.Size, &item.TotalPrice,
+			&item.NmID, &item.Brand, &item.Status, &item.CreatedAt,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to scan order item: %w", err)
+		}
+		if orderFull, ok := byUID[item.OrderUID]; ok {
+			orderFull.Items = append(orderFull.Items, item)
+		}
+	}
+	return rows.Err()
+}
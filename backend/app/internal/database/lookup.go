@@ -0,0 +1,41 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"order-service/internal/models"
+)
+
+// MaxTrackNumberOrders ограничивает число заказов, возвращаемых по одному трек-номеру
+const MaxTrackNumberOrders = 100
+
+// GetOrdersByTrackNumber возвращает заказы с трек-номером от новых к старым, не больше
+// MaxTrackNumberOrders (индекс idx_orders_track_number)
+func (p *PostgresDB) GetOrdersByTrackNumber(ctx context.Context, trackNumber string) ([]*models.OrderFull, error) {
+	query := fullOrderSelect + fmt.Sprintf(" WHERE o.track_number = $1 ORDER BY o.created_at DESC, o.order_uid DESC LIMIT %d", MaxTrackNumberOrders)
+	orders, err := queryFullOrders(ctx, p.db, query, trackNumber)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get orders by track number: %w", err)
+	}
+	return orders, nil
+}
+
+// GetOrdersByCustomer возвращает страницу заказов клиента от новых к старым
+// (индекс idx_orders_customer_id), пагинация как у ListOrders
+func (p *PostgresDB) GetOrdersByCustomer(ctx context.Context, customerID string, limit int, after *OrderCursor) ([]*models.OrderFull, *OrderCursor, error) {
+	return p.ListOrders(ctx, OrderFilter{CustomerID: customerID}, limit, after)
+}
+
+// GetOrderByTransaction возвращает заказ по ID транзакции платежа (индекс idx_payments_transaction).
+// Если транзакция встречается в нескольких заказах, возвращается самый новый. nil, если не найден
+func (p *PostgresDB) GetOrderByTransaction(ctx context.Context, transaction string) (*models.OrderFull, error) {
+	query := fullOrderSelect + " WHERE p.transaction = $1 ORDER BY o.created_at DESC, o.order_uid DESC LIMIT 1"
+	orders, err := queryFullOrders(ctx, p.db, query, transaction)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get order by transaction: %w", err)
+	}
+	if len(orders) == 0 {
+		return nil, nil
+	}
+	return orders[0], nil
+}
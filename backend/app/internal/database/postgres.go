@@ -1,6 +1,7 @@
 package database
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"order-service/internal/models"
@@ -18,8 +19,24 @@ type PostgresDB struct {
 
 type OrderRepository interface {
 	CreateOrder(orderFull *models.OrderFull) error
+	CreateOrders(batch []*models.OrderFull) ([]string, error)
+	UpsertOrder(orderFull *models.OrderFull, policy ConflictPolicy) (UpsertResult, error)
+	UpdateOrderStatus(change *models.OrderStatusChange) error
+	GetOrderStatusHistory(orderUID string) ([]models.OrderStatusHistory, error)
 	GetOrderByUID(orderUID string) (*models.OrderFull, error)
 	GetAllOrders(limit int) ([]models.OrderFull, error)
+	ListOrders(ctx context.Context, filter OrderFilter, limit int, after *OrderCursor) ([]*models.OrderFull, *OrderCursor, error)
+	GetOrdersByTrackNumber(ctx context.Context, trackNumber string) ([]*models.OrderFull, error)
+	GetOrdersByCustomer(ctx context.Context, customerID string, limit int, after *OrderCursor) ([]*models.OrderFull, *OrderCursor, error)
+	GetOrderByTransaction(ctx context.Context, transaction string) (*models.OrderFull, error)
+	SearchOrders(ctx context.Context, query string, limit int) ([]SearchHit, error)
+	RevenueByPeriod(ctx context.Context, filter AnalyticsFilter, interval Interval) ([]RevenuePoint, error)
+	OrdersByDeliveryService(ctx context.Context, filter AnalyticsFilter) ([]DeliveryServiceStats, error)
+	TopBrands(ctx context.Context, filter AnalyticsFilter, metric TopMetric, limit int) ([]BrandStats, error)
+	TopProducts(ctx context.Context, filter AnalyticsFilter, metric TopMetric, limit int) ([]ProductStats, error)
+	BasketStats(ctx context.Context, filter AnalyticsFilter) ([]BasketStats, error)
+	SaleDistribution(ctx context.Context, filter AnalyticsFilter) ([]SaleBucket, error)
+	StreamOrders(ctx context.Context, filter OrderFilter, fn func(*models.OrderFull) error) error
 	OrderExists(orderUID string) (bool, error)
 }
 
@@ -61,28 +78,63 @@ func (p *PostgresDB) CreateOrder(orderFull *models.OrderFull) error {
 	}
 	defer tx.Rollback()
 
+	warnings, err := validationWarningsValue(orderFull.ValidationWarnings)
+	if err != nil {
+		return err
+	}
+
 	// 1. Вставляем основной заказ
 	orderQuery := `
 		INSERT INTO orders (order_uid, track_number, entry, locale, internal_signature, 
-						   customer_id, delivery_service, shardkey, sm_id, date_created, oof_shard)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+						   customer_id, delivery_service, shardkey, sm_id, date_created, oof_shard,
+						   content_hash, validation_warnings)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+		RETURNING created_at, updated_at
 	`
-	_, err = tx.Exec(orderQuery,
+	// Время записи нужно модели, которая дальше попадает в кеш
+	err = tx.QueryRow(orderQuery,
 		orderFull.OrderUID, orderFull.TrackNumber, orderFull.Entry, orderFull.Locale,
 		orderFull.InternalSignature, orderFull.CustomerID, orderFull.DeliveryService,
 		orderFull.Shardkey, orderFull.SmID, orderFull.DateCreated, orderFull.OofShard,
-	)
+		orderFull.ContentHash(), warnings,
+	).Scan(&orderFull.CreatedAt, &orderFull.UpdatedAt)
 	if err != nil {
 		return fmt.Errorf("failed to insert order: %w", err)
 	}
 
-	// 2. Вставляем данные доставки
+	// 2-4. Вставляем доставку, платеж и товары
+	if err := insertOrderDetails(tx, orderFull); err != nil {
+		return err
+	}
+
+	// 5. Записываем начальные статусы товаров в историю
+	if err := insertStatusHistory(tx, itemStatusRows(orderFull, nil, StatusSourceCreated, orderFull.DateCreated)); err != nil {
+		return err
+	}
+
+	// 6. Добавляем заказ в дневные агрегаты аналитики
+	if err := addRollups(tx, []string{orderFull.OrderUID}, 1); err != nil {
+		return err
+	}
+
+	// Коммитим транзакцию
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	p.logger.WithField("order_uid", orderFull.OrderUID).Info("Order saved successfully")
+	return nil
+}
+
+// insertOrderDetails вставляет данные доставки, платежа и товары заказа в рамках транзакции
+func insertOrderDetails(tx *sql.Tx, orderFull *models.OrderFull) error {
+	// Вставляем данные доставки
 	if orderFull.Delivery != nil {
 		deliveryQuery := `
 			INSERT INTO deliveries (order_uid, name, phone, zip, city, address, region, email)
 			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
 		`
-		_, err = tx.Exec(deliveryQuery,
+		_, err := tx.Exec(deliveryQuery,
 			orderFull.OrderUID, orderFull.Delivery.Name, orderFull.Delivery.Phone,
 			orderFull.Delivery.Zip, orderFull.Delivery.City, orderFull.Delivery.Address,
 			orderFull.Delivery.Region, orderFull.Delivery.Email,
@@ -92,14 +144,14 @@ func (p *PostgresDB) CreateOrder(orderFull *models.OrderFull) error {
 		}
 	}
 
-	// 3. Вставляем платежные данные
+	// Вставляем платежные данные
 	if orderFull.Payment != nil {
 		paymentQuery := `
 			INSERT INTO payments (order_uid, transaction, request_id, currency, provider, 
 								 amount, payment_dt, bank, delivery_cost, goods_total, custom_fee)
 			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
 		`
-		_, err = tx.Exec(paymentQuery,
+		_, err := tx.Exec(paymentQuery,
 			orderFull.OrderUID, orderFull.Payment.Transaction, orderFull.Payment.RequestID,
 			orderFull.Payment.Currency, orderFull.Payment.Provider, orderFull.Payment.Amount,
 			orderFull.Payment.PaymentDt, orderFull.Payment.Bank, orderFull.Payment.DeliveryCost,
@@ -110,14 +162,14 @@ func (p *PostgresDB) CreateOrder(orderFull *models.OrderFull) error {
 		}
 	}
 
-	// 4. Вставляем товары
+	// Вставляем товары
 	for _, item := range orderFull.Items {
 		itemQuery := `
 			INSERT INTO order_items (order_uid, chrt_id, track_number, price, rid, name, 
 								   sale, size, total_price, nm_id, brand, status)
 			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
 		`
-		_, err = tx.Exec(itemQuery,
+		_, err := tx.Exec(itemQuery,
 			orderFull.OrderUID, item.ChrtID, item.TrackNumber, item.Price, item.Rid,
 			item.Name, item.Sale, item.Size, item.TotalPrice, item.NmID, item.Brand, item.Status,
 		)
@@ -126,133 +178,36 @@ func (p *PostgresDB) CreateOrder(orderFull *models.OrderFull) error {
 		}
 	}
 
-	// Коммитим транзакцию
-	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("failed to commit transaction: %w", err)
-	}
-
-	p.logger.WithField("order_uid", orderFull.OrderUID).Info("Order saved successfully")
 	return nil
 }
 
-// GetOrderByUID получает полную информацию о заказе по UID
+// GetOrderByUID получает полную информацию о заказе по UID: заказ с доставкой и платежом
+// одним запросом и товары вторым
 func (p *PostgresDB) GetOrderByUID(orderUID string) (*models.OrderFull, error) {
-	orderFull := &models.OrderFull{}
-
-	// 1. Получаем основной заказ
-	orderQuery := `
-		SELECT order_uid, track_number, entry, locale, internal_signature, customer_id,
-			   delivery_service, shardkey, sm_id, date_created, oof_shard, created_at, updated_at
-		FROM orders WHERE order_uid = $1
-	`
-	err := p.db.QueryRow(orderQuery, orderUID).Scan(
-		&orderFull.OrderUID, &orderFull.TrackNumber, &orderFull.Entry, &orderFull.Locale,
-		&orderFull.InternalSignature, &orderFull.CustomerID, &orderFull.DeliveryService,
-		&orderFull.Shardkey, &orderFull.SmID, &orderFull.DateCreated, &orderFull.OofShard,
-		&orderFull.CreatedAt, &orderFull.UpdatedAt,
-	)
-	if err == sql.ErrNoRows {
-		return nil, nil
-	}
+	orders, err := queryFullOrders(context.Background(), p.db, fullOrderSelect+" WHERE o.order_uid = $1", orderUID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get order: %w", err)
 	}
-
-	// 2. Получаем данные доставки
-	deliveryQuery := `
-		SELECT id, order_uid, name, phone, zip, city, address, region, email, created_at
-		FROM deliveries WHERE order_uid = $1
-	`
-	delivery := &models.Delivery{}
-	err = p.db.QueryRow(deliveryQuery, orderUID).Scan(
-		&delivery.ID, &delivery.OrderUID, &delivery.Name, &delivery.Phone, &delivery.Zip,
-		&delivery.City, &delivery.Address, &delivery.Region, &delivery.Email, &delivery.CreatedAt,
-	)
-	if err == nil {
-		orderFull.Delivery = delivery
-	} else if err != sql.ErrNoRows {
-		return nil, fmt.Errorf("failed to get delivery: %w", err)
-	}
-
-	// 3. Получаем платежные данные
-	paymentQuery := `
-		SELECT id, order_uid, transaction, request_id, currency, provider, amount, payment_dt,
-			   bank, delivery_cost, goods_total, custom_fee, created_at
-		FROM payments WHERE order_uid = $1
-	`
-	payment := &models.Payment{}
-	err = p.db.QueryRow(paymentQuery, orderUID).Scan(
-		&payment.ID, &payment.OrderUID, &payment.Transaction, &payment.RequestID,
-		&payment.Currency, &payment.Provider, &payment.Amount, &payment.PaymentDt,
-		&payment.Bank, &payment.DeliveryCost, &payment.GoodsTotal, &payment.CustomFee,
-		&payment.CreatedAt,
-	)
-	if err == nil {
-		orderFull.Payment = payment
-	} else if err != sql.ErrNoRows {
-		return nil, fmt.Errorf("failed to get payment: %w", err)
-	}
-
-	// 4. Получаем товары
-	itemsQuery := `
-		SELECT id, order_uid, chrt_id, track_number, price, rid, name, sale, size,
-			   total_price, nm_id, brand, status, created_at
-		FROM order_items WHERE order_uid = $1 ORDER BY id
-	`
-	rows, err := p.db.Query(itemsQuery, orderUID)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get order items: %w", err)
-	}
-	defer rows.Close()
-
-	var items []models.OrderItem
-	for rows.Next() {
-		var item models.OrderItem
-		err := rows.Scan(
-			&item.ID, &item.OrderUID, &item.ChrtID, &item.TrackNumber, &item.Price,
-			&item.Rid, &item.Name, &item.Sale, &item.Size, &item.TotalPrice,
-			&item.NmID, &item.Brand, &item.Status, &item.CreatedAt,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan order item: %w", err)
-		}
-		items = append(items, item)
+	if len(orders) == 0 {
+		return nil, nil
 	}
-	orderFull.Items = items
-
-	return orderFull, nil
+	return orders[0], nil
 }
 
-// GetAllOrders получает все заказы с ограничением
+// GetAllOrders получает последние заказы с ограничением. Заказы, доставка и платежи
+// читаются одним запросом, товары всех заказов - вторым, независимо от числа заказов
 func (p *PostgresDB) GetAllOrders(limit int) ([]models.OrderFull, error) {
-	query := `
-		SELECT order_uid FROM orders 
-		ORDER BY created_at DESC LIMIT $1
-	`
-	rows, err := p.db.Query(query, limit)
+	query := fullOrderSelect + " ORDER BY o.created_at DESC LIMIT $1"
+	orders, err := queryFullOrders(context.Background(), p.db, query, limit)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get order list: %w", err)
 	}
-	defer rows.Close()
 
-	var orders []models.OrderFull
-	for rows.Next() {
-		var orderUID string
-		if err := rows.Scan(&orderUID); err != nil {
-			return nil, fmt.Errorf("failed to scan order UID: %w", err)
-		}
-
-		orderFull, err := p.GetOrderByUID(orderUID)
-		if err != nil {
-			p.logger.WithError(err).WithField("order_uid", orderUID).Error("Failed to get full order")
-			continue
-		}
-		if orderFull != nil {
-			orders = append(orders, *orderFull)
-		}
+	result := make([]models.OrderFull, len(orders))
+	for i, orderFull := range orders {
+		result[i] = *orderFull
 	}
-
-	return orders, nil
+	return result, nil
 }
 
 // OrderExists проверяет существование заказа
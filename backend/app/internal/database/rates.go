@@ -0,0 +1,37 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"order-service/internal/currency"
+)
+
+// LoadRates читает курсы валют из таблицы exchange_rates (миграция 009)
+func (p *PostgresDB) LoadRates(ctx context.Context) ([]currency.Rate, error) {
+	rows, err := p.db.QueryContext(ctx, `
+		SELECT currency, rate::text, to_char(effective_from, 'YYYY-MM-DD')
+		FROM exchange_rates
+		ORDER BY currency, effective_from
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query exchange rates: %w", err)
+	}
+	defer rows.Close()
+
+	var rates []currency.Rate
+	for rows.Next() {
+		var code, value, effectiveFrom string
+		if err := rows.Scan(&code, &value, &effectiveFrom); err != nil {
+			return nil, fmt.Errorf("failed to scan exchange rate: %w", err)
+		}
+		rate, err := currency.ParseRate(code, value, effectiveFrom)
+		if err != nil {
+			return nil, err
+		}
+		rates = append(rates, rate)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate exchange rates: %w", err)
+	}
+	return rates, nil
+}
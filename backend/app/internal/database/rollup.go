@@ -0,0 +1,161 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/sirupsen/logrus"
+)
+
+// rollupDay - день заказа в дневных агрегатах (миграция 008)
+const rollupDay = "(COALESCE(o.date_created, o.created_at) AT TIME ZONE 'UTC')::date"
+
+// rollupTables - таблицы дневных агрегатов в порядке обновления
+var rollupTables = []string{"order_daily_rollups", "brand_daily_rollups", "product_daily_rollups", "sale_daily_rollups"}
+
+// rollupStatements возвращают запросы, добавляющие в агрегаты вклад заказов, отобранных условием where.
+// $1 - знак вклада (1 или -1), параметры условия начинаются с $2. Строки вставляются в порядке
+// первичного ключа, чтобы параллельные транзакции блокировали их в одном порядке.
+// Миграция 008 заполняет агрегаты по уже сохраненным заказам копией этих запросов
+var rollupStatements = []string{`
+	INSERT INTO order_daily_rollups AS r (day, delivery_service, region, currency,
+		orders, items, amount, goods_total, delivery_cost)
+	SELECT ` + rollupDay + `, o.delivery_service, COALESCE(d.region, ''), p.currency,
+		$1 * COUNT(*), $1 * SUM((SELECT COUNT(*) FROM order_items i WHERE i.order_uid = o.order_uid)),
+		$1 * SUM(p.amount), $1 * SUM(p.goods_total), $1 * SUM(p.delivery_cost)
+	FROM orders o
+	JOIN payments p ON p.order_uid = o.order_uid
+	LEFT JOIN deliveries d ON d.order_uid = o.order_uid
+	WHERE %s
+	GROUP BY 1, 2, 3, 4
+	ORDER BY 1, 2, 3, 4
+	ON CONFLICT (day, delivery_service, region, currency) DO UPDATE SET
+		orders = r.orders + EXCLUDED.orders, items = r.items + EXCLUDED.items,
+		amount = r.amount + EXCLUDED.amount, goods_total = r.goods_total + EXCLUDED.goods_total,
+		delivery_cost = r.delivery_cost + EXCLUDED.delivery_cost
+`, `
+	INSERT INTO brand_daily_rollups AS r (day, delivery_service, region, brand, currency,
+		units, revenue, discount, orders)
+	SELECT ` + rollupDay + `, o.delivery_service, COALESCE(d.region, ''), i.brand, p.currency,
+		$1 * COUNT(*), $1 * SUM(i.total_price), $1 * SUM(i.price - i.total_price), $1 * COUNT(DISTINCT o.order_uid)
+	FROM orders o
+	JOIN payments p ON p.order_uid = o.order_uid
+	JOIN order_items i ON i.order_uid = o.order_uid
+	LEFT JOIN deliveries d ON d.order_uid = o.order_uid
+	WHERE %s
+	GROUP BY 1, 2, 3, 4, 5
+	ORDER BY 1, 2, 3, 4, 5
+	ON CONFLICT (day, delivery_service, region, brand, currency) DO UPDATE SET
+		units = r.units + EXCLUDED.units, revenue = r.revenue + EXCLUDED.revenue,
+		discount = r.discount + EXCLUDED.discount, orders = r.orders + EXCLUDED.orders
+`, `
+	INSERT INTO product_daily_rollups AS r (day, nm_id, currency, name, brand, units, revenue, orders)
+	SELECT ` + rollupDay + `, i.nm_id, p.currency, MAX(i.name), MAX(i.brand),
+		$1 * COUNT(*), $1 * SUM(i.total_price), $1 * COUNT(DISTINCT o.order_uid)
+	FROM orders o
+	JOIN payments p ON p.order_uid = o.order_uid
+	JOIN order_items i ON i.order_uid = o.order_uid
+	WHERE %s
+	GROUP BY 1, 2, 3
+	ORDER BY 1, 2, 3
+	ON CONFLICT (day, nm_id, currency) DO UPDATE SET
+		name = CASE WHEN EXCLUDED.units > 0 THEN EXCLUDED.name ELSE r.name END,
+		brand = CASE WHEN EXCLUDED.units > 0 THEN EXCLUDED.brand ELSE r.brand END,
+		units = r.units + EXCLUDED.units, revenue = r.revenue + EXCLUDED.revenue, orders = r.orders + EXCLUDED.orders
+`, `
+	INSERT INTO sale_daily_rollups AS r (day, sale_from, currency, units, revenue, discount)
+	SELECT ` + rollupDay + `,
+		CASE WHEN COALESCE(i.sale, 0) <= 0 THEN 0 ELSE LEAST((i.sale - 1) / 10, 9) * 10 + 1 END,
+		p.currency, $1 * COUNT(*), $1 * SUM(i.total_price), $1 * SUM(i.price - i.total_price)
+	FROM orders o
+	JOIN payments p ON p.order_uid = o.order_uid
+	JOIN order_items i ON i.order_uid = o.order_uid
+	WHERE %s
+	GROUP BY 1, 2, 3
+	ORDER BY 1, 2, 3
+	ON CONFLICT (day, sale_from, currency) DO UPDATE SET
+		units = r.units + EXCLUDED.units, revenue = r.revenue + EXCLUDED.revenue,
+		discount = r.discount + EXCLUDED.discount
+`}
+
+// addRollups добавляет заказы в дневные агрегаты (sign = 1) или убирает их оттуда (sign = -1).
+// Вызывается в транзакции записи заказа: для удаления - до изменения заказа, для добавления - после
+// вставки доставки, платежа и товаров
+func addRollups(tx *sql.Tx, orderUIDs []string, sign int) error {
+	if len(orderUIDs) == 0 {
+		return nil
+	}
+	for i, statement := range rollupStatements {
+		if _, err := tx.Exec(fmt.Sprintf(statement, "o.order_uid = ANY($2)"), sign, pq.Array(orderUIDs)); err != nil {
+			return fmt.Errorf("failed to update %s: %w", rollupTables[i], err)
+		}
+	}
+	return nil
+}
+
+// RebuildRollups пересчитывает дневные агрегаты за дни [from, to) по сохраненным заказам.
+// Нулевые границы не ограничивают период. Таблицы агрегатов блокируются на запись до конца
+// пересчета, поэтому заказы, сохраняемые в это время, ждут его и не учитываются дважды
+func (p *PostgresDB) RebuildRollups(ctx context.Context, from, to time.Time) error {
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "LOCK TABLE "+strings.Join(rollupTables, ", ")+" IN EXCLUSIVE MODE"); err != nil {
+		return fmt.Errorf("failed to lock rollup tables: %w", err)
+	}
+
+	var dayConditions, orderConditions []string
+	args := []interface{}{1}
+	if !from.IsZero() {
+		args = append(args, rollupDate(from, false))
+		dayConditions = append(dayConditions, fmt.Sprintf("day >= $%d", len(args)-1))
+		orderConditions = append(orderConditions, fmt.Sprintf(rollupDay+" >= $%d", len(args)))
+	}
+	if !to.IsZero() {
+		args = append(args, rollupDate(to, true))
+		dayConditions = append(dayConditions, fmt.Sprintf("day < $%d", len(args)-1))
+		orderConditions = append(orderConditions, fmt.Sprintf(rollupDay+" < $%d", len(args)))
+	}
+	dayWhere, orderWhere := "", "TRUE"
+	if len(orderConditions) > 0 {
+		dayWhere = " WHERE " + strings.Join(dayConditions, " AND ")
+		orderWhere = strings.Join(orderConditions, " AND ")
+	}
+
+	for i, statement := range rollupStatements {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM "+rollupTables[i]+dayWhere, args[1:]...); err != nil {
+			return fmt.Errorf("failed to clear %s: %w", rollupTables[i], err)
+		}
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf(statement, orderWhere), args...); err != nil {
+			return fmt.Errorf("failed to rebuild %s: %w", rollupTables[i], err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	p.logger.WithFields(logrus.Fields{
+		"from": from,
+		"to":   to,
+	}).Info("Analytics rollups rebuilt")
+	return nil
+}
+
+// rollupDate переводит границу периода в день агрегатов (YYYY-MM-DD, UTC). Верхняя граница
+// со временем округляется вверх, чтобы день, в который она попадает, учитывался целиком
+func rollupDate(t time.Time, upper bool) string {
+	t = t.UTC()
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	if upper && day.Before(t) {
+		day = day.AddDate(0, 0, 1)
+	}
+	return day.Format("2006-01-02")
+}
@@ -0,0 +1,168 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"order-service/internal/models"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// Разметка совпадений в SearchHighlight.Snippet
+const (
+	HighlightStart = "<mark>"
+	HighlightStop  = "</mark>"
+)
+
+// SearchHighlight - поле заказа, в котором найдены слова запроса, с размеченными совпадениями.
+// Field: items.name, items.brand, delivery.name, delivery.city, delivery.address или delivery.email
+type SearchHighlight struct {
+	Field   string `json:"field"`
+	Snippet string `json:"snippet"`
+}
+
+// SearchHit - заказ, найденный полнотекстовым поиском
+type SearchHit struct {
+	Order      *models.OrderFull `json:"order"`
+	Rank       float64           `json:"rank"`
+	Highlights []SearchHighlight `json:"highlights"`
+}
+
+// SearchOrders ищет заказы по словам из названий и брендов товаров, имени получателя,
+// города, адреса и email (orders.search_vector, миграция 007). Запрос в синтаксисе
+// websearch_to_tsquery: заказ должен содержать все слова, "фраза" ищется целиком, -слово исключает.
+// Результаты упорядочены по релевантности, затем от новых к старым
+func (p *PostgresDB) SearchOrders(ctx context.Context, query string, limit int) ([]SearchHit, error) {
+	rankQuery := `
+		SELECT o.order_uid, ts_rank_cd(o.search_vector, q.query) AS rank
+		FROM orders o, websearch_to_tsquery('simple', $1) AS q(query)
+		WHERE o.search_vector @@ q.query
+		ORDER BY rank DESC, o.created_at DESC, o.order_uid DESC
+		LIMIT $2
+	`
+	rows, err := p.db.QueryContext(ctx, rankQuery, query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search orders: %w", err)
+	}
+	defer rows.Close()
+
+	var uids []string
+	ranks := make(map[string]float64)
+	for rows.Next() {
+		var uid string
+		var rank float64
+		if err := rows.Scan(&uid, &rank); err != nil {
+			return nil, fmt.Errorf("failed to scan search result: %w", err)
+		}
+		uids = append(uids, uid)
+		ranks[uid] = rank
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate search results: %w", err)
+	}
+	if len(uids) == 0 {
+		return nil, nil
+	}
+
+	orders, err := queryFullOrders(ctx, p.db, fullOrderSelect+" WHERE o.order_uid = ANY($1)", pq.Array(uids))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load found orders: %w", err)
+	}
+	byUID := make(map[string]*models.OrderFull, len(orders))
+	for _, orderFull := range orders {
+		byUID[orderFull.OrderUID] = orderFull
+	}
+
+	highlights, err := p.searchHighlights(ctx, uids, highlightTerms(query))
+	if err != nil {
+		return nil, err
+	}
+
+	// Заказ мог быть удален между запросами
+	hits := make([]SearchHit, 0, len(uids))
+	for _, uid := range uids {
+		orderFull, ok := byUID[uid]
+		if !ok {
+			continue
+		}
+		hit := SearchHit{Order: orderFull, Rank: ranks[uid], Highlights: highlights[uid]}
+		if hit.Highlights == nil {
+			hit.Highlights = []SearchHighlight{}
+		}
+		hits = append(hits, hit)
+	}
+	return hits, nil
+}
+
+// searchHighlights размечает совпадения в полях найденных заказов. Заказ совпадает с запросом целиком,
+// а отдельное поле - лишь с частью слов ("Nike Иванов Казань"), поэтому поля проверяются по
+// запросу, объединяющему слова через ИЛИ
+func (p *PostgresDB) searchHighlights(ctx context.Context, uids, terms []string) (map[string][]SearchHighlight, error) {
+	if len(terms) == 0 {
+		return nil, nil
+	}
+
+	args := []interface{}{pq.Array(uids)}
+	termQueries := make([]string, 0, len(terms))
+	for _, term := range terms {
+		args = append(args, term)
+		termQueries = append(termQueries, fmt.Sprintf("plainto_tsquery('simple', $%d)", len(args)))
+	}
+	args = append(args, fmt.Sprintf("StartSel=%s, StopSel=%s, HighlightAll=true", HighlightStart, HighlightStop))
+
+	query := fmt.Sprintf(`
+		WITH fields AS (
+			SELECT order_uid, 1 AS pos, 'items.name' AS field, name AS value FROM order_items WHERE order_uid = ANY($1)
+			UNION ALL SELECT order_uid, 2, 'items.brand', brand FROM order_items WHERE order_uid = ANY($1)
+			UNION ALL SELECT order_uid, 3, 'delivery.name', name FROM deliveries WHERE order_uid = ANY($1)
+			UNION ALL SELECT order_uid, 4, 'delivery.city', city FROM deliveries WHERE order_uid = ANY($1)
+			UNION ALL SELECT order_uid, 5, 'delivery.address', address FROM deliveries WHERE order_uid = ANY($1)
+			UNION ALL SELECT order_uid, 6, 'delivery.email', email FROM deliveries WHERE order_uid = ANY($1)
+		)
+		SELECT DISTINCT f.order_uid, f.pos, f.field, ts_headline('simple', f.value, q.query, $%d)
+		FROM fields f, (SELECT %s) AS q(query)
+		WHERE f.value <> '' AND to_tsvector('simple', f.value) @@ q.query
+		ORDER BY f.order_uid, f.pos
+	`, len(args), strings.Join(termQueries, " || "))
+
+	rows, err := p.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to highlight search results: %w", err)
+	}
+	defer rows.Close()
+
+	highlights := make(map[string][]SearchHighlight)
+	for rows.Next() {
+		var uid string
+		var pos int
+		var highlight SearchHighlight
+		if err := rows.Scan(&uid, &pos, &highlight.Field, &highlight.Snippet); err != nil {
+			return nil, fmt.Errorf("failed to scan search highlight: %w", err)
+		}
+		highlights[uid] = append(highlights[uid], highlight)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate search highlights: %w", err)
+	}
+	return highlights, nil
+}
+
+// highlightTerms возвращает слова запроса, которые нужно разметить: без исключенных (-слово),
+// оператора or и кавычек фраз
+func highlightTerms(query string) []string {
+	var terms []string
+	seen := make(map[string]bool)
+	for _, word := range strings.Fields(query) {
+		if strings.HasPrefix(word, "-") || strings.EqualFold(word, "or") {
+			continue
+		}
+		word = strings.Trim(word, `"`)
+		if word == "" || seen[word] {
+			continue
+		}
+		seen[word] = true
+		terms = append(terms, word)
+	}
+	return terms
+}
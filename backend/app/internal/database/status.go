@@ -0,0 +1,124 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"order-service/internal/models"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	// ErrOrderNotFound возвращается, если событие относится к несуществующему заказу
+	ErrOrderNotFound = errors.New("order not found")
+	// ErrOrderItemNotFound возвращается, если в заказе нет товара с указанным chrt_id
+	ErrOrderItemNotFound = errors.New("order item not found")
+)
+
+// UpdateOrderStatus применяет изменение статуса к товарам заказа, обновляет updated_at заказа
+// и записывает изменившиеся статусы в историю. Сначала применяется общий статус,
+// затем статусы отдельных товаров.
+// content_hash не пересчитывается: он описывает последнее принятое сообщение о заказе,
+// поэтому повторная доставка исходного заказа не откатит статус
+func (p *PostgresDB) UpdateOrderStatus(change *models.OrderStatusChange) error {
+	tx, err := p.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(`UPDATE orders SET updated_at = CURRENT_TIMESTAMP WHERE order_uid = $1`, change.OrderUID)
+	if err != nil {
+		return fmt.Errorf("failed to update order: %w", err)
+	}
+	if updated, _ := res.RowsAffected(); updated == 0 {
+		return fmt.Errorf("%w: %s", ErrOrderNotFound, change.OrderUID)
+	}
+
+	items, err := lockOrderItems(tx, change.OrderUID)
+	if err != nil {
+		return err
+	}
+
+	// Вычисляем новые статусы товаров
+	newStatuses := make([]int, len(items))
+	for i, item := range items {
+		newStatuses[i] = item.status
+		if change.Status != nil {
+			newStatuses[i] = *change.Status
+		}
+	}
+	for _, itemChange := range change.Items {
+		found := false
+		for i, item := range items {
+			if item.chrtID == itemChange.ChrtID {
+				newStatuses[i] = itemChange.Status
+				found = true
+			}
+		}
+		if !found {
+			return fmt.Errorf("%w: order %s, chrt_id %d", ErrOrderItemNotFound, change.OrderUID, itemChange.ChrtID)
+		}
+	}
+
+	changedAt := change.ChangedAt
+	if changedAt.IsZero() {
+		changedAt = time.Now()
+	}
+
+	var historyRows [][]interface{}
+	for i, item := range items {
+		if newStatuses[i] == item.status {
+			continue
+		}
+		if _, err := tx.Exec(`UPDATE order_items SET status = $2 WHERE id = $1`, item.id, newStatuses[i]); err != nil {
+			return fmt.Errorf("failed to update order item status: %w", err)
+		}
+		oldStatus := item.status
+		historyRows = append(historyRows, historyRow(change.OrderUID, item.chrtID, &oldStatus, newStatuses[i], StatusSourceStatusEvent, changedAt))
+	}
+
+	if err := insertStatusHistory(tx, historyRows); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	p.logger.WithFields(logrus.Fields{
+		"order_uid":     change.OrderUID,
+		"items_changed": len(historyRows),
+	}).Info("Order status updated successfully")
+	return nil
+}
+
+type itemStatus struct {
+	id     int
+	chrtID int64
+	status int
+}
+
+// lockOrderItems возвращает текущие статусы товаров заказа, блокируя строки до конца транзакции
+func lockOrderItems(tx *sql.Tx, orderUID string) ([]itemStatus, error) {
+	rows, err := tx.Query(`SELECT id, chrt_id, status FROM order_items WHERE order_uid = $1 ORDER BY id FOR UPDATE`, orderUID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock order items: %w", err)
+	}
+	defer rows.Close()
+
+	var items []itemStatus
+	for rows.Next() {
+		var item itemStatus
+		if err := rows.Scan(&item.id, &item.chrtID, &item.status); err != nil {
+			return nil, fmt.Errorf("failed to scan order item: %w", err)
+		}
+		items = append(items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to lock order items: %w", err)
+	}
+	return items, nil
+}
@@ -0,0 +1,210 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"order-service/internal/models"
+	"time"
+)
+
+// ConflictPolicy определяет, что делать, если заказ с таким UID уже сохранен,
+// а повторно доставленный заказ отличается по содержимому
+type ConflictPolicy string
+
+const (
+	ConflictIgnore    ConflictPolicy = "ignore"    // Оставить сохраненный заказ
+	ConflictOverwrite ConflictPolicy = "overwrite" // Перезаписать заказ новыми данными
+	ConflictReject    ConflictPolicy = "conflict"  // Вернуть ErrOrderConflict
+)
+
+// ParseConflictPolicy разбирает политику из строки конфигурации
+func ParseConflictPolicy(value string) (ConflictPolicy, error) {
+	switch policy := ConflictPolicy(value); policy {
+	case ConflictIgnore, ConflictOverwrite, ConflictReject:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("unknown conflict policy %q (expected ignore, overwrite or conflict)", value)
+	}
+}
+
+// UpsertResult описывает, что произошло с заказом при идемпотентной записи
+type UpsertResult string
+
+const (
+	UpsertCreated     UpsertResult = "created"     // Новый заказ
+	UpsertUnchanged   UpsertResult = "unchanged"   // Повторная доставка того же содержимого
+	UpsertIgnored     UpsertResult = "ignored"     // Содержимое отличается, сохраненный заказ оставлен
+	UpsertOverwritten UpsertResult = "overwritten" // Содержимое отличается, заказ перезаписан
+)
+
+// ErrOrderConflict возвращается политикой ConflictReject, если повторно
+// доставленный заказ отличается от сохраненного
+var ErrOrderConflict = errors.New("order already exists with different content")
+
+// UpsertOrder идемпотентно сохраняет заказ: вставка выполняется через
+// INSERT ... ON CONFLICT DO NOTHING, поэтому параллельная запись одного
+// order_uid не приводит к ошибке первичного ключа. Если заказ уже существует,
+// содержимое сравнивается по хешу и применяется политика конфликтов
+func (p *PostgresDB) UpsertOrder(orderFull *models.OrderFull, policy ConflictPolicy) (UpsertResult, error) {
+	contentHash := orderFull.ContentHash()
+	warnings, err := validationWarningsValue(orderFull.ValidationWarnings)
+	if err != nil {
+		return "", err
+	}
+
+	tx, err := p.db.Begin()
+	if err != nil {
+		return "", fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	orderQuery := `
+		INSERT INTO orders (order_uid, track_number, entry, locale, internal_signature,
+						   customer_id, delivery_service, shardkey, sm_id, date_created, oof_shard,
+						   content_hash, validation_warnings)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+		ON CONFLICT (order_uid) DO NOTHING
+		RETURNING created_at, updated_at
+	`
+	// Время записи нужно модели, которая дальше попадает в кеш; без строки заказ уже существует
+	err = tx.QueryRow(orderQuery,
+		orderFull.OrderUID, orderFull.TrackNumber, orderFull.Entry, orderFull.Locale,
+		orderFull.InternalSignature, orderFull.CustomerID, orderFull.DeliveryService,
+		orderFull.Shardkey, orderFull.SmID, orderFull.DateCreated, orderFull.OofShard,
+		contentHash, warnings,
+	).Scan(&orderFull.CreatedAt, &orderFull.UpdatedAt)
+	inserted := true
+	if errors.Is(err, sql.ErrNoRows) {
+		inserted = false
+	} else if err != nil {
+		return "", fmt.Errorf("failed to insert order: %w", err)
+	}
+
+	result := UpsertCreated
+	historyRows := itemStatusRows(orderFull, nil, StatusSourceCreated, orderFull.DateCreated)
+	if !inserted {
+		var previous map[int64]int
+		result, previous, err = p.resolveConflict(tx, orderFull, contentHash, warnings, policy)
+		if err != nil {
+			return "", err
+		}
+		if result != UpsertOverwritten {
+			return result, nil
+		}
+		historyRows = itemStatusRows(orderFull, previous, StatusSourceOverwrite, time.Now())
+	}
+
+	if err := insertOrderDetails(tx, orderFull); err != nil {
+		return "", err
+	}
+	if err := insertStatusHistory(tx, historyRows); err != nil {
+		return "", err
+	}
+	if err := addRollups(tx, []string{orderFull.OrderUID}, 1); err != nil {
+		return "", err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return "", fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	p.logger.WithField("order_uid", orderFull.OrderUID).WithField("result", result).Info("Order saved successfully")
+	return result, nil
+}
+
+// resolveConflict сравнивает повторно доставленный заказ с сохраненным и применяет политику.
+// При перезаписи обновляет основной заказ и удаляет связанные данные, чтобы их можно было вставить заново,
+// и возвращает статусы удаленных товаров по chrt_id для истории статусов
+func (p *PostgresDB) resolveConflict(tx *sql.Tx, orderFull *models.OrderFull, contentHash string, warnings interface{}, policy ConflictPolicy) (UpsertResult, map[int64]int, error) {
+	// Блокируем строку, чтобы параллельная перезапись не смешала данные двух версий
+	var storedHash sql.NullString
+	err := tx.QueryRow(`SELECT content_hash FROM orders WHERE order_uid = $1 FOR UPDATE`, orderFull.OrderUID).Scan(&storedHash)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to lock existing order: %w", err)
+	}
+
+	// Заказы, сохраненные до появления content_hash, сравниваем по текущему содержимому.
+	// Читаем его в той же транзакции, под блокировкой строки
+	if !storedHash.Valid {
+		stored, err := queryFullOrders(context.Background(), tx, fullOrderSelect+" WHERE o.order_uid = $1", orderFull.OrderUID)
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to load existing order: %w", err)
+		}
+		if len(stored) > 0 {
+			storedHash = sql.NullString{String: stored[0].ContentHash(), Valid: true}
+		}
+	}
+
+	if storedHash.String == contentHash {
+		return UpsertUnchanged, nil, nil
+	}
+
+	switch policy {
+	case ConflictOverwrite:
+		// Вклад прежней версии убирается из агрегатов до ее изменения
+		if err := addRollups(tx, []string{orderFull.OrderUID}, -1); err != nil {
+			return "", nil, err
+		}
+
+		updateQuery := `
+			UPDATE orders SET track_number = $2, entry = $3, locale = $4, internal_signature = $5,
+				customer_id = $6, delivery_service = $7, shardkey = $8, sm_id = $9, date_created = $10,
+				oof_shard = $11, content_hash = $12, validation_warnings = $13, updated_at = CURRENT_TIMESTAMP
+			WHERE order_uid = $1
+			RETURNING created_at, updated_at
+		`
+		err := tx.QueryRow(updateQuery,
+			orderFull.OrderUID, orderFull.TrackNumber, orderFull.Entry, orderFull.Locale,
+			orderFull.InternalSignature, orderFull.CustomerID, orderFull.DeliveryService,
+			orderFull.Shardkey, orderFull.SmID, orderFull.DateCreated, orderFull.OofShard,
+			contentHash, warnings,
+		).Scan(&orderFull.CreatedAt, &orderFull.UpdatedAt)
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to update order: %w", err)
+		}
+
+		for _, table := range []string{"deliveries", "payments"} {
+			if _, err := tx.Exec(`DELETE FROM `+table+` WHERE order_uid = $1`, orderFull.OrderUID); err != nil {
+				return "", nil, fmt.Errorf("failed to delete existing %s: %w", table, err)
+			}
+		}
+
+		previous, err := deleteOrderItems(tx, orderFull.OrderUID)
+		if err != nil {
+			return "", nil, err
+		}
+		return UpsertOverwritten, previous, nil
+
+	case ConflictReject:
+		return "", nil, fmt.Errorf("%w: %s", ErrOrderConflict, orderFull.OrderUID)
+
+	default:
+		p.logger.WithField("order_uid", orderFull.OrderUID).Warn("Redelivered order differs from stored one, ignoring")
+		return UpsertIgnored, nil, nil
+	}
+}
+
+// deleteOrderItems удаляет товары заказа и возвращает их статусы по chrt_id
+func deleteOrderItems(tx *sql.Tx, orderUID string) (map[int64]int, error) {
+	rows, err := tx.Query(`DELETE FROM order_items WHERE order_uid = $1 RETURNING chrt_id, status`, orderUID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete existing order_items: %w", err)
+	}
+	defer rows.Close()
+
+	statuses := make(map[int64]int)
+	for rows.Next() {
+		var chrtID int64
+		var status int
+		if err := rows.Scan(&chrtID, &status); err != nil {
+			return nil, fmt.Errorf("failed to scan deleted order item: %w", err)
+		}
+		statuses[chrtID] = status
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to delete existing order_items: %w", err)
+	}
+	return statuses, nil
+}
@@ -0,0 +1,32 @@
+package database
+
+import (
+	"encoding/json"
+	"fmt"
+	"order-service/internal/models"
+)
+
+// validationWarningsValue возвращает значение колонки validation_warnings:
+// JSON-массив предупреждений или NULL, если их нет
+func validationWarningsValue(warnings []models.ValidationWarning) (interface{}, error) {
+	if len(warnings) == 0 {
+		return nil, nil
+	}
+	data, err := json.Marshal(warnings)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode validation warnings: %w", err)
+	}
+	return string(data), nil
+}
+
+// parseValidationWarnings разбирает значение колонки validation_warnings
+func parseValidationWarnings(data []byte) ([]models.ValidationWarning, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+	var warnings []models.ValidationWarning
+	if err := json.Unmarshal(data, &warnings); err != nil {
+		return nil, fmt.Errorf("failed to decode validation warnings: %w", err)
+	}
+	return warnings, nil
+}
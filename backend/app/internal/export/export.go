@@ -0,0 +1,269 @@
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"order-service/internal/models"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+// Format - формат выгрузки заказов
+type Format string
+
+const (
+	FormatCSV     Format = "csv"     // Плоская таблица: заказ + доставка + платеж, строка на товар
+	FormatNDJSON  Format = "ndjson"  // OrderFull в JSON, заказ на строку
+	FormatParquet Format = "parquet" // Та же плоская таблица, что и CSV
+)
+
+// parquetRowGroupSize ограничивает число строк, которые Parquet writer держит в памяти
+const parquetRowGroupSize = 10000
+
+// ParseFormat разбирает формат выгрузки
+func ParseFormat(value string) (Format, error) {
+	switch format := Format(strings.ToLower(strings.TrimSpace(value))); format {
+	case FormatCSV, FormatNDJSON, FormatParquet:
+		return format, nil
+	case "json", "jsonl":
+		return FormatNDJSON, nil
+	default:
+		return "", fmt.Errorf("unknown export format %q (expected csv, ndjson or parquet)", value)
+	}
+}
+
+// ContentType возвращает MIME-тип формата
+func (f Format) ContentType() string {
+	switch f {
+	case FormatCSV:
+		return "text/csv; charset=utf-8"
+	case FormatParquet:
+		return "application/vnd.apache.parquet"
+	default:
+		return "application/x-ndjson"
+	}
+}
+
+// Extension возвращает расширение файла выгрузки
+func (f Format) Extension() string {
+	return "." + string(f)
+}
+
+// Writer записывает заказы в поток в выбранном формате. Close дописывает
+// буферизованные данные (для Parquet - последнюю группу строк и футер), но не закрывает поток
+type Writer interface {
+	Write(orderFull *models.OrderFull) error
+	Close() error
+}
+
+// NewWriter создает Writer для формата
+func NewWriter(format Format, w io.Writer) (Writer, error) {
+	switch format {
+	case FormatCSV:
+		return newCSVWriter(w)
+	case FormatNDJSON:
+		return &ndjsonWriter{encoder: json.NewEncoder(w)}, nil
+	case FormatParquet:
+		return &parquetWriter{writer: parquet.NewGenericWriter[Row](w, parquet.MaxRowsPerRowGroup(parquetRowGroupSize))}, nil
+	default:
+		return nil, fmt.Errorf("unknown export format %q", format)
+	}
+}
+
+// Row - строка плоской выгрузки: данные заказа, доставки и платежа повторяются для каждого товара.
+// Заказ без товаров выгружается одной строкой с пустыми полями товара
+type Row struct {
+	OrderUID        string    `parquet:"order_uid"`
+	TrackNumber     string    `parquet:"track_number"`
+	Entry           string    `parquet:"entry"`
+	Locale          string    `parquet:"locale"`
+	CustomerID      string    `parquet:"customer_id"`
+	DeliveryService string    `parquet:"delivery_service"`
+	Shardkey        string    `parquet:"shardkey"`
+	SmID            int64     `parquet:"sm_id"`
+	DateCreated     time.Time `parquet:"date_created,timestamp(microsecond)"`
+	OofShard        string    `parquet:"oof_shard"`
+
+	DeliveryName    string `parquet:"delivery_name"`
+	DeliveryPhone   string `parquet:"delivery_phone"`
+	DeliveryZip     string `parquet:"delivery_zip"`
+	DeliveryCity    string `parquet:"delivery_city"`
+	DeliveryAddress string `parquet:"delivery_address"`
+	DeliveryRegion  string `parquet:"delivery_region"`
+	DeliveryEmail   string `parquet:"delivery_email"`
+
+	PaymentTransaction  string `parquet:"payment_transaction"`
+	PaymentRequestID    string `parquet:"payment_request_id"`
+	PaymentCurrency     string `parquet:"payment_currency"`
+	PaymentProvider     string `parquet:"payment_provider"`
+	PaymentAmount       int64  `parquet:"payment_amount"`
+	PaymentDt           int64  `parquet:"payment_dt"`
+	PaymentBank         string `parquet:"payment_bank"`
+	PaymentDeliveryCost int64  `parquet:"payment_delivery_cost"`
+	PaymentGoodsTotal   int64  `parquet:"payment_goods_total"`
+	PaymentCustomFee    int64  `parquet:"payment_custom_fee"`
+
+	ItemChrtID      int64  `parquet:"item_chrt_id"`
+	ItemTrackNumber string `parquet:"item_track_number"`
+	ItemPrice       int64  `parquet:"item_price"`
+	ItemRid         string `parquet:"item_rid"`
+	ItemName        string `parquet:"item_name"`
+	ItemSale        int64  `parquet:"item_sale"`
+	ItemSize        string `parquet:"item_size"`
+	ItemTotalPrice  int64  `parquet:"item_total_price"`
+	ItemNmID        int64  `parquet:"item_nm_id"`
+	ItemBrand       string `parquet:"item_brand"`
+	ItemStatus      int64  `parquet:"item_status"`
+}
+
+// csvColumns - заголовок CSV, порядок совпадает с Row.values
+var csvColumns = []string{
+	"order_uid", "track_number", "entry", "locale", "customer_id", "delivery_service",
+	"shardkey", "sm_id", "date_created", "oof_shard",
+	"delivery_name", "delivery_phone", "delivery_zip", "delivery_city", "delivery_address",
+	"delivery_region", "delivery_email",
+	"payment_transaction", "payment_request_id", "payment_currency", "payment_provider",
+	"payment_amount", "payment_dt", "payment_bank", "payment_delivery_cost",
+	"payment_goods_total", "payment_custom_fee",
+	"item_chrt_id", "item_track_number", "item_price", "item_rid", "item_name", "item_sale",
+	"item_size", "item_total_price", "item_nm_id", "item_brand", "item_status",
+}
+
+// Rows разворачивает заказ в строки плоской выгрузки
+func Rows(orderFull *models.OrderFull) []Row {
+	base := Row{
+		OrderUID:        orderFull.OrderUID,
+		TrackNumber:     orderFull.TrackNumber,
+		Entry:           orderFull.Entry,
+		Locale:          orderFull.Locale,
+		CustomerID:      orderFull.CustomerID,
+		DeliveryService: orderFull.DeliveryService,
+		Shardkey:        orderFull.Shardkey,
+		SmID:            int64(orderFull.SmID),
+		DateCreated:     orderFull.DateCreated.UTC(),
+		OofShard:        orderFull.OofShard,
+	}
+	if d := orderFull.Delivery; d != nil {
+		base.DeliveryName = d.Name
+		base.DeliveryPhone = d.Phone
+		base.DeliveryZip = d.Zip
+		base.DeliveryCity = d.City
+		base.DeliveryAddress = d.Address
+		base.DeliveryRegion = d.Region
+		base.DeliveryEmail = d.Email
+	}
+	if p := orderFull.Payment; p != nil {
+		base.PaymentTransaction = p.Transaction
+		base.PaymentRequestID = p.RequestID
+		base.PaymentCurrency = p.Currency
+		base.PaymentProvider = p.Provider
+		base.PaymentAmount = p.Amount.Amount()
+		base.PaymentDt = p.PaymentDt
+		base.PaymentBank = p.Bank
+		base.PaymentDeliveryCost = p.DeliveryCost.Amount()
+		base.PaymentGoodsTotal = p.GoodsTotal.Amount()
+		base.PaymentCustomFee = p.CustomFee.Amount()
+	}
+
+	if len(orderFull.Items) == 0 {
+		return []Row{base}
+	}
+
+	rows := make([]Row, len(orderFull.Items))
+	for i, item := range orderFull.Items {
+		row := base
+		row.ItemChrtID = item.ChrtID
+		row.ItemTrackNumber = item.TrackNumber
+		row.ItemPrice = item.Price.Amount()
+		row.ItemRid = item.Rid
+		row.ItemName = item.Name
+		row.ItemSale = int64(item.Sale)
+		row.ItemSize = item.Size
+		row.ItemTotalPrice = item.TotalPrice.Amount()
+		row.ItemNmID = item.NmID
+		row.ItemBrand = item.Brand
+		row.ItemStatus = int64(item.Status)
+		rows[i] = row
+	}
+	return rows
+}
+
+// values возвращает поля строки для CSV в порядке csvColumns
+func (r Row) values() []string {
+	i := func(v int64) string { return strconv.FormatInt(v, 10) }
+	return []string{
+		r.OrderUID, r.TrackNumber, r.Entry, r.Locale, r.CustomerID, r.DeliveryService,
+		r.Shardkey, i(r.SmID), r.DateCreated.Format(time.RFC3339), r.OofShard,
+		r.DeliveryName, r.DeliveryPhone, r.DeliveryZip, r.DeliveryCity, r.DeliveryAddress,
+		r.DeliveryRegion, r.DeliveryEmail,
+		r.PaymentTransaction, r.PaymentRequestID, r.PaymentCurrency, r.PaymentProvider,
+		i(r.PaymentAmount), i(r.PaymentDt), r.PaymentBank, i(r.PaymentDeliveryCost),
+		i(r.PaymentGoodsTotal), i(r.PaymentCustomFee),
+		i(r.ItemChrtID), r.ItemTrackNumber, i(r.ItemPrice), r.ItemRid, r.ItemName, i(r.ItemSale),
+		r.ItemSize, i(r.ItemTotalPrice), i(r.ItemNmID), r.ItemBrand, i(r.ItemStatus),
+	}
+}
+
+type csvWriter struct {
+	writer *csv.Writer
+}
+
+func newCSVWriter(w io.Writer) (*csvWriter, error) {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(csvColumns); err != nil {
+		return nil, fmt.Errorf("failed to write CSV header: %w", err)
+	}
+	return &csvWriter{writer: writer}, nil
+}
+
+func (c *csvWriter) Write(orderFull *models.OrderFull) error {
+	for _, row := range Rows(orderFull) {
+		if err := c.writer.Write(row.values()); err != nil {
+			return fmt.Errorf("failed to write CSV row: %w", err)
+		}
+	}
+	return nil
+}
+
+func (c *csvWriter) Close() error {
+	c.writer.Flush()
+	return c.writer.Error()
+}
+
+type ndjsonWriter struct {
+	encoder *json.Encoder
+}
+
+func (n *ndjsonWriter) Write(orderFull *models.OrderFull) error {
+	if err := n.encoder.Encode(orderFull); err != nil {
+		return fmt.Errorf("failed to write order: %w", err)
+	}
+	return nil
+}
+
+func (n *ndjsonWriter) Close() error {
+	return nil
+}
+
+type parquetWriter struct {
+	writer *parquet.GenericWriter[Row]
+}
+
+func (p *parquetWriter) Write(orderFull *models.OrderFull) error {
+	if _, err := p.writer.Write(Rows(orderFull)); err != nil {
+		return fmt.Errorf("failed to write Parquet rows: %w", err)
+	}
+	return nil
+}
+
+func (p *parquetWriter) Close() error {
+	if err := p.writer.Close(); err != nil {
+		return fmt.Errorf("failed to finish Parquet file: %w", err)
+	}
+	return nil
+}
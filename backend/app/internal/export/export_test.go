@@ -0,0 +1,251 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"order-service/internal/database"
+	"order-service/internal/models"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+func testOrder(orderUID string, items ...models.OrderItem) *models.OrderFull {
+	order := &models.OrderFull{
+		Order: models.Order{
+			OrderUID:        orderUID,
+			TrackNumber:     "WBILMTESTTRACK",
+			Entry:           "WBIL",
+			Locale:          "en",
+			CustomerID:      "test",
+			DeliveryService: "meest",
+			Shardkey:        "9",
+			SmID:            99,
+			DateCreated:     time.Date(2021, 11, 26, 9, 22, 19, 0, time.FixedZone("MSK", 3*60*60)),
+			OofShard:        "1",
+		},
+		Delivery: &models.Delivery{
+			Name: "Test Testov", Phone: "+9720000000", Zip: "2639809", City: "Kiryat Mozkin",
+			Address: "Ploshad Mira 15", Region: "Kraiot", Email: "test@gmail.com",
+		},
+		Payment: &models.Payment{
+			Transaction: orderUID, RequestID: "req-42", Currency: "USD", Provider: "wbpay",
+			Amount: models.NewMoney(4817, ""), PaymentDt: 1637907727, Bank: "alpha",
+			DeliveryCost: models.NewMoney(1500, ""), GoodsTotal: models.NewMoney(3217, ""), CustomFee: models.NewMoney(100, ""),
+		},
+		Items: items,
+	}
+	order.SetCurrency("USD")
+	return order
+}
+
+func testItem(chrtID int64, name string, price, totalPrice int64, sale int) models.OrderItem {
+	return models.OrderItem{
+		ChrtID: chrtID, TrackNumber: "WBILMTESTTRACK", Price: models.NewMoney(price, ""), Rid: "rid-" + name,
+		Name: name, Sale: sale, Size: "0", TotalPrice: models.NewMoney(totalPrice, ""), NmID: chrtID + 1,
+		Brand: "Vivienne Sabo", Status: 202,
+	}
+}
+
+func TestRows(t *testing.T) {
+	t.Run("order without items", func(t *testing.T) {
+		rows := Rows(testOrder("a"))
+		if len(rows) != 1 {
+			t.Fatalf("Rows() returned %d rows, want 1", len(rows))
+		}
+		row := rows[0]
+		if row.OrderUID != "a" || row.DeliveryRegion != "Kraiot" || row.PaymentAmount != 4817 || row.PaymentCustomFee != 100 {
+			t.Errorf("Rows() order fields = %+v", row)
+		}
+		if row.ItemChrtID != 0 || row.ItemName != "" || row.ItemTotalPrice != 0 {
+			t.Errorf("Rows() item fields of order without items = %+v, want empty", row)
+		}
+		// Дата выгружается в UTC
+		if want := time.Date(2021, 11, 26, 6, 22, 19, 0, time.UTC); row.DateCreated != want {
+			t.Errorf("DateCreated = %v, want %v", row.DateCreated, want)
+		}
+	})
+
+	t.Run("order without delivery and payment", func(t *testing.T) {
+		order := testOrder("a", testItem(1, "Mascaras", 453, 317, 30))
+		order.Delivery, order.Payment = nil, nil
+		rows := Rows(order)
+		if len(rows) != 1 || rows[0].DeliveryName != "" || rows[0].PaymentCurrency != "" || rows[0].ItemName != "Mascaras" {
+			t.Errorf("Rows() = %+v", rows)
+		}
+	})
+
+	t.Run("multiple items", func(t *testing.T) {
+		rows := Rows(testOrder("a", testItem(1, "Mascaras", 453, 317, 30), testItem(2, "Lipstick", 2900, 2900, 0)))
+		if len(rows) != 2 {
+			t.Fatalf("Rows() returned %d rows, want 2", len(rows))
+		}
+		// Данные заказа повторяются в каждой строке
+		for _, row := range rows {
+			if row.OrderUID != "a" || row.DeliveryName != "Test Testov" || row.PaymentGoodsTotal != 3217 {
+				t.Errorf("Rows() order fields = %+v", row)
+			}
+		}
+		if rows[0].ItemChrtID != 1 || rows[0].ItemPrice != 453 || rows[0].ItemTotalPrice != 317 || rows[0].ItemSale != 30 {
+			t.Errorf("first item row = %+v", rows[0])
+		}
+		if rows[1].ItemChrtID != 2 || rows[1].ItemName != "Lipstick" || rows[1].ItemNmID != 3 || rows[1].ItemStatus != 202 {
+			t.Errorf("second item row = %+v", rows[1])
+		}
+	})
+}
+
+// parquetColumns возвращает имена колонок Parquet в порядке полей Row
+func parquetColumns() []string {
+	rowType := reflect.TypeOf(Row{})
+	columns := make([]string, rowType.NumField())
+	for i := range columns {
+		columns[i] = strings.Split(rowType.Field(i).Tag.Get("parquet"), ",")[0]
+	}
+	return columns
+}
+
+func TestCSVColumns(t *testing.T) {
+	// CSV и Parquet - одна и та же плоская таблица
+	if got := parquetColumns(); !reflect.DeepEqual(csvColumns, got) {
+		t.Errorf("csvColumns = %v,\nwant Row parquet columns %v", csvColumns, got)
+	}
+
+	var buf bytes.Buffer
+	writer, err := NewWriter(FormatCSV, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.Write(testOrder("a", testItem(1, "Mascaras, big", 453, 317, 30))); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("failed to read CSV: %v", err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("CSV has %d records, want header and 1 row", len(records))
+	}
+	if !reflect.DeepEqual(records[0], csvColumns) {
+		t.Errorf("CSV header = %v, want %v", records[0], csvColumns)
+	}
+
+	row := make(map[string]string)
+	for i, column := range records[0] {
+		row[column] = records[1][i]
+	}
+	want := map[string]string{
+		"order_uid":          "a",
+		"sm_id":              "99",
+		"date_created":       "2021-11-26T06:22:19Z",
+		"oof_shard":          "1",
+		"delivery_name":      "Test Testov",
+		"delivery_email":     "test@gmail.com",
+		"payment_currency":   "USD",
+		"payment_amount":     "4817",
+		"payment_dt":         "1637907727",
+		"payment_custom_fee": "100",
+		"item_chrt_id":       "1",
+		"item_price":         "453",
+		"item_name":          "Mascaras, big",
+		"item_sale":          "30",
+		"item_total_price":   "317",
+		"item_nm_id":         "2",
+		"item_status":        "202",
+	}
+	for column, value := range want {
+		if row[column] != value {
+			t.Errorf("CSV %s = %q, want %q", column, row[column], value)
+		}
+	}
+}
+
+func TestParquetRoundTrip(t *testing.T) {
+	orders := []*models.OrderFull{
+		testOrder("a", testItem(1, "Mascaras", 453, 317, 30), testItem(2, "Lipstick", 2900, 2900, 0)),
+		testOrder("b"),
+	}
+
+	var buf bytes.Buffer
+	writer, err := NewWriter(FormatParquet, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var want []Row
+	for _, order := range orders {
+		if err := writer.Write(order); err != nil {
+			t.Fatal(err)
+		}
+		want = append(want, Rows(order)...)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := parquet.Read[Row](bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("parquet.Read() error = %v", err)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("read %d rows, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if !got[i].DateCreated.Equal(want[i].DateCreated) {
+			t.Errorf("row %d date_created = %v, want %v", i, got[i].DateCreated, want[i].DateCreated)
+		}
+		got[i].DateCreated = want[i].DateCreated
+		if got[i] != want[i] {
+			t.Errorf("row %d = %+v,\nwant %+v", i, got[i], want[i])
+		}
+	}
+}
+
+// fakeRepository отдает заказы StreamOrders из памяти
+type fakeRepository struct {
+	database.OrderRepository
+	orders []*models.OrderFull
+}
+
+func (r *fakeRepository) StreamOrders(ctx context.Context, filter database.OrderFilter, fn func(*models.OrderFull) error) error {
+	for _, order := range r.orders {
+		if err := fn(order); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestExportNDJSON(t *testing.T) {
+	repo := &fakeRepository{orders: []*models.OrderFull{testOrder("a", testItem(1, "Mascaras", 453, 317, 30)), testOrder("b")}}
+
+	var buf bytes.Buffer
+	count, err := Export(context.Background(), repo, database.OrderFilter{}, FormatNDJSON, &buf)
+	if err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	if count != 2 {
+		t.Errorf("Export() count = %d, want 2", count)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Export() wrote %d lines, want 2", len(lines))
+	}
+	var order models.OrderFull
+	if err := json.Unmarshal([]byte(lines[0]), &order); err != nil {
+		t.Fatalf("line 1 is not an order: %v", err)
+	}
+	if order.OrderUID != "a" || len(order.Items) != 1 || order.Items[0].TotalPrice.Amount() != 317 {
+		t.Errorf("line 1 = %s", lines[0])
+	}
+}
@@ -0,0 +1,29 @@
+package export
+
+import (
+	"context"
+	"io"
+	"order-service/internal/database"
+	"order-service/internal/models"
+)
+
+// Export выгружает заказы, подходящие под фильтр, в w в указанном формате.
+// Заказы читаются из репозитория частями и сразу записываются, вся выборка в памяти не держится.
+// Возвращает число выгруженных заказов
+func Export(ctx context.Context, repo database.OrderRepository, filter database.OrderFilter, format Format, w io.Writer) (int, error) {
+	writer, err := NewWriter(format, w)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	err = repo.StreamOrders(ctx, filter, func(orderFull *models.OrderFull) error {
+		count++
+		return writer.Write(orderFull)
+	})
+	if err != nil {
+		return count, err
+	}
+
+	return count, writer.Close()
+}
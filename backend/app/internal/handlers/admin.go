@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"order-service/internal/database"
+	"order-service/internal/kafka"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// ListDeadLetters возвращает последние сообщения из dead-letter топика
+func (h *HTTPHandler) ListDeadLetters(w http.ResponseWriter, r *http.Request) {
+	if h.replayer == nil {
+		h.writeErrorResponse(w, http.StatusServiceUnavailable, "Dead-letter queue is not configured")
+		return
+	}
+
+	limit := 50
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		if parsedLimit, err := strconv.Atoi(limitStr); err == nil && parsedLimit > 0 && parsedLimit <= 1000 {
+			limit = parsedLimit
+		}
+	}
+
+	letters, err := h.replayer.List(r.Context(), limit)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to list dead letters")
+		h.writeErrorResponse(w, http.StatusBadGateway, "Failed to read dead-letter topic")
+		return
+	}
+
+	h.writeSuccessResponse(w, map[string]interface{}{
+		"messages": letters,
+		"count":    len(letters),
+		"limit":    limit,
+	})
+}
+
+// GetDeadLetter возвращает одно сообщение из dead-letter топика
+func (h *HTTPHandler) GetDeadLetter(w http.ResponseWriter, r *http.Request) {
+	if h.replayer == nil {
+		h.writeErrorResponse(w, http.StatusServiceUnavailable, "Dead-letter queue is not configured")
+		return
+	}
+
+	partition, offset, ok := h.parseDeadLetterPosition(w, r)
+	if !ok {
+		return
+	}
+
+	letter, err := h.replayer.Get(r.Context(), partition, offset)
+	if err != nil {
+		h.writeDeadLetterError(w, err)
+		return
+	}
+
+	h.writeSuccessResponse(w, letter)
+}
+
+// ReplayDeadLetter повторно обрабатывает сообщение из dead-letter топика.
+// Тело запроса (необязательное) - kafka.ReplayRequest с патчем или заменой сообщения
+func (h *HTTPHandler) ReplayDeadLetter(w http.ResponseWriter, r *http.Request) {
+	if h.replayer == nil {
+		h.writeErrorResponse(w, http.StatusServiceUnavailable, "Dead-letter queue is not configured")
+		return
+	}
+
+	partition, offset, ok := h.parseDeadLetterPosition(w, r)
+	if !ok {
+		return
+	}
+
+	var req kafka.ReplayRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid replay request: "+err.Error())
+		return
+	}
+	if r.URL.Query().Get("dry_run") == "true" {
+		req.DryRun = true
+	}
+
+	result, err := h.replayer.Replay(r.Context(), partition, offset, req)
+	if err != nil {
+		h.writeDeadLetterError(w, err)
+		return
+	}
+
+	h.writeSuccessResponse(w, result)
+}
+
+func (h *HTTPHandler) parseDeadLetterPosition(w http.ResponseWriter, r *http.Request) (int, int64, bool) {
+	vars := mux.Vars(r)
+
+	partition, err := strconv.Atoi(vars["partition"])
+	if err != nil || partition < 0 {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid partition")
+		return 0, 0, false
+	}
+
+	offset, err := strconv.ParseInt(vars["offset"], 10, 64)
+	if err != nil || offset < 0 {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid offset")
+		return 0, 0, false
+	}
+
+	return partition, offset, true
+}
+
+func (h *HTTPHandler) writeDeadLetterError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, kafka.ErrDeadLetterNotFound):
+		h.writeErrorResponse(w, http.StatusNotFound, "Dead letter not found")
+	case kafka.IsValidationError(err):
+		h.writeErrorResponse(w, http.StatusUnprocessableEntity, err.Error())
+	case errors.Is(err, database.ErrOrderNotFound), errors.Is(err, database.ErrOrderItemNotFound):
+		h.writeErrorResponse(w, http.StatusUnprocessableEntity, err.Error())
+	case errors.Is(err, database.ErrOrderConflict):
+		h.writeErrorResponse(w, http.StatusConflict, err.Error())
+	default:
+		h.logger.WithError(err).Error("Failed to process dead letter")
+		h.writeErrorResponse(w, http.StatusInternalServerError, "Internal server error")
+	}
+}
@@ -0,0 +1,216 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"order-service/internal/currency"
+	"order-service/internal/database"
+	"strconv"
+)
+
+// Отчеты по продажам. Общие параметры: from, to (RFC 3339 или YYYY-MM-DD, по date_created),
+// currency (код валюты платежа) и reporting_currency (валюта, в которую переводятся суммы).
+// Без reporting_currency строки отчетов разбиты по валютам
+
+// GetRevenue возвращает выручку по периодам. Параметр interval: day (по умолчанию), week или month
+func (h *HTTPHandler) GetRevenue(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter, ok := h.parseAnalyticsFilter(w, query)
+	if !ok {
+		return
+	}
+
+	interval := database.IntervalDay
+	if intervalStr := query.Get("interval"); intervalStr != "" {
+		parsed, err := database.ParseInterval(intervalStr)
+		if err != nil {
+			h.writeErrorResponse(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		interval = parsed
+	}
+
+	points, err := h.db.RevenueByPeriod(r.Context(), filter, interval)
+	if err != nil {
+		h.writeAnalyticsError(w, err)
+		return
+	}
+	if points == nil {
+		points = []database.RevenuePoint{}
+	}
+	h.writeSuccessResponse(w, map[string]interface{}{
+		"interval": interval,
+		"revenue":  points,
+	})
+}
+
+// GetDeliveryServiceStats возвращает число заказов и выручку по службам доставки
+func (h *HTTPHandler) GetDeliveryServiceStats(w http.ResponseWriter, r *http.Request) {
+	filter, ok := h.parseAnalyticsFilter(w, r.URL.Query())
+	if !ok {
+		return
+	}
+
+	stats, err := h.db.OrdersByDeliveryService(r.Context(), filter)
+	if err != nil {
+		h.writeAnalyticsError(w, err)
+		return
+	}
+	if stats == nil {
+		stats = []database.DeliveryServiceStats{}
+	}
+	h.writeSuccessResponse(w, map[string]interface{}{"delivery_services": stats})
+}
+
+// GetTopBrands возвращает бренды-лидеры. Параметры: by (units по умолчанию или revenue) и limit
+func (h *HTTPHandler) GetTopBrands(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter, ok := h.parseAnalyticsFilter(w, query)
+	if !ok {
+		return
+	}
+	metric, limit, ok := h.parseTopParams(w, query)
+	if !ok {
+		return
+	}
+
+	stats, err := h.db.TopBrands(r.Context(), filter, metric, limit)
+	if err != nil {
+		h.writeAnalyticsError(w, err)
+		return
+	}
+	if stats == nil {
+		stats = []database.BrandStats{}
+	}
+	h.writeSuccessResponse(w, map[string]interface{}{
+		"by":     metric,
+		"limit":  limit,
+		"brands": stats,
+	})
+}
+
+// GetTopProducts возвращает товары (nm_id)-лидеры. Параметры как у GetTopBrands
+func (h *HTTPHandler) GetTopProducts(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter, ok := h.parseAnalyticsFilter(w, query)
+	if !ok {
+		return
+	}
+	metric, limit, ok := h.parseTopParams(w, query)
+	if !ok {
+		return
+	}
+
+	stats, err := h.db.TopProducts(r.Context(), filter, metric, limit)
+	if err != nil {
+		h.writeAnalyticsError(w, err)
+		return
+	}
+	if stats == nil {
+		stats = []database.ProductStats{}
+	}
+	h.writeSuccessResponse(w, map[string]interface{}{
+		"by":       metric,
+		"limit":    limit,
+		"products": stats,
+	})
+}
+
+// GetBasketStats возвращает средний размер корзины
+func (h *HTTPHandler) GetBasketStats(w http.ResponseWriter, r *http.Request) {
+	filter, ok := h.parseAnalyticsFilter(w, r.URL.Query())
+	if !ok {
+		return
+	}
+
+	stats, err := h.db.BasketStats(r.Context(), filter)
+	if err != nil {
+		h.writeAnalyticsError(w, err)
+		return
+	}
+	if stats == nil {
+		stats = []database.BasketStats{}
+	}
+	h.writeSuccessResponse(w, map[string]interface{}{"baskets": stats})
+}
+
+// GetSaleDistribution возвращает распределение товаров по размеру скидки
+func (h *HTTPHandler) GetSaleDistribution(w http.ResponseWriter, r *http.Request) {
+	filter, ok := h.parseAnalyticsFilter(w, r.URL.Query())
+	if !ok {
+		return
+	}
+
+	buckets, err := h.db.SaleDistribution(r.Context(), filter)
+	if err != nil {
+		h.writeAnalyticsError(w, err)
+		return
+	}
+	if buckets == nil {
+		buckets = []database.SaleBucket{}
+	}
+	h.writeSuccessResponse(w, map[string]interface{}{"sales": buckets})
+}
+
+// parseAnalyticsFilter разбирает общие параметры отчетов. При ошибке отправляет 400 и возвращает false
+func (h *HTTPHandler) parseAnalyticsFilter(w http.ResponseWriter, query url.Values) (database.AnalyticsFilter, bool) {
+	var filter database.AnalyticsFilter
+
+	dates, err := database.ParseOrderFilter(query.Get("from"), query.Get("to"), "", "")
+	if err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, err.Error())
+		return filter, false
+	}
+	filter.DateFrom, filter.DateTo = dates.DateFrom, dates.DateTo
+
+	if code := query.Get("currency"); code != "" {
+		filter.Currency = currency.Normalize(code)
+		if !currency.Known(filter.Currency) {
+			h.writeErrorResponse(w, http.StatusBadRequest, fmt.Sprintf("unknown currency %q", code))
+			return filter, false
+		}
+	}
+
+	if code := query.Get("reporting_currency"); code != "" {
+		filter.ReportingCurrency = currency.Normalize(code)
+		if !currency.Known(filter.ReportingCurrency) {
+			h.writeErrorResponse(w, http.StatusBadRequest, fmt.Sprintf("unknown reporting_currency %q", code))
+			return filter, false
+		}
+		filter.Rates = h.rates.Table()
+	}
+	return filter, true
+}
+
+// parseTopParams разбирает параметры рейтингов: by и limit (по умолчанию 10, не больше 100)
+func (h *HTTPHandler) parseTopParams(w http.ResponseWriter, query url.Values) (database.TopMetric, int, bool) {
+	metric := database.TopByUnits
+	if by := query.Get("by"); by != "" {
+		parsed, err := database.ParseTopMetric(by)
+		if err != nil {
+			h.writeErrorResponse(w, http.StatusBadRequest, err.Error())
+			return "", 0, false
+		}
+		metric = parsed
+	}
+
+	limit := 10 // по умолчанию
+	if limitStr := query.Get("limit"); limitStr != "" {
+		if parsedLimit, err := strconv.Atoi(limitStr); err == nil && parsedLimit > 0 && parsedLimit <= 100 {
+			limit = parsedLimit
+		}
+	}
+	return metric, limit, true
+}
+
+// writeAnalyticsError отправляет ошибку отчета: отсутствие курса для перевода в валюту отчета - 422
+func (h *HTTPHandler) writeAnalyticsError(w http.ResponseWriter, err error) {
+	if errors.Is(err, currency.ErrNoRate) || errors.Is(err, currency.ErrUnknownCurrency) {
+		h.writeErrorResponse(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+	h.logger.WithError(err).Error("Failed to build analytics report")
+	h.writeErrorResponse(w, http.StatusInternalServerError, "Internal server error")
+}
@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"order-service/internal/export"
+	"time"
+)
+
+// exportWriteTimeout - сколько выгрузка ждет, пока клиент примет очередную часть ответа
+const exportWriteTimeout = 30 * time.Second
+
+// ExportOrders выгружает заказы потоком в CSV, NDJSON или Parquet.
+// Параметры: format (по умолчанию ndjson) и фильтры parseOrderFilter.
+// Ошибка после начала выгрузки только логируется: статус ответа уже отправлен, и файл будет обрезан
+func (h *HTTPHandler) ExportOrders(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	format := export.FormatNDJSON
+	if formatStr := query.Get("format"); formatStr != "" {
+		parsed, err := export.ParseFormat(formatStr)
+		if err != nil {
+			h.writeErrorResponse(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		format = parsed
+	}
+
+	filter, err := parseOrderFilter(query)
+	if err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", format.ContentType())
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="orders%s"`, format.Extension()))
+
+	// Выгрузка большой выборки длится дольше таймаута записи сервера, поэтому таймаут
+	// продлевается перед каждой записью: клиент, который перестал читать ответ, отключается
+	writer := &deadlineWriter{w: w, controller: http.NewResponseController(w), timeout: exportWriteTimeout}
+	count, err := export.Export(r.Context(), h.db, filter, format, writer)
+	if err != nil {
+		h.logger.WithError(err).WithField("exported", count).Error("Order export aborted")
+		return
+	}
+
+	h.logger.WithField("format", format).WithField("exported", count).Info("Orders exported")
+}
+
+// deadlineWriter продлевает таймаут записи ответа перед каждой записью
+type deadlineWriter struct {
+	w          http.ResponseWriter
+	controller *http.ResponseController
+	timeout    time.Duration
+}
+
+func (d *deadlineWriter) Write(p []byte) (int, error) {
+	if err := d.controller.SetWriteDeadline(time.Now().Add(d.timeout)); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return 0, err
+	}
+	return d.w.Write(p)
+}
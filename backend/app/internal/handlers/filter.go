@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"order-service/internal/database"
+	"strconv"
+)
+
+// parseOrderFilter собирает фильтр заказов из параметров запроса:
+// from, to (RFC 3339 или YYYY-MM-DD), customer_id, track_number, delivery_service,
+// city, region, provider и brand
+func parseOrderFilter(query url.Values) (database.OrderFilter, error) {
+	filter, err := database.ParseOrderFilter(query.Get("from"), query.Get("to"), query.Get("customer_id"), query.Get("delivery_service"))
+	if err != nil {
+		return filter, err
+	}
+
+	filter.TrackNumber = query.Get("track_number")
+	filter.City = query.Get("city")
+	filter.Region = query.Get("region")
+	filter.Provider = query.Get("provider")
+	filter.Brand = query.Get("brand")
+	return filter, nil
+}
+
+const (
+	defaultPageLimit = 50
+	maxPageLimit     = 1000
+)
+
+// parsePage разбирает параметры пагинации limit и cursor (next_cursor предыдущей страницы)
+func parsePage(query url.Values) (int, *database.OrderCursor, error) {
+	limit := defaultPageLimit
+	if limitStr := query.Get("limit"); limitStr != "" {
+		parsedLimit, err := strconv.Atoi(limitStr)
+		if err != nil || parsedLimit <= 0 || parsedLimit > maxPageLimit {
+			return 0, nil, fmt.Errorf("invalid limit: expected integer from 1 to %d", maxPageLimit)
+		}
+		limit = parsedLimit
+	}
+
+	var after *database.OrderCursor
+	if cursorStr := query.Get("cursor"); cursorStr != "" {
+		cursor, err := database.DecodeOrderCursor(cursorStr)
+		if err != nil {
+			return 0, nil, errors.New("invalid cursor")
+		}
+		after = cursor
+	}
+	return limit, after, nil
+}
@@ -2,13 +2,16 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"math/rand"
 	"net/http"
 	"order-service/internal/cache"
+	"order-service/internal/currency"
 	"order-service/internal/database"
+	"order-service/internal/kafka"
 	"order-service/internal/models"
-	"strconv"
+	"order-service/internal/validation"
 	"strings"
 	"time"
 
@@ -17,9 +20,12 @@ import (
 )
 
 type HTTPHandler struct {
-	db     database.OrderRepository
-	cache  cache.OrderCache
-	logger *logrus.Logger
+	db        database.OrderRepository
+	cache     cache.OrderCache
+	processor *kafka.OrderProcessor
+	replayer  *kafka.Replayer // nil, если Kafka или DLQ отключены
+	rates     *currency.Rates
+	logger    *logrus.Logger
 }
 
 type APIResponse struct {
@@ -29,11 +35,14 @@ type APIResponse struct {
 }
 
 // NewHTTPHandler создает новый HTTP handler
-func NewHTTPHandler(db database.OrderRepository, cache cache.OrderCache, logger *logrus.Logger) *HTTPHandler {
+func NewHTTPHandler(db database.OrderRepository, cache cache.OrderCache, processor *kafka.OrderProcessor, replayer *kafka.Replayer, rates *currency.Rates, logger *logrus.Logger) *HTTPHandler {
 	return &HTTPHandler{
-		db:     db,
-		cache:  cache,
-		logger: logger,
+		db:        db,
+		cache:     cache,
+		processor: processor,
+		replayer:  replayer,
+		rates:     rates,
+		logger:    logger,
 	}
 }
 
@@ -61,12 +70,33 @@ func (h *HTTPHandler) SetupRoutes() *mux.Router {
 
 	// API маршруты
 	api := r.PathPrefix("/api/v1").Subrouter()
+	// Выгрузка регистрируется раньше /orders/{order_uid}, иначе "export" будет принят за UID
+	api.HandleFunc("/orders/export", h.ExportOrders).Methods("GET")
+	api.HandleFunc("/orders/search", h.SearchOrders).Methods("GET")
+	api.HandleFunc("/orders/by-track/{track_number}", h.GetOrdersByTrackNumber).Methods("GET")
 	api.HandleFunc("/orders/{order_uid}", h.GetOrder).Methods("GET")
+	api.HandleFunc("/orders/{order_uid}/history", h.GetOrderHistory).Methods("GET")
 	api.HandleFunc("/orders", h.GetAllOrders).Methods("GET")
+	api.HandleFunc("/orders", h.CreateOrder).Methods("POST", "OPTIONS")
 	api.HandleFunc("/orders/random", h.GenerateRandomOrder).Methods("POST", "OPTIONS")
+	api.HandleFunc("/orders/validate", h.ValidateOrder).Methods("POST", "OPTIONS")
+	api.HandleFunc("/orders/import", h.ImportOrders).Methods("POST", "OPTIONS")
+	api.HandleFunc("/customers/{customer_id}/orders", h.GetCustomerOrders).Methods("GET")
+	api.HandleFunc("/payments/{transaction}", h.GetOrderByTransaction).Methods("GET")
 	api.HandleFunc("/cache/stats", h.GetCacheStats).Methods("GET")
 	api.HandleFunc("/health", h.HealthCheck).Methods("GET")
-	
+
+	// Отчеты по продажам
+	analytics := api.PathPrefix("/analytics").Subrouter()
+	analytics.HandleFunc("/revenue", h.GetRevenue).Methods("GET")
+	analytics.HandleFunc("/delivery-services", h.GetDeliveryServiceStats).Methods("GET")
+	analytics.HandleFunc("/top-brands", h.GetTopBrands).Methods("GET")
+	analytics.HandleFunc("/top-products", h.GetTopProducts).Methods("GET")
+	analytics.HandleFunc("/basket", h.GetBasketStats).Methods("GET")
+	analytics.HandleFunc("/discounts", h.GetSaleDistribution).Methods("GET")
+
+	// Административные маршруты обслуживает отдельный сервер, см. SetupAdminRoutes
+
 	// Обработчик для всех остальных API маршрутов (404)
 	api.PathPrefix("/").HandlerFunc(h.APINotFound)
 
@@ -120,31 +150,88 @@ func (h *HTTPHandler) GetOrder(w http.ResponseWriter, r *http.Request) {
 	h.writeSuccessResponse(w, order)
 }
 
-// GetAllOrders возвращает список всех заказов
-func (h *HTTPHandler) GetAllOrders(w http.ResponseWriter, r *http.Request) {
-	limitStr := r.URL.Query().Get("limit")
-	limit := 50 // по умолчанию
+// GetOrderHistory возвращает историю статусов заказа
+func (h *HTTPHandler) GetOrderHistory(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	orderUID := vars["order_uid"]
 
-	if limitStr != "" {
-		if parsedLimit, err := strconv.Atoi(limitStr); err == nil && parsedLimit > 0 && parsedLimit <= 1000 {
-			limit = parsedLimit
-		}
+	if strings.TrimSpace(orderUID) == "" {
+		h.writeErrorResponse(w, http.StatusBadRequest, "order_uid is required")
+		return
 	}
 
-	orders, err := h.db.GetAllOrders(limit)
+	history, err := h.db.GetOrderStatusHistory(orderUID)
 	if err != nil {
-		h.logger.WithError(err).Error("Failed to get orders from database")
+		h.logger.WithError(err).WithField("order_uid", orderUID).Error("Failed to get order status history")
 		h.writeErrorResponse(w, http.StatusInternalServerError, "Internal server error")
 		return
 	}
 
+	// У существующего заказа история не пуста, поэтому пустой результат проверяем отдельно
+	if len(history) == 0 {
+		exists, err := h.db.OrderExists(orderUID)
+		if err != nil {
+			h.logger.WithError(err).WithField("order_uid", orderUID).Error("Failed to check order existence")
+			h.writeErrorResponse(w, http.StatusInternalServerError, "Internal server error")
+			return
+		}
+		if !exists {
+			h.writeErrorResponse(w, http.StatusNotFound, "Order not found")
+			return
+		}
+	}
+
 	h.writeSuccessResponse(w, map[string]interface{}{
-		"orders": orders,
-		"count":  len(orders),
-		"limit":  limit,
+		"order_uid": orderUID,
+		"history":   history,
+		"count":     len(history),
 	})
 }
 
+// GetAllOrders возвращает страницу списка заказов от новых к старым.
+// Параметры: limit, cursor (next_cursor предыдущей страницы) и фильтры parseOrderFilter
+func (h *HTTPHandler) GetAllOrders(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	limit, after, err := parsePage(query)
+	if err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	filter, err := parseOrderFilter(query)
+	if err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	orders, next, err := h.db.ListOrders(r.Context(), filter, limit, after)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to get orders from database")
+		h.writeErrorResponse(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	h.writeOrderPage(w, orders, limit, next)
+}
+
+// writeOrderPage отправляет страницу заказов с курсором следующей страницы
+func (h *HTTPHandler) writeOrderPage(w http.ResponseWriter, orders []*models.OrderFull, limit int, next *database.OrderCursor) {
+	if orders == nil {
+		orders = []*models.OrderFull{}
+	}
+
+	response := map[string]interface{}{
+		"orders":   orders,
+		"count":    len(orders),
+		"limit":    limit,
+		"has_more": next != nil,
+	}
+	if next != nil {
+		response["next_cursor"] = next.Encode()
+	}
+	h.writeSuccessResponse(w, response)
+}
+
 // GetCacheStats возвращает статистику кеша
 func (h *HTTPHandler) GetCacheStats(w http.ResponseWriter, r *http.Request) {
 	stats := h.cache.GetStats()
@@ -212,11 +299,71 @@ func (h *HTTPHandler) APIInfoPage(w http.ResponseWriter, r *http.Request) {
             </div>
             
             <div class="endpoint">
-                <div><span class="method">GET</span><span class="path">/api/v1/orders?limit=10</span></div>
-                <div class="description">Получить список заказов с ограничением</div>
+                <div><span class="method">GET</span><span class="path">/api/v1/orders/{order_uid}/history</span></div>
+                <div class="description">Получить историю статусов заказа</div>
+                <div class="example">curl http://localhost:8080/api/v1/orders/b563feb7b2b84b6test/history</div>
+            </div>
+            
+            <div class="endpoint">
+                <div><span class="method">GET</span><span class="path">/api/v1/orders?limit=10&amp;cursor=...</span></div>
+                <div class="description">Получить страницу списка заказов (фильтры: customer_id, track_number, delivery_service, city, region, provider, brand, from, to)</div>
                 <div class="example">curl http://localhost:8080/api/v1/orders?limit=5</div>
             </div>
             
+            <div class="endpoint">
+                <div><span class="method">GET</span><span class="path">/api/v1/orders/search?q=...&amp;limit=20</span></div>
+                <div class="description">Полнотекстовый поиск по товарам, получателю, адресу и email</div>
+                <div class="example">curl "http://localhost:8080/api/v1/orders/search?q=Vivienne+Sabo"</div>
+            </div>
+            
+            <div class="endpoint">
+                <div><span class="method">GET</span><span class="path">/api/v1/orders/by-track/{track_number}</span></div>
+                <div class="description">Получить заказы по трек-номеру</div>
+                <div class="example">curl http://localhost:8080/api/v1/orders/by-track/WBILMTESTTRACK</div>
+            </div>
+            
+            <div class="endpoint">
+                <div><span class="method">GET</span><span class="path">/api/v1/customers/{customer_id}/orders?limit=10&amp;cursor=...</span></div>
+                <div class="description">Получить страницу заказов клиента</div>
+                <div class="example">curl http://localhost:8080/api/v1/customers/test/orders</div>
+            </div>
+            
+            <div class="endpoint">
+                <div><span class="method">GET</span><span class="path">/api/v1/payments/{transaction}</span></div>
+                <div class="description">Получить заказ по ID транзакции платежа</div>
+                <div class="example">curl http://localhost:8080/api/v1/payments/b563feb7b2b84b6test</div>
+            </div>
+            
+            <div class="endpoint">
+                <div><span class="method">POST</span><span class="path">/api/v1/orders</span></div>
+                <div class="description">Создать заказ (тело в формате сообщения Kafka)</div>
+                <div class="example">curl -X POST -d @order.json http://localhost:8080/api/v1/orders</div>
+            </div>
+            
+            <div class="endpoint">
+                <div><span class="method">POST</span><span class="path">/api/v1/orders/import?batch_size=500</span></div>
+                <div class="description">Импортировать заказы из NDJSON, результат по каждой строке</div>
+                <div class="example">curl -X POST --data-binary @orders.ndjson http://localhost:8080/api/v1/orders/import</div>
+            </div>
+            
+            <div class="endpoint">
+                <div><span class="method">POST</span><span class="path">/api/v1/orders/validate</span></div>
+                <div class="description">Проверить JSON заказа по правилам валидации без сохранения</div>
+                <div class="example">curl -X POST -d @order.json http://localhost:8080/api/v1/orders/validate</div>
+            </div>
+            
+            <div class="endpoint">
+                <div><span class="method">GET</span><span class="path">/api/v1/orders/export?format=csv&amp;from=2021-11-01&amp;to=2021-11-30</span></div>
+                <div class="description">Выгрузить заказы в CSV, NDJSON или Parquet (фильтры: from, to, customer_id, delivery_service)</div>
+                <div class="example">curl -o orders.csv "http://localhost:8080/api/v1/orders/export?format=csv"</div>
+            </div>
+            
+            <div class="endpoint">
+                <div><span class="method">GET</span><span class="path">/api/v1/analytics/{report}?from=...&amp;to=...&amp;currency=RUB&amp;reporting_currency=RUB</span></div>
+                <div class="description">Отчеты по продажам: revenue (interval=day|week|month), delivery-services, top-brands, top-products (by=units|revenue), basket, discounts. reporting_currency переводит суммы в одну валюту по курсу дня заказа</div>
+                <div class="example">curl "http://localhost:8080/api/v1/analytics/revenue?interval=month&amp;currency=USD"</div>
+            </div>
+            
             <div class="endpoint">
                 <div><span class="method">GET</span><span class="path">/api/v1/cache/stats</span></div>
                 <div class="description">Получить статистику кеша</div>
@@ -298,6 +445,37 @@ func (h *HTTPHandler) loggingMiddleware(next http.Handler) http.Handler {
 	})
 }
 
+// SetupAdminRoutes настраивает маршруты административного API. Они меняют состояние
+// (повторная обработка сообщений из DLQ), поэтому обслуживаются отдельным сервером без CORS
+// и не принимают запросы из браузера
+func (h *HTTPHandler) SetupAdminRoutes() *mux.Router {
+	r := mux.NewRouter()
+
+	admin := r.PathPrefix("/api/v1/admin").Subrouter()
+	admin.HandleFunc("/dlq", h.ListDeadLetters).Methods("GET")
+	admin.HandleFunc("/dlq/{partition:[0-9]+}/{offset:[0-9]+}", h.GetDeadLetter).Methods("GET")
+	admin.HandleFunc("/dlq/{partition:[0-9]+}/{offset:[0-9]+}/replay", h.ReplayDeadLetter).Methods("POST")
+	r.PathPrefix("/").HandlerFunc(h.APINotFound)
+
+	r.Use(h.loggingMiddleware)
+	r.Use(h.rejectBrowserMiddleware)
+
+	return r
+}
+
+// rejectBrowserMiddleware отклоняет запросы с заголовком Origin. Его отправляет браузер при запросах
+// со сторонних страниц, в том числе при отправке форм, которые не требуют preflight; ordersctl и curl его не передают
+func (h *HTTPHandler) rejectBrowserMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Origin") != "" {
+			h.logger.WithField("origin", r.Header.Get("Origin")).Warn("Rejected browser request to admin API")
+			h.writeErrorResponse(w, http.StatusForbidden, "Admin API does not accept browser requests")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
 // Middleware для CORS
 func (h *HTTPHandler) corsMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -324,6 +502,11 @@ func (rw *responseWrapper) WriteHeader(code int) {
 	rw.ResponseWriter.WriteHeader(code)
 }
 
+// Unwrap нужен http.ResponseController для потоковых ответов (Flush, дедлайны)
+func (rw *responseWrapper) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
 // GenerateRandomOrder генерирует случайный заказ
 func (h *HTTPHandler) GenerateRandomOrder(w http.ResponseWriter, r *http.Request) {
 	h.logger.Info("Generating random order")
@@ -337,17 +520,20 @@ func (h *HTTPHandler) GenerateRandomOrder(w http.ResponseWriter, r *http.Request
 	// Генерируем случайный заказ
 	order := generateRandomOrderData(names, cities, brands, products)
 
-	// Сохраняем в базу данных
-	if err := h.db.CreateOrder(order); err != nil {
+	// Сохраняем тем же путем, что и остальные источники заказов: идемпотентная запись
+	// с политикой конфликтов и обновление кеша
+	result, err := h.processor.Store(order)
+	if err != nil {
+		if errors.Is(err, database.ErrOrderConflict) {
+			h.writeErrorResponse(w, http.StatusConflict, err.Error())
+			return
+		}
 		h.logger.WithError(err).Error("Failed to create random order")
 		h.writeErrorResponse(w, http.StatusInternalServerError, "Ошибка создания заказа")
 		return
 	}
 
-	// Добавляем в кеш
-	h.cache.Set(order.OrderUID, order)
-
-	h.logger.WithField("order_uid", order.OrderUID).Info("Random order created successfully")
+	h.logger.WithField("order_uid", order.OrderUID).WithField("result", result).Info("Random order created successfully")
 
 	// Возвращаем созданный заказ
 	h.writeSuccessResponse(w, order)
@@ -389,31 +575,15 @@ func generateRandomOrderData(names, cities, brands, products []string) *models.O
 		Email:    fmt.Sprintf("test%d@example.com", rand.Intn(1000)),
 	}
 
-	// Генерируем платеж
-	goodsTotal := rand.Intn(5000) + 100
-	deliveryCost := rand.Intn(500) + 100
-	order.Payment = &models.Payment{
-		OrderUID:     orderUID,
-		Transaction:  orderUID,
-		RequestID:    "",
-		Currency:     "RUB",
-		Provider:     "test_pay",
-		Amount:       goodsTotal + deliveryCost,
-		PaymentDt:    time.Now().Unix(),
-		Bank:         "test_bank",
-		DeliveryCost: deliveryCost,
-		GoodsTotal:   goodsTotal,
-		CustomFee:    0,
-	}
-
 	// Генерируем товары
 	numItems := rand.Intn(3) + 1
 	order.Items = make([]models.OrderItem, numItems)
 	
 	for i := 0; i < numItems; i++ {
-		price := rand.Intn(2000) + 100
+		price := models.NewMoney(int64(rand.Intn(2000)+100), "RUB")
 		sale := rand.Intn(50)
-		totalPrice := price - (price * sale / 100)
+		// Скидка всегда в диапазоне 0-100%, ошибки быть не может
+		totalPrice, _ := price.ApplySale(sale)
 		
 		order.Items[i] = models.OrderItem{
 			OrderUID:    orderUID,
@@ -431,5 +601,21 @@ func generateRandomOrderData(names, cities, brands, products []string) *models.O
 		}
 	}
 
+	// Генерируем платеж, стоимость товаров равна сумме их итоговых цен
+	order.Payment = &models.Payment{
+		OrderUID:     orderUID,
+		Transaction:  orderUID,
+		RequestID:    "",
+		Currency:     "RUB",
+		Provider:     "test_pay",
+		PaymentDt:    time.Now().Unix(),
+		Bank:         "test_bank",
+		DeliveryCost: models.NewMoney(int64(rand.Intn(500)+100), "RUB"),
+		CustomFee:    models.NewMoney(0, "RUB"),
+	}
+	// Суммы нескольких небольших цен не переполняются
+	order.Payment.GoodsTotal, _ = validation.ItemsTotal(order)
+	order.Payment.Amount, _ = order.Payment.GoodsTotal.Add(order.Payment.DeliveryCost)
+
 	return order
 }
@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"order-service/internal/kafka"
+	"strconv"
+	"time"
+)
+
+// ImportOrders загружает заказы из NDJSON (один заказ в формате сообщения Kafka на строку).
+// Тело читается потоково, валидные заказы сохраняются пачками по batch_size.
+// Ответ - NDJSON: результат каждой строки (kafka.ImportLineResult) и последней строкой
+// {"summary": ...} или {"error": ...}, если импорт прерван
+func (h *HTTPHandler) ImportOrders(w http.ResponseWriter, r *http.Request) {
+	batchSize := kafka.DefaultImportBatchSize
+	if batchSizeStr := r.URL.Query().Get("batch_size"); batchSizeStr != "" {
+		parsed, err := strconv.Atoi(batchSizeStr)
+		if err != nil || parsed < 1 || parsed > 10000 {
+			h.writeErrorResponse(w, http.StatusBadRequest, "batch_size must be between 1 and 10000")
+			return
+		}
+		batchSize = parsed
+	}
+
+	// Импорт большого файла длится дольше таймаутов сервера, снимаем их для этого запроса
+	controller := http.NewResponseController(w)
+	_ = controller.SetReadDeadline(time.Time{})
+	_ = controller.SetWriteDeadline(time.Time{})
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+	encoder := json.NewEncoder(w)
+
+	importer := kafka.NewImporter(h.processor, batchSize, h.logger)
+	summary, err := importer.Import(r.Context(), r.Body, orderHeaders(r), func(result kafka.ImportLineResult) error {
+		if err := encoder.Encode(result); err != nil {
+			return err
+		}
+		return controller.Flush()
+	})
+	if err != nil {
+		h.logger.WithError(err).Error("Order import aborted")
+		if err := encoder.Encode(map[string]interface{}{"error": err.Error(), "summary": summary}); err != nil {
+			h.logger.WithError(err).Warn("Failed to write import error")
+		}
+		return
+	}
+
+	if err := encoder.Encode(map[string]interface{}{"summary": summary}); err != nil {
+		h.logger.WithError(err).Warn("Failed to write import summary")
+	}
+}
@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"order-service/internal/database"
+	"order-service/internal/kafka"
+	"order-service/internal/models"
+	"order-service/internal/validation"
+	"strings"
+)
+
+// maxOrderBodySize ограничивает размер тела запроса с заказом
+const maxOrderBodySize = 1 << 20
+
+// ValidationReport - результат проверки заказа без сохранения
+type ValidationReport struct {
+	Valid    bool                       `json:"valid"`
+	Errors   []validation.Violation     `json:"errors,omitempty"`
+	Warnings []models.ValidationWarning `json:"warnings,omitempty"`
+	Order    *models.OrderFull          `json:"order,omitempty"` // Заказ после автоисправлений
+}
+
+// IngestResult - результат приема заказа по HTTP
+type IngestResult struct {
+	Result   database.UpsertResult      `json:"result"` // created, unchanged, ignored или overwritten
+	Warnings []models.ValidationWarning `json:"warnings,omitempty"`
+	Order    *models.OrderFull          `json:"order"`
+}
+
+// CreateOrder принимает заказ в формате сообщения Kafka и сохраняет его тем же путем,
+// что и consumer: разбор, валидация, идемпотентная запись в БД и обновление кеша.
+// Новый заказ возвращает 201, повторная доставка - 200, конфликт содержимого - 409
+func (h *HTTPHandler) CreateOrder(w http.ResponseWriter, r *http.Request) {
+	body, ok := h.readOrderBody(w, r)
+	if !ok {
+		return
+	}
+
+	orderFull, result, err := h.processor.Process(body, orderHeaders(r))
+	if err != nil {
+		switch {
+		case kafka.IsValidationError(err):
+			h.writeValidationFailure(w, err)
+		case errors.Is(err, database.ErrOrderConflict):
+			h.writeErrorResponse(w, http.StatusConflict, err.Error())
+		default:
+			h.logger.WithError(err).Error("Failed to create order")
+			h.writeErrorResponse(w, http.StatusInternalServerError, "Internal server error")
+		}
+		return
+	}
+
+	statusCode := http.StatusOK
+	if result == database.UpsertCreated {
+		statusCode = http.StatusCreated
+	}
+	h.writeJSONResponse(w, statusCode, APIResponse{
+		Success: true,
+		Data: IngestResult{
+			Result:   result,
+			Warnings: orderFull.ValidationWarnings,
+			Order:    orderFull,
+		},
+	})
+}
+
+// ValidateOrder проверяет JSON заказа тем же путем разбора и валидации, что и Kafka consumer,
+// и возвращает все нарушения. Ничего не сохраняет. Версию схемы можно передать заголовком schema-version
+func (h *HTTPHandler) ValidateOrder(w http.ResponseWriter, r *http.Request) {
+	body, ok := h.readOrderBody(w, r)
+	if !ok {
+		return
+	}
+
+	orderFull, err := h.processor.ParseAndValidate(body, orderHeaders(r))
+	if err != nil {
+		if !kafka.IsValidationError(err) {
+			h.logger.WithError(err).Error("Failed to validate order")
+			h.writeErrorResponse(w, http.StatusInternalServerError, "Internal server error")
+			return
+		}
+		h.writeValidationFailure(w, err)
+		return
+	}
+
+	h.writeSuccessResponse(w, ValidationReport{
+		Valid:    true,
+		Warnings: orderFull.ValidationWarnings,
+		Order:    orderFull,
+	})
+}
+
+// readOrderBody читает тело запроса с заказом. При ошибке ответ уже записан
+func (h *HTTPHandler) readOrderBody(w http.ResponseWriter, r *http.Request) ([]byte, bool) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxOrderBodySize))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			h.writeErrorResponse(w, http.StatusRequestEntityTooLarge, "Request body is too large")
+			return nil, false
+		}
+		// Клиент оборвал соединение или прислал некорректное chunked-тело
+		h.logger.WithError(err).Warn("Failed to read request body")
+		h.writeErrorResponse(w, http.StatusBadRequest, "Failed to read request body")
+		return nil, false
+	}
+	if len(strings.TrimSpace(string(body))) == 0 {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Request body is required")
+		return nil, false
+	}
+	return body, true
+}
+
+// writeValidationFailure возвращает 422 со списком всех нарушений
+func (h *HTTPHandler) writeValidationFailure(w http.ResponseWriter, err error) {
+	h.writeJSONResponse(w, http.StatusUnprocessableEntity, APIResponse{
+		Success: false,
+		Data:    ValidationReport{Valid: false, Errors: kafka.Violations(err)},
+		Error:   err.Error(),
+	})
+}
+
+// orderHeaders возвращает заголовки сообщения для заказа, присланного по HTTP.
+// Тело всегда JSON, версия схемы берется из заголовка schema-version запроса
+func orderHeaders(r *http.Request) map[string]string {
+	headers := map[string]string{kafka.HeaderContentType: kafka.ContentTypeJSON}
+	if version := r.Header.Get(kafka.HeaderSchemaVersion); version != "" {
+		headers[kafka.HeaderSchemaVersion] = version
+	}
+	return headers
+}
@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"net/http"
+	"order-service/internal/database"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// GetOrdersByTrackNumber возвращает заказы с трек-номером от новых к старым.
+// Кеш отвечает только тогда, когда в нем есть полный набор заказов трек-номера
+func (h *HTTPHandler) GetOrdersByTrackNumber(w http.ResponseWriter, r *http.Request) {
+	trackNumber := mux.Vars(r)["track_number"]
+	if strings.TrimSpace(trackNumber) == "" {
+		h.writeErrorResponse(w, http.StatusBadRequest, "track_number is required")
+		return
+	}
+
+	logger := h.logger.WithField("track_number", trackNumber)
+
+	orders, found := h.cache.GetByTrackNumber(trackNumber)
+	if found {
+		logger.Debug("Orders found in cache")
+	} else {
+		var err error
+		orders, err = h.db.GetOrdersByTrackNumber(r.Context(), trackNumber)
+		if err != nil {
+			logger.WithError(err).Error("Failed to get orders by track number from database")
+			h.writeErrorResponse(w, http.StatusInternalServerError, "Internal server error")
+			return
+		}
+		if len(orders) == 0 {
+			h.writeErrorResponse(w, http.StatusNotFound, "Orders not found")
+			return
+		}
+		h.cache.SetTrackNumberOrders(trackNumber, orders, database.MaxTrackNumberOrders)
+	}
+
+	h.writeSuccessResponse(w, map[string]interface{}{
+		"orders": orders,
+		"count":  len(orders),
+	})
+}
+
+// GetCustomerOrders возвращает страницу заказов клиента от новых к старым.
+// Параметры limit и cursor как у GetAllOrders; кеш не используется,
+// так как в нем может быть только часть заказов клиента
+func (h *HTTPHandler) GetCustomerOrders(w http.ResponseWriter, r *http.Request) {
+	customerID := mux.Vars(r)["customer_id"]
+	if strings.TrimSpace(customerID) == "" {
+		h.writeErrorResponse(w, http.StatusBadRequest, "customer_id is required")
+		return
+	}
+
+	limit, after, err := parsePage(r.URL.Query())
+	if err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	orders, next, err := h.db.GetOrdersByCustomer(r.Context(), customerID, limit, after)
+	if err != nil {
+		h.logger.WithError(err).WithField("customer_id", customerID).Error("Failed to get customer orders from database")
+		h.writeErrorResponse(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	h.writeOrderPage(w, orders, limit, next)
+}
+
+// GetOrderByTransaction возвращает заказ по ID транзакции платежа (сначала из кеша, затем из БД)
+func (h *HTTPHandler) GetOrderByTransaction(w http.ResponseWriter, r *http.Request) {
+	transaction := mux.Vars(r)["transaction"]
+	if strings.TrimSpace(transaction) == "" {
+		h.writeErrorResponse(w, http.StatusBadRequest, "transaction is required")
+		return
+	}
+
+	logger := h.logger.WithField("transaction", transaction)
+
+	if order, found := h.cache.GetByTransaction(transaction); found {
+		logger.Debug("Order found in cache")
+		h.writeSuccessResponse(w, order)
+		return
+	}
+
+	order, err := h.db.GetOrderByTransaction(r.Context(), transaction)
+	if err != nil {
+		logger.WithError(err).Error("Failed to get order by transaction from database")
+		h.writeErrorResponse(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+	if order == nil {
+		h.writeErrorResponse(w, http.StatusNotFound, "Order not found")
+		return
+	}
+
+	h.cache.Set(order.OrderUID, order)
+	h.writeSuccessResponse(w, order)
+}
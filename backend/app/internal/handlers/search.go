@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"net/http"
+	"order-service/internal/database"
+	"strconv"
+	"strings"
+)
+
+// maxSearchQueryLength ограничивает длину поискового запроса
+const maxSearchQueryLength = 256
+
+// SearchOrders выполняет полнотекстовый поиск заказов.
+// Параметры: q (обязательный, синтаксис websearch_to_tsquery) и limit (по умолчанию 20, не больше 100)
+func (h *HTTPHandler) SearchOrders(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	q := strings.TrimSpace(query.Get("q"))
+	if q == "" {
+		h.writeErrorResponse(w, http.StatusBadRequest, "q is required")
+		return
+	}
+	if len(q) > maxSearchQueryLength {
+		h.writeErrorResponse(w, http.StatusBadRequest, "q is too long")
+		return
+	}
+
+	limit := 20 // по умолчанию
+	if limitStr := query.Get("limit"); limitStr != "" {
+		if parsedLimit, err := strconv.Atoi(limitStr); err == nil && parsedLimit > 0 && parsedLimit <= 100 {
+			limit = parsedLimit
+		}
+	}
+
+	hits, err := h.db.SearchOrders(r.Context(), q, limit)
+	if err != nil {
+		h.logger.WithError(err).WithField("query", q).Error("Failed to search orders")
+		h.writeErrorResponse(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+	if hits == nil {
+		hits = []database.SearchHit{}
+	}
+
+	h.writeSuccessResponse(w, map[string]interface{}{
+		"query":   q,
+		"results": hits,
+		"count":   len(hits),
+	})
+}
@@ -0,0 +1,38 @@
+package kafka
+
+import (
+	_ "embed"
+	"fmt"
+	"order-service/internal/models"
+
+	"github.com/linkedin/goavro/v2"
+)
+
+//go:embed schemas/order_v1.avsc
+var orderAvroSchemaV1 string
+
+// newAvroOrderDecoder создает декодер бинарного Avro (без префикса schema registry)
+// по схеме записи. Схема не содержит union'ов, поэтому ее текстовое JSON-представление
+// совпадает с JSON-форматом заказа и дальше разбирается тем же декодером
+func newAvroOrderDecoder(schema string) (OrderDecoder, error) {
+	codec, err := goavro.NewCodec(schema)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse avro schema: %w", err)
+	}
+
+	return func(data []byte) (*models.KafkaOrderMessage, error) {
+		native, rest, err := codec.NativeFromBinary(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to unmarshal avro: %w", err)
+		}
+		if len(rest) > 0 {
+			return nil, fmt.Errorf("failed to unmarshal avro: %d trailing bytes", len(rest))
+		}
+
+		textual, err := codec.TextualFromNative(nil, native)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert avro to JSON: %w", err)
+		}
+		return decodeOrderJSON(textual)
+	}, nil
+}
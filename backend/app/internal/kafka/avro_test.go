@@ -0,0 +1,120 @@
+package kafka
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/linkedin/goavro/v2"
+)
+
+func newTestAvroDecoder(t *testing.T) OrderDecoder {
+	t.Helper()
+	decoder, err := newAvroOrderDecoder(orderAvroSchemaV1)
+	if err != nil {
+		t.Fatalf("newAvroOrderDecoder() error = %v", err)
+	}
+	return decoder
+}
+
+func TestDecodeOrderAvroProducerOutput(t *testing.T) {
+	msg, err := newTestAvroDecoder(t)(readTestdata(t, "order_v1.avro"))
+	if err != nil {
+		t.Fatalf("avro decoder error = %v", err)
+	}
+	assertOrderMessage(t, msg, loadTestOrder(t))
+}
+
+// avroRecord - запись Avro-схемы с вложенными записями и массивами записей
+type avroRecord struct {
+	Name   string `json:"name"`
+	Fields []struct {
+		Name string          `json:"name"`
+		Type json.RawMessage `json:"type"`
+	} `json:"fields"`
+}
+
+// compareAvroFields проверяет, что поля записи схемы совпадают с полями JSON-представления заказа:
+// поле, которого нет в схеме или в JSON, при переводе Avro в JSON потеряется
+func compareAvroFields(t *testing.T, record avroRecord, value map[string]interface{}) {
+	t.Helper()
+	inSchema := make(map[string]bool)
+	for _, field := range record.Fields {
+		inSchema[field.Name] = true
+		fieldValue, ok := value[field.Name]
+		if !ok {
+			t.Errorf("avro field %s.%s is missing from testdata/order_v1.json", record.Name, field.Name)
+			continue
+		}
+
+		var nested avroRecord
+		var array struct {
+			Items avroRecord `json:"items"`
+		}
+		switch {
+		case json.Unmarshal(field.Type, &nested) == nil && nested.Fields != nil:
+			compareAvroFields(t, nested, fieldValue.(map[string]interface{}))
+		case json.Unmarshal(field.Type, &array) == nil && array.Items.Fields != nil:
+			for _, item := range fieldValue.([]interface{}) {
+				compareAvroFields(t, array.Items, item.(map[string]interface{}))
+			}
+		}
+	}
+	for name := range value {
+		if !inSchema[name] {
+			t.Errorf("JSON field %s.%s is missing from order_v1.avsc", record.Name, name)
+		}
+	}
+}
+
+func TestDecodeOrderAvroMatchesSchema(t *testing.T) {
+	textual := readTestdata(t, "order_v1.json")
+
+	var schema avroRecord
+	if err := json.Unmarshal([]byte(orderAvroSchemaV1), &schema); err != nil {
+		t.Fatal(err)
+	}
+	var value map[string]interface{}
+	if err := json.Unmarshal(textual, &value); err != nil {
+		t.Fatal(err)
+	}
+	compareAvroFields(t, schema, value)
+
+	codec, err := goavro.NewCodec(orderAvroSchemaV1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	native, _, err := codec.NativeFromTextual(textual)
+	if err != nil {
+		t.Fatalf("NativeFromTextual() error = %v", err)
+	}
+	data, err := codec.BinaryFromNative(nil, native)
+	if err != nil {
+		t.Fatalf("BinaryFromNative() error = %v", err)
+	}
+
+	msg, err := newTestAvroDecoder(t)(data)
+	if err != nil {
+		t.Fatalf("avro decoder error = %v", err)
+	}
+	assertOrderMessage(t, msg, loadTestOrder(t))
+}
+
+func TestDecodeOrderAvroErrors(t *testing.T) {
+	data := readTestdata(t, "order_v1.avro")
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{name: "truncated", data: data[:len(data)/2]},
+		{name: "trailing bytes", data: append(append([]byte{}, data...), 0)},
+	}
+
+	decoder := newTestAvroDecoder(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := decoder(tt.data); err == nil {
+				t.Error("avro decoder succeeded, want error")
+			}
+		})
+	}
+}
@@ -2,13 +2,13 @@ package kafka
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
-	"order-service/internal/cache"
+	"hash/fnv"
 	"order-service/internal/database"
 	"order-service/internal/models"
 	"order-service/pkg/config"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/segmentio/kafka-go"
@@ -16,70 +16,140 @@ import (
 )
 
 type Consumer struct {
-	reader   *kafka.Reader
-	db       database.OrderRepository
-	cache    cache.OrderCache
-	logger   *logrus.Logger
-	stopChan chan struct{}
-	stopped  bool
+	reader    *kafka.Reader
+	dlq       *DeadLetterPublisher
+	processor *OrderProcessor
+	retry     retryPolicy
+	logger    *logrus.Logger
+	stopChan  chan struct{}
+	done      chan struct{} // закрывается после выхода из цикла обработки
+	stopped   bool
+
+	workers      int
+	batchSize    int
+	batchTimeout time.Duration
 }
 
+const (
+	workerQueueSize = 100  // Размер очереди сообщений одного воркера
+	commitQueueSize = 1000 // Размер очереди offset'ов, ожидающих коммита
+)
+
 type MessageProcessor interface {
 	Start(ctx context.Context) error
 	Stop() error
 }
 
 // NewConsumer создает новый Kafka consumer
-func NewConsumer(cfg *config.KafkaConfig, db database.OrderRepository, cache cache.OrderCache, logger *logrus.Logger) *Consumer {
+func NewConsumer(cfg *config.KafkaConfig, processor *OrderProcessor, logger *logrus.Logger) *Consumer {
 	reader := kafka.NewReader(kafka.ReaderConfig{
-		Brokers:     cfg.Brokers,
-		Topic:       cfg.Topic,
-		GroupID:     cfg.GroupID,
-		MinBytes:    1,        // 1 байт минимум
-		MaxBytes:    10e6,     // 10MB максимум
-		MaxWait:     1 * time.Second, // Увеличиваем таймаут ожидания
-		StartOffset: kafka.FirstOffset, // Начинаем с первого сообщения
+		Brokers:        cfg.Brokers,
+		Topic:          cfg.Topic,
+		GroupID:        cfg.GroupID,
+		MinBytes:       1,                 // 1 байт минимум
+		MaxBytes:       10e6,              // 10MB максимум
+		MaxWait:        1 * time.Second,   // Увеличиваем таймаут ожидания
+		StartOffset:    kafka.FirstOffset, // Начинаем с первого сообщения
+		CommitInterval: 0,                 // Синхронный коммит: offset фиксируется только явным CommitMessages
 		ErrorLogger: kafka.LoggerFunc(func(msg string, args ...interface{}) {
 			// Логируем только критические ошибки, игнорируем таймауты
 			if !strings.Contains(fmt.Sprintf(msg, args...), "timeout") &&
-			   !strings.Contains(fmt.Sprintf(msg, args...), "deadline exceeded") {
+				!strings.Contains(fmt.Sprintf(msg, args...), "deadline exceeded") {
 				logger.Errorf("Kafka error: "+msg, args...)
 			}
 		}),
 	})
 
+	var dlq *DeadLetterPublisher
+	if cfg.DLQTopic != "" {
+		dlq = NewDeadLetterPublisher(cfg, logger)
+	}
+
+	workers := cfg.Workers
+	if workers < 1 {
+		workers = 1
+	}
+	batchSize := cfg.BatchSize
+	if batchSize < 1 {
+		batchSize = 1
+	}
+	batchTimeout := cfg.BatchTimeout
+	if batchTimeout <= 0 {
+		batchTimeout = 500 * time.Millisecond
+	}
+
 	return &Consumer{
-		reader:   reader,
-		db:       db,
-		cache:    cache,
+		reader:    reader,
+		dlq:       dlq,
+		processor: processor,
+		retry: retryPolicy{
+			maxAttempts:    cfg.RetryMaxAttempts,
+			initialBackoff: cfg.RetryInitialBackoff,
+			maxBackoff:     cfg.RetryMaxBackoff,
+		},
 		logger:   logger,
 		stopChan: make(chan struct{}),
+		done:     make(chan struct{}),
+		workers:  workers,
+
+		batchSize:    batchSize,
+		batchTimeout: batchTimeout,
 	}
 }
 
-// Start запускает consumer в отдельной горутине
+// Start запускает consumer: цикл получения сообщений, пул воркеров и горутину коммитов
 func (c *Consumer) Start(ctx context.Context) error {
-	c.logger.Info("Starting Kafka consumer")
+	c.logger.WithFields(logrus.Fields{
+		"workers":       c.workers,
+		"batch_size":    c.batchSize,
+		"batch_timeout": c.batchTimeout,
+	}).Info("Starting Kafka consumer")
 
+	// Контекст отменяется при остановке, чтобы прервать ожидание новых сообщений
+	runCtx, cancel := context.WithCancel(ctx)
 	go func() {
-		for {
-			select {
-			case <-ctx.Done():
-				c.logger.Info("Context cancelled, stopping consumer")
-				return
-			case <-c.stopChan:
-				c.logger.Info("Stop signal received, stopping consumer")
-				return
-			default:
-				if err := c.processMessage(ctx); err != nil {
-					// Логируем только если это не таймаут
-					if !strings.Contains(err.Error(), "deadline exceeded") &&
-					   !strings.Contains(err.Error(), "timeout") {
-						c.logger.WithError(err).Error("Failed to process Kafka message")
-					}
-				}
-			}
+		select {
+		case <-c.stopChan:
+			cancel()
+		case <-runCtx.Done():
+		}
+	}()
+
+	commits := make(chan kafka.Message, commitQueueSize)
+	tracker := newOffsetTracker(commits)
+
+	// Каждый воркер получает сообщения своего набора ключей, поэтому
+	// сообщения одного заказа обрабатываются последовательно
+	queues := make([]chan kafka.Message, c.workers)
+	var workers sync.WaitGroup
+	for i := range queues {
+		queues[i] = make(chan kafka.Message, workerQueueSize)
+		workers.Add(1)
+		go func(queue <-chan kafka.Message) {
+			defer workers.Done()
+			c.runWorker(runCtx, queue, tracker)
+		}(queues[i])
+	}
+
+	committerDone := make(chan struct{})
+	go func() {
+		defer close(committerDone)
+		c.runCommitter(commits)
+	}()
+
+	go func() {
+		defer close(c.done)
+		defer cancel()
+
+		c.fetchLoop(runCtx, tracker, queues)
+
+		// Дожидаемся воркеров, затем коммитим все, что они успели обработать
+		for _, queue := range queues {
+			close(queue)
 		}
+		workers.Wait()
+		close(commits)
+		<-committerDone
 	}()
 
 	return nil
@@ -93,23 +163,75 @@ func (c *Consumer) Stop() error {
 	c.logger.Info("Stopping Kafka consumer")
 	c.stopped = true
 	close(c.stopChan)
+
+	// Дожидаемся завершения обработки текущего сообщения, прежде чем закрывать reader.
+	// Необработанные сообщения не коммитятся и будут получены повторно после перезапуска
+	select {
+	case <-c.done:
+	case <-time.After(30 * time.Second):
+		c.logger.Warn("Timed out waiting for Kafka consumer to finish current message")
+	}
+
+	if c.dlq != nil {
+		if err := c.dlq.Close(); err != nil {
+			c.logger.WithError(err).Error("Failed to close dead-letter publisher")
+		}
+	}
 	return c.reader.Close()
 }
 
-// processMessage обрабатывает одно сообщение из Kafka
-func (c *Consumer) processMessage(ctx context.Context) error {
+// fetchLoop получает сообщения из Kafka и распределяет их по воркерам
+func (c *Consumer) fetchLoop(ctx context.Context, tracker *offsetTracker, queues []chan kafka.Message) {
+	for {
+		select {
+		case <-ctx.Done():
+			c.logger.Info("Stopping Kafka fetch loop")
+			return
+		default:
+		}
+
+		msg, err := c.fetchMessage(ctx)
+		if err != nil {
+			// Логируем только если это не таймаут
+			if !strings.Contains(err.Error(), "deadline exceeded") &&
+				!strings.Contains(err.Error(), "timeout") {
+				c.logger.WithError(err).Error("Failed to fetch Kafka message")
+			}
+			continue
+		}
+		if msg == nil {
+			continue
+		}
+
+		tracker.track(*msg)
+
+		select {
+		case queues[workerIndex(*msg, len(queues))] <- *msg:
+		case <-ctx.Done():
+			// Сообщение не обработано и не будет закоммичено
+			return
+		}
+	}
+}
+
+// fetchMessage получает следующее сообщение из Kafka (без коммита offset'а)
+func (c *Consumer) fetchMessage(ctx context.Context) (*kafka.Message, error) {
 	// Устанавливаем таймаут для чтения сообщения
 	readCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
 
-	msg, err := c.reader.ReadMessage(readCtx)
+	msg, err := c.reader.FetchMessage(readCtx)
 	if err != nil {
+		// Consumer останавливается
+		if ctx.Err() != nil {
+			return nil, nil
+		}
 		if err == context.DeadlineExceeded {
 			time.Sleep(2 * time.Second)
-			return nil
+			return nil, nil
 		}
 		time.Sleep(1 * time.Second)
-		return fmt.Errorf("failed to read message: %w", err)
+		return nil, fmt.Errorf("failed to read message: %w", err)
 	}
 
 	c.logger.WithFields(logrus.Fields{
@@ -118,167 +240,263 @@ func (c *Consumer) processMessage(ctx context.Context) error {
 		"topic":     msg.Topic,
 	}).Debug("Received Kafka message")
 
-	// Парсим и валидируем сообщение
-	orderFull, err := c.parseAndValidateMessage(msg.Value)
-	if err != nil {
-		c.logger.WithError(err).WithField("raw_message", string(msg.Value)).Error("Failed to parse message")
-		// Возвращаем nil, чтобы не останавливать consumer из-за одного невалидного сообщения
-		return nil
-	}
+	return &msg, nil
+}
 
-	// Проверяем, что заказ еще не существует
-	exists, err := c.db.OrderExists(orderFull.OrderUID)
-	if err != nil {
-		return fmt.Errorf("failed to check if order exists: %w", err)
-	}
+// batchEntry - провалидированный заказ, ожидающий сохранения в пачке
+type batchEntry struct {
+	msg   kafka.Message
+	order *models.OrderFull
+}
 
-	if exists {
-		c.logger.WithField("order_uid", orderFull.OrderUID).Info("Order already exists, skipping")
-		return nil
+// runWorker обрабатывает сообщения из своей очереди, накапливая валидные заказы
+// в пачку до batchSize сообщений или batchTimeout с момента первого сообщения.
+// Пачка, которую не удалось сохранить из-за временной ошибки, повторяется до успеха,
+// чтобы не нарушить порядок обработки сообщений одного ключа
+func (c *Consumer) runWorker(ctx context.Context, queue <-chan kafka.Message, tracker *offsetTracker) {
+	batch := make([]batchEntry, 0, c.batchSize)
+	timer := time.NewTimer(c.batchTimeout)
+	timer.Stop()
+	defer timer.Stop()
+
+	flush := func() {
+		timer.Stop()
+		if len(batch) == 0 {
+			return
+		}
+		if c.untilDone(ctx, func() error { return c.storeBatch(ctx, batch) }) {
+			for _, entry := range batch {
+				tracker.markDone(entry.msg)
+			}
+		}
+		batch = batch[:0]
 	}
 
-	// Сохраняем в базу данных
-	if err := c.db.CreateOrder(orderFull); err != nil {
-		return fmt.Errorf("failed to save order to database: %w", err)
-	}
+	for {
+		select {
+		case msg, ok := <-queue:
+			if !ok {
+				// Очередь закрыта при остановке: сохраняем то, что уже накоплено
+				flush()
+				return
+			}
+			// При остановке оставшиеся сообщения не обрабатываются и не коммитятся
+			if ctx.Err() != nil {
+				continue
+			}
 
-	// Добавляем в кеш
-	c.cache.Set(orderFull.OrderUID, orderFull)
+			headers := headerMap(msg.Headers)
+			eventType, err := c.processor.EventType(msg.Value, headers)
+			if err == nil && eventType == models.EventOrderStatusChanged {
+				// Накопленные заказы сохраняются первыми, чтобы событие применилось к уже созданному заказу
+				flush()
+				if c.untilDone(ctx, func() error { return c.applyStatusChange(ctx, msg) }) {
+					tracker.markDone(msg)
+				}
+				continue
+			}
 
-	c.logger.WithField("order_uid", orderFull.OrderUID).Info("Order processed successfully")
-	return nil
-}
+			var orderFull *models.OrderFull
+			if err == nil {
+				orderFull, err = c.processor.ParseAndValidate(msg.Value, headers)
+			}
+			if err != nil {
+				c.logger.WithError(err).WithField("raw_message", string(msg.Value)).Error("Failed to parse message")
+				// Отправляем сообщение в DLQ, чтобы его можно было найти и переиграть
+				if c.untilDone(ctx, func() error { return c.sendToDeadLetter(ctx, msg, err) }) {
+					tracker.markDone(msg)
+				}
+				continue
+			}
 
-// parseAndValidateMessage парсит JSON сообщение и конвертирует его в модель
-func (c *Consumer) parseAndValidateMessage(data []byte) (*models.OrderFull, error) {
-	var kafkaMsg models.KafkaOrderMessage
-	if err := json.Unmarshal(data, &kafkaMsg); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal JSON: %w", err)
-	}
+			batch = append(batch, batchEntry{msg: msg, order: orderFull})
+			if len(batch) == 1 {
+				timer.Reset(c.batchTimeout)
+			}
+			if len(batch) >= c.batchSize {
+				flush()
+			}
 
-	// Валидация обязательных полей
-	if err := c.validateKafkaMessage(&kafkaMsg); err != nil {
-		return nil, fmt.Errorf("message validation failed: %w", err)
+		case <-timer.C:
+			flush()
+		}
 	}
+}
 
-	// Конвертируем в наши модели
-	orderFull, err := c.convertKafkaToModel(&kafkaMsg)
-	if err != nil {
-		return nil, fmt.Errorf("failed to convert message: %w", err)
+// untilDone повторяет операцию, пока она не завершится успешно.
+// Возвращает false, если consumer останавливается
+func (c *Consumer) untilDone(ctx context.Context, op func() error) bool {
+	for {
+		err := op()
+		if err == nil {
+			return true
+		}
+		if ctx.Err() != nil {
+			return false
+		}
+
+		c.logger.WithError(err).WithField("retry_in", c.retry.maxBackoff).Error("Failed to process messages, will retry")
+		c.sleep(ctx, c.retry.maxBackoff)
 	}
+}
+
+// runCommitter коммитит offset'ы, которые выдает offsetTracker.
+// Накопившиеся коммиты объединяются в один (см. commitQueue)
+func (c *Consumer) runCommitter(commits <-chan kafka.Message) {
+	queue := newCommitQueue()
+	for msg := range commits {
+		queue.add(msg)
+
+	drain:
+		for {
+			select {
+			case next, ok := <-commits:
+				if !ok {
+					break drain
+				}
+				queue.add(next)
+			default:
+				break drain
+			}
+		}
+		batch := queue.take()
+		if len(batch) == 0 {
+			continue
+		}
 
-	return orderFull, nil
+		// Коммитим с отдельным таймаутом, чтобы успеть зафиксировать обработанные сообщения
+		// даже во время остановки. Если коммит не удался, сообщения будут получены повторно,
+		// а повторное сохранение безопасно: UpsertOrder вставляет заказ через ON CONFLICT
+		// и по content_hash распознает повторную доставку того же содержимого
+		commitCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		if err := c.reader.CommitMessages(commitCtx, batch...); err != nil {
+			c.logger.WithError(err).Error("Failed to commit Kafka offsets")
+		} else {
+			queue.committed(batch)
+		}
+		cancel()
+	}
 }
 
-// validateKafkaMessage проверяет валидность данных
-func (c *Consumer) validateKafkaMessage(msg *models.KafkaOrderMessage) error {
-	if strings.TrimSpace(msg.OrderUID) == "" {
-		return fmt.Errorf("order_uid is required")
+// workerIndex выбирает воркер по ключу сообщения (order_uid), чтобы сообщения
+// одного заказа всегда обрабатывались одним воркером по порядку
+func workerIndex(msg kafka.Message, workers int) int {
+	if len(msg.Key) == 0 {
+		return msg.Partition % workers
 	}
-	if strings.TrimSpace(msg.TrackNumber) == "" {
-		return fmt.Errorf("track_number is required")
+	h := fnv.New32a()
+	h.Write(msg.Key)
+	return int(h.Sum32() % uint32(workers))
+}
+
+// storeBatch сохраняет пачку заказов, повторяя попытки при временных ошибках.
+// Если пачка не сохраняется из-за постоянной ошибки, заказы сохраняются по одному,
+// чтобы в DLQ попали только проблемные сообщения.
+// Ошибка возвращается только если пачку нужно обработать повторно
+func (c *Consumer) storeBatch(ctx context.Context, batch []batchEntry) error {
+	orders := make([]*models.OrderFull, len(batch))
+	for i, entry := range batch {
+		orders[i] = entry.order
 	}
-	if strings.TrimSpace(msg.CustomerID) == "" {
-		return fmt.Errorf("customer_id is required")
+
+	var created []string
+	err := c.retry.do(ctx, c.logger.WithField("batch_size", len(batch)), func() error {
+		var err error
+		created, err = c.processor.StoreBatch(orders)
+		return err
+	})
+	if err == nil {
+		// Заказы, которые уже были в базе, проходят через идемпотентную запись,
+		// чтобы применить политику конфликтов к отличающемуся содержимому
+		createdSet := make(map[string]bool, len(created))
+		for _, orderUID := range created {
+			createdSet[orderUID] = true
+		}
+		for _, entry := range batch {
+			if createdSet[entry.order.OrderUID] {
+				delete(createdSet, entry.order.OrderUID)
+				continue
+			}
+			if err := c.storeOrder(ctx, entry); err != nil {
+				return err
+			}
+		}
+		return nil
 	}
-	if len(msg.Items) == 0 {
-		return fmt.Errorf("items are required")
+	if database.IsTransientError(err) || ctx.Err() != nil {
+		return fmt.Errorf("failed to store order batch: %w", err)
 	}
 
-	// Валидация доставки
-	if strings.TrimSpace(msg.Delivery.Name) == "" {
-		return fmt.Errorf("delivery name is required")
-	}
-	if strings.TrimSpace(msg.Delivery.Phone) == "" {
-		return fmt.Errorf("delivery phone is required")
+	c.logger.WithError(err).WithField("batch_size", len(batch)).Warn("Failed to store order batch, storing orders one by one")
+	for _, entry := range batch {
+		if err := c.storeOrder(ctx, entry); err != nil {
+			return err
+		}
 	}
+	return nil
+}
+
+// storeOrder сохраняет один заказ, повторяя попытки при временных ошибках.
+// Заказ, который нельзя сохранить из-за постоянной ошибки, отправляется в DLQ
+func (c *Consumer) storeOrder(ctx context.Context, entry batchEntry) error {
+	orderUID := entry.order.OrderUID
 
-	// Валидация платежа
-	if msg.Payment.Amount <= 0 {
-		return fmt.Errorf("payment amount must be positive")
+	err := c.retry.do(ctx, c.logger.WithField("order_uid", orderUID), func() error {
+		_, err := c.processor.Store(entry.order)
+		return err
+	})
+	if err == nil {
+		return nil
 	}
-	if strings.TrimSpace(msg.Payment.Currency) == "" {
-		return fmt.Errorf("payment currency is required")
+	if database.IsTransientError(err) || ctx.Err() != nil {
+		return fmt.Errorf("failed to store order %s: %w", orderUID, err)
 	}
 
-	return nil
+	c.logger.WithError(err).WithField("order_uid", orderUID).Error("Failed to store order")
+	return c.sendToDeadLetter(ctx, entry.msg, err)
 }
 
-// convertKafkaToModel конвертирует Kafka сообщение в наши модели
-func (c *Consumer) convertKafkaToModel(msg *models.KafkaOrderMessage) (*models.OrderFull, error) {
-	// Парсим дату
-	dateCreated, err := time.Parse(time.RFC3339, msg.DateCreated)
+// applyStatusChange применяет событие изменения статуса, повторяя попытки при временных ошибках.
+// Невалидное событие или событие, которое нельзя применить (например, заказ не найден),
+// отправляется в DLQ
+func (c *Consumer) applyStatusChange(ctx context.Context, msg kafka.Message) error {
+	change, err := c.processor.ParseStatusChange(msg.Value)
 	if err != nil {
-		c.logger.WithError(err).WithField("date", msg.DateCreated).Warn("Failed to parse date, using current time")
-		dateCreated = time.Now()
+		c.logger.WithError(err).WithField("raw_message", string(msg.Value)).Error("Failed to parse status change")
+		return c.sendToDeadLetter(ctx, msg, err)
 	}
 
-	// Основной заказ
-	order := models.Order{
-		OrderUID:          msg.OrderUID,
-		TrackNumber:       msg.TrackNumber,
-		Entry:             msg.Entry,
-		Locale:            msg.Locale,
-		InternalSignature: msg.InternalSignature,
-		CustomerID:        msg.CustomerID,
-		DeliveryService:   msg.DeliveryService,
-		Shardkey:          msg.Shardkey,
-		SmID:              msg.SmID,
-		DateCreated:       dateCreated,
-		OofShard:          msg.OofShard,
+	err = c.retry.do(ctx, c.logger.WithField("order_uid", change.OrderUID), func() error {
+		return c.processor.ApplyStatusChange(change)
+	})
+	if err == nil {
+		return nil
 	}
-
-	// Доставка
-	delivery := &models.Delivery{
-		OrderUID: msg.OrderUID,
-		Name:     msg.Delivery.Name,
-		Phone:    msg.Delivery.Phone,
-		Zip:      msg.Delivery.Zip,
-		City:     msg.Delivery.City,
-		Address:  msg.Delivery.Address,
-		Region:   msg.Delivery.Region,
-		Email:    msg.Delivery.Email,
+	if database.IsTransientError(err) || ctx.Err() != nil {
+		return fmt.Errorf("failed to apply status change for order %s: %w", change.OrderUID, err)
 	}
 
-	// Платеж
-	payment := &models.Payment{
-		OrderUID:     msg.OrderUID,
-		Transaction:  msg.Payment.Transaction,
-		RequestID:    msg.Payment.RequestID,
-		Currency:     msg.Payment.Currency,
-		Provider:     msg.Payment.Provider,
-		Amount:       msg.Payment.Amount,
-		PaymentDt:    msg.Payment.PaymentDt,
-		Bank:         msg.Payment.Bank,
-		DeliveryCost: msg.Payment.DeliveryCost,
-		GoodsTotal:   msg.Payment.GoodsTotal,
-		CustomFee:    msg.Payment.CustomFee,
-	}
+	c.logger.WithError(err).WithField("order_uid", change.OrderUID).Error("Failed to apply status change")
+	return c.sendToDeadLetter(ctx, msg, err)
+}
 
-	// Товары
-	var items []models.OrderItem
-	for _, kafkaItem := range msg.Items {
-		item := models.OrderItem{
-			OrderUID:    msg.OrderUID,
-			ChrtID:      kafkaItem.ChrtID,
-			TrackNumber: kafkaItem.TrackNumber,
-			Price:       kafkaItem.Price,
-			Rid:         kafkaItem.Rid,
-			Name:        kafkaItem.Name,
-			Sale:        kafkaItem.Sale,
-			Size:        kafkaItem.Size,
-			TotalPrice:  kafkaItem.TotalPrice,
-			NmID:        kafkaItem.NmID,
-			Brand:       kafkaItem.Brand,
-			Status:      kafkaItem.Status,
-		}
-		items = append(items, item)
+// sleep ждет указанное время или отмену контекста/остановку consumer'а
+func (c *Consumer) sleep(ctx context.Context, d time.Duration) {
+	select {
+	case <-ctx.Done():
+	case <-c.stopChan:
+	case <-time.After(d):
 	}
+}
 
-	return &models.OrderFull{
-		Order:    order,
-		Delivery: delivery,
-		Payment:  payment,
-		Items:    items,
-	}, nil
+// sendToDeadLetter отправляет отклоненное сообщение в dead-letter топик (если он настроен)
+func (c *Consumer) sendToDeadLetter(ctx context.Context, msg kafka.Message, reason error) error {
+	if c.dlq == nil {
+		c.logger.WithFields(logrus.Fields{
+			"partition": msg.Partition,
+			"offset":    msg.Offset,
+		}).Warn("Dead-letter topic is not configured, dropping message")
+		return nil
+	}
+	return c.dlq.Publish(ctx, msg, reason)
 }
@@ -0,0 +1,292 @@
+package kafka
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"order-service/pkg/config"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/sirupsen/logrus"
+)
+
+// Заголовки, которые добавляются к сообщению при отправке в dead-letter топик
+const (
+	HeaderOriginalTopic     = "x-original-topic"
+	HeaderOriginalPartition = "x-original-partition"
+	HeaderOriginalOffset    = "x-original-offset"
+	HeaderError             = "x-error"
+	HeaderFailedAt          = "x-failed-at"
+)
+
+// ErrDeadLetterNotFound возвращается, если по указанным партиции и offset нет сообщения
+var ErrDeadLetterNotFound = errors.New("dead letter not found")
+
+// DeadLetter описывает сообщение из dead-letter топика
+type DeadLetter struct {
+	Partition         int               `json:"partition"`
+	Offset            int64             `json:"offset"`
+	Key               string            `json:"key"`
+	OriginalTopic     string            `json:"original_topic"`
+	OriginalPartition int               `json:"original_partition"`
+	OriginalOffset    int64             `json:"original_offset"`
+	Error             string            `json:"error"`
+	FailedAt          string            `json:"failed_at"`
+	Headers           map[string]string `json:"headers,omitempty"`        // Исходные заголовки сообщения
+	Payload           json.RawMessage   `json:"payload,omitempty"`        // Тело сообщения, если это валидный JSON
+	RawPayload        string            `json:"raw_payload,omitempty"`    // Иначе - исходное тело как строка
+	PayloadBase64     string            `json:"payload_base64,omitempty"` // Бинарное тело (Protobuf, Avro) в base64
+	Value             []byte            `json:"-"`
+}
+
+// DeadLetterPublisher отправляет отклоненные сообщения в dead-letter топик,
+// чтобы их можно было найти и переиграть позже
+type DeadLetterPublisher struct {
+	writer *kafka.Writer
+	topic  string
+	logger *logrus.Logger
+}
+
+// NewDeadLetterPublisher создает publisher для dead-letter топика
+func NewDeadLetterPublisher(cfg *config.KafkaConfig, logger *logrus.Logger) *DeadLetterPublisher {
+	writer := &kafka.Writer{
+		Addr:                   kafka.TCP(cfg.Brokers...),
+		Topic:                  cfg.DLQTopic,
+		Balancer:               &kafka.Hash{}, // Сообщения с одним ключом попадают в одну партицию
+		RequiredAcks:           kafka.RequireAll,
+		AllowAutoTopicCreation: true,
+	}
+
+	return &DeadLetterPublisher{
+		writer: writer,
+		topic:  cfg.DLQTopic,
+		logger: logger,
+	}
+}
+
+// Publish отправляет исходное сообщение в dead-letter топик вместе с причиной отказа
+func (p *DeadLetterPublisher) Publish(ctx context.Context, msg kafka.Message, reason error) error {
+	headers := make([]kafka.Header, 0, len(msg.Headers)+5)
+	headers = append(headers, msg.Headers...)
+	headers = append(headers,
+		kafka.Header{Key: HeaderOriginalTopic, Value: []byte(msg.Topic)},
+		kafka.Header{Key: HeaderOriginalPartition, Value: []byte(strconv.Itoa(msg.Partition))},
+		kafka.Header{Key: HeaderOriginalOffset, Value: []byte(strconv.FormatInt(msg.Offset, 10))},
+		kafka.Header{Key: HeaderError, Value: []byte(reason.Error())},
+		kafka.Header{Key: HeaderFailedAt, Value: []byte(time.Now().UTC().Format(time.RFC3339))},
+	)
+
+	dlqMsg := kafka.Message{
+		Key:     msg.Key,
+		Value:   msg.Value,
+		Headers: headers,
+	}
+
+	if err := p.writer.WriteMessages(ctx, dlqMsg); err != nil {
+		return fmt.Errorf("failed to publish message to dead-letter topic %s: %w", p.topic, err)
+	}
+
+	p.logger.WithFields(logrus.Fields{
+		"dlq_topic": p.topic,
+		"partition": msg.Partition,
+		"offset":    msg.Offset,
+		"reason":    reason.Error(),
+	}).Warn("Message sent to dead-letter topic")
+
+	return nil
+}
+
+// Close закрывает writer dead-letter топика
+func (p *DeadLetterPublisher) Close() error {
+	return p.writer.Close()
+}
+
+// DeadLetterReader читает сообщения из dead-letter топика для просмотра и повторной обработки
+type DeadLetterReader struct {
+	brokers []string
+	topic   string
+}
+
+// NewDeadLetterReader создает reader для dead-letter топика
+func NewDeadLetterReader(cfg *config.KafkaConfig) *DeadLetterReader {
+	return &DeadLetterReader{
+		brokers: cfg.Brokers,
+		topic:   cfg.DLQTopic,
+	}
+}
+
+// List возвращает до limit последних сообщений из каждой партиции dead-letter топика
+func (r *DeadLetterReader) List(ctx context.Context, limit int) ([]DeadLetter, error) {
+	conn, err := kafka.DialContext(ctx, "tcp", r.brokers[0])
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to kafka: %w", err)
+	}
+	defer conn.Close()
+
+	partitions, err := conn.ReadPartitions(r.topic)
+	if err != nil {
+		if errors.Is(err, kafka.UnknownTopicOrPartition) {
+			return []DeadLetter{}, nil
+		}
+		return nil, fmt.Errorf("failed to read partitions of %s: %w", r.topic, err)
+	}
+
+	letters := []DeadLetter{}
+	for _, partition := range partitions {
+		partitionLetters, err := r.readPartition(ctx, partition.ID, limit)
+		if err != nil {
+			return nil, err
+		}
+		letters = append(letters, partitionLetters...)
+	}
+
+	return letters, nil
+}
+
+// Get возвращает одно сообщение из dead-letter топика по партиции и offset
+func (r *DeadLetterReader) Get(ctx context.Context, partition int, offset int64) (*DeadLetter, error) {
+	conn, err := r.dialPartition(ctx, partition)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	first, last, err := conn.ReadOffsets()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read offsets: %w", err)
+	}
+	if offset < first || offset >= last {
+		return nil, ErrDeadLetterNotFound
+	}
+
+	if _, err := conn.Seek(offset, kafka.SeekAbsolute); err != nil {
+		return nil, fmt.Errorf("failed to seek to offset %d: %w", offset, err)
+	}
+
+	batch := conn.ReadBatch(1, 10e6)
+	defer batch.Close()
+
+	msg, err := batch.ReadMessage()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read message: %w", err)
+	}
+	if msg.Offset != offset {
+		return nil, ErrDeadLetterNotFound
+	}
+
+	letter := newDeadLetter(msg)
+	return &letter, nil
+}
+
+// readPartition читает последние limit сообщений партиции
+func (r *DeadLetterReader) readPartition(ctx context.Context, partition int, limit int) ([]DeadLetter, error) {
+	conn, err := r.dialPartition(ctx, partition)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	first, last, err := conn.ReadOffsets()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read offsets: %w", err)
+	}
+
+	start := last - int64(limit)
+	if start < first {
+		start = first
+	}
+	if start >= last {
+		return nil, nil
+	}
+
+	if _, err := conn.Seek(start, kafka.SeekAbsolute); err != nil {
+		return nil, fmt.Errorf("failed to seek to offset %d: %w", start, err)
+	}
+
+	var letters []DeadLetter
+	next := start
+	for next < last {
+		progressed := false
+		batch := conn.ReadBatch(1, 10e6)
+		for next < last {
+			msg, err := batch.ReadMessage()
+			if err != nil {
+				break
+			}
+			letters = append(letters, newDeadLetter(msg))
+			next = msg.Offset + 1
+			progressed = true
+		}
+		if err := batch.Close(); err != nil {
+			return nil, fmt.Errorf("failed to read partition %d: %w", partition, err)
+		}
+		// Защита от бесконечного цикла, если брокер не вернул новых сообщений
+		if !progressed {
+			break
+		}
+	}
+
+	return letters, nil
+}
+
+func (r *DeadLetterReader) dialPartition(ctx context.Context, partition int) (*kafka.Conn, error) {
+	conn, err := kafka.DialLeader(ctx, "tcp", r.brokers[0], r.topic, partition)
+	if err != nil {
+		if errors.Is(err, kafka.UnknownTopicOrPartition) {
+			return nil, ErrDeadLetterNotFound
+		}
+		return nil, fmt.Errorf("failed to connect to partition %d of %s: %w", partition, r.topic, err)
+	}
+	if err := conn.SetReadDeadline(time.Now().Add(10 * time.Second)); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to set read deadline: %w", err)
+	}
+	return conn, nil
+}
+
+// newDeadLetter собирает DeadLetter из сообщения Kafka и его заголовков
+func newDeadLetter(msg kafka.Message) DeadLetter {
+	letter := DeadLetter{
+		Partition: msg.Partition,
+		Offset:    msg.Offset,
+		Key:       string(msg.Key),
+		Value:     msg.Value,
+	}
+
+	for _, header := range msg.Headers {
+		value := string(header.Value)
+		switch header.Key {
+		case HeaderOriginalTopic:
+			letter.OriginalTopic = value
+		case HeaderOriginalPartition:
+			letter.OriginalPartition, _ = strconv.Atoi(value)
+		case HeaderOriginalOffset:
+			letter.OriginalOffset, _ = strconv.ParseInt(value, 10, 64)
+		case HeaderError:
+			letter.Error = value
+		case HeaderFailedAt:
+			letter.FailedAt = value
+		default:
+			if letter.Headers == nil {
+				letter.Headers = make(map[string]string)
+			}
+			letter.Headers[header.Key] = value
+		}
+	}
+
+	switch {
+	case json.Valid(msg.Value):
+		letter.Payload = json.RawMessage(msg.Value)
+	case utf8.Valid(msg.Value):
+		letter.RawPayload = string(msg.Value)
+	default:
+		letter.PayloadBase64 = base64.StdEncoding.EncodeToString(msg.Value)
+	}
+
+	return letter
+}
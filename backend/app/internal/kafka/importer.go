@@ -0,0 +1,217 @@
+package kafka
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"order-service/internal/database"
+	"order-service/internal/models"
+	"order-service/internal/validation"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// MaxImportLineSize - максимальный размер одной строки NDJSON при импорте
+	MaxImportLineSize = 4 << 20
+
+	// DefaultImportBatchSize - число заказов в одной транзакции импорта по умолчанию
+	DefaultImportBatchSize = 500
+)
+
+// Статусы строк импорта, которые не сохранились. Для сохраненных строк статус
+// совпадает с результатом database.UpsertResult
+const (
+	ImportStatusInvalid = "invalid" // строка не прошла разбор или валидацию
+	ImportStatusFailed  = "failed"  // заказ не удалось сохранить (в том числе конфликт содержимого)
+)
+
+// RuleDecode - имя "правила" для ошибок разбора сообщения, которые не относятся к бизнес-правилам
+const RuleDecode = "decode"
+
+// ImportLineResult - результат импорта одной строки NDJSON
+type ImportLineResult struct {
+	Line       int                        `json:"line"` // Номер строки, начиная с 1
+	OrderUID   string                     `json:"order_uid,omitempty"`
+	Status     string                     `json:"status"`
+	Error      string                     `json:"error,omitempty"`
+	Violations []validation.Violation     `json:"violations,omitempty"`
+	Warnings   []models.ValidationWarning `json:"warnings,omitempty"`
+}
+
+// Failed сообщает, что строка не была сохранена
+func (r ImportLineResult) Failed() bool {
+	return r.Status == ImportStatusInvalid || r.Status == ImportStatusFailed
+}
+
+// ImportSummary - итог импорта: число обработанных строк и число строк по статусам
+type ImportSummary struct {
+	Lines  int            `json:"lines"`
+	Counts map[string]int `json:"counts"`
+}
+
+// Importer загружает заказы из NDJSON тем же путем разбора, валидации и сохранения,
+// что и consumer: валидные заказы сохраняются пачками через StoreBatch, уже существующие -
+// через Store с политикой конфликтов
+type Importer struct {
+	processor *OrderProcessor
+	batchSize int
+	logger    *logrus.Logger
+}
+
+// importEntry - валидный заказ, ожидающий сохранения в пачке
+type importEntry struct {
+	result *ImportLineResult
+	order  *models.OrderFull
+}
+
+// NewImporter создает новый Importer. batchSize < 1 заменяется на DefaultImportBatchSize
+func NewImporter(processor *OrderProcessor, batchSize int, logger *logrus.Logger) *Importer {
+	if batchSize < 1 {
+		batchSize = DefaultImportBatchSize
+	}
+	return &Importer{
+		processor: processor,
+		batchSize: batchSize,
+		logger:    logger,
+	}
+}
+
+// Import читает заказы из r построчно и сохраняет их пачками, не загружая весь файл в память.
+// Пустые строки пропускаются. emit вызывается для каждой строки в порядке строк после
+// сохранения ее пачки; ошибка emit прерывает импорт. headers - заголовки сообщения
+// (например, schema-version), content-type всегда JSON
+func (i *Importer) Import(ctx context.Context, r io.Reader, headers map[string]string, emit func(ImportLineResult) error) (*ImportSummary, error) {
+	messageHeaders := map[string]string{HeaderContentType: ContentTypeJSON}
+	for key, value := range headers {
+		if key != HeaderContentType {
+			messageHeaders[key] = value
+		}
+	}
+
+	summary := &ImportSummary{Counts: make(map[string]int)}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), MaxImportLineSize)
+
+	// pending - результаты строк в порядке чтения, batch - валидные заказы из них
+	var pending []*ImportLineResult
+	var batch []importEntry
+
+	flush := func() error {
+		i.storeBatch(batch)
+		for _, result := range pending {
+			summary.Counts[result.Status]++
+			if err := emit(*result); err != nil {
+				return err
+			}
+		}
+		pending, batch = pending[:0], batch[:0]
+		return nil
+	}
+
+	line := 0
+	for scanner.Scan() {
+		line++
+		data := scanner.Bytes()
+		if len(bytes.TrimSpace(data)) == 0 {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return summary, err
+		}
+		summary.Lines++
+
+		result := &ImportLineResult{Line: line}
+		pending = append(pending, result)
+
+		orderFull, err := i.processor.ParseAndValidate(data, messageHeaders)
+		if err != nil {
+			result.Status = ImportStatusInvalid
+			result.Error = err.Error()
+			result.Violations = Violations(err)
+		} else {
+			result.OrderUID = orderFull.OrderUID
+			result.Warnings = orderFull.ValidationWarnings
+			batch = append(batch, importEntry{result: result, order: orderFull})
+		}
+
+		// Результаты отдаются, как только пачка заполнена. Невалидные строки без ожидающих
+		// сохранения заказов отдаются сразу, а накопленные результаты не превышают размер пачки,
+		// даже если в файле почти нет валидных заказов
+		if len(batch) == 0 || len(batch) >= i.batchSize || len(pending) >= i.batchSize {
+			if err := flush(); err != nil {
+				return summary, err
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		if errors.Is(err, bufio.ErrTooLong) {
+			err = fmt.Errorf("line %d exceeds %d bytes", line+1, MaxImportLineSize)
+		}
+		return summary, fmt.Errorf("failed to read import data: %w", err)
+	}
+	if err := flush(); err != nil {
+		return summary, err
+	}
+
+	i.logger.WithFields(logrus.Fields{
+		"lines":  summary.Lines,
+		"counts": summary.Counts,
+	}).Info("Order import finished")
+	return summary, nil
+}
+
+// storeBatch сохраняет пачку одной транзакцией. Заказы, которые уже были в базе,
+// и заказы пачки, которая не сохранилась целиком, сохраняются по одному
+func (i *Importer) storeBatch(batch []importEntry) {
+	if len(batch) == 0 {
+		return
+	}
+
+	orders := make([]*models.OrderFull, len(batch))
+	for n, entry := range batch {
+		orders[n] = entry.order
+	}
+
+	created, err := i.processor.StoreBatch(orders)
+	if err != nil {
+		i.logger.WithError(err).WithField("batch_size", len(batch)).Warn("Failed to store import batch, storing orders one by one")
+	}
+
+	createdSet := make(map[string]bool, len(created))
+	for _, orderUID := range created {
+		createdSet[orderUID] = true
+	}
+	for _, entry := range batch {
+		if createdSet[entry.order.OrderUID] {
+			delete(createdSet, entry.order.OrderUID)
+			entry.result.Status = string(database.UpsertCreated)
+			continue
+		}
+
+		result, err := i.processor.Store(entry.order)
+		if err != nil {
+			entry.result.Status = ImportStatusFailed
+			entry.result.Error = err.Error()
+			continue
+		}
+		entry.result.Status = string(result)
+	}
+}
+
+// Violations возвращает нарушения бизнес-правил из ошибки валидации.
+// Ошибки разбора сообщения возвращаются одним нарушением правила decode
+func Violations(err error) []validation.Violation {
+	var ruleErrors validation.Errors
+	if errors.As(err, &ruleErrors) {
+		return ruleErrors
+	}
+	var validationErr *ValidationError
+	if errors.As(err, &validationErr) {
+		err = validationErr.Err
+	}
+	return []validation.Violation{{Rule: RuleDecode, Message: err.Error()}}
+}
@@ -0,0 +1,239 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"order-service/internal/cache"
+	"order-service/internal/database"
+	"order-service/internal/models"
+	"order-service/internal/validation"
+
+	"github.com/sirupsen/logrus"
+)
+
+// testOrderMessage возвращает валидный заказ, в котором заполнены все поля
+func testOrderMessage(orderUID string) models.KafkaOrderMessage {
+	return models.KafkaOrderMessage{
+		OrderUID:    orderUID,
+		TrackNumber: "WBILMTESTTRACK",
+		Entry:       "WBIL",
+		Delivery: models.KafkaDelivery{
+			Name:    "Test Testov",
+			Phone:   "+9720000000",
+			Zip:     "2639809",
+			City:    "Kiryat Mozkin",
+			Address: "Ploshad Mira 15",
+			Region:  "Kraiot",
+			Email:   "test@gmail.com",
+		},
+		Payment: models.KafkaPayment{
+			Transaction:  orderUID,
+			RequestID:    "req-1",
+			Currency:     "USD",
+			Provider:     "wbpay",
+			Amount:       1817,
+			PaymentDt:    1637907727,
+			Bank:         "alpha",
+			DeliveryCost: 1500,
+			GoodsTotal:   317,
+			CustomFee:    0,
+		},
+		Items: []models.KafkaOrderItem{{
+			ChrtID:      9934930,
+			TrackNumber: "WBILMTESTTRACK",
+			Price:       453,
+			Rid:         "ab4219087a764ae0btest",
+			Name:        "Mascaras",
+			Sale:        30,
+			Size:        "0",
+			TotalPrice:  317,
+			NmID:        2389212,
+			Brand:       "Vivienne Sabo",
+			Status:      202,
+		}},
+		Locale:            "en",
+		InternalSignature: "sig",
+		CustomerID:        "test",
+		DeliveryService:   "meest",
+		Shardkey:          "9",
+		SmID:              99,
+		DateCreated:       "2021-11-26T06:22:19Z",
+		OofShard:          "1",
+	}
+}
+
+func testLogger() *logrus.Logger {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	return logger
+}
+
+// fakeRepository сохраняет заказы в памяти. Остальные методы OrderRepository не используются
+type fakeRepository struct {
+	database.OrderRepository
+	stored map[string]bool
+}
+
+func (r *fakeRepository) CreateOrders(batch []*models.OrderFull) ([]string, error) {
+	var created []string
+	for _, orderFull := range batch {
+		if !r.stored[orderFull.OrderUID] {
+			r.stored[orderFull.OrderUID] = true
+			created = append(created, orderFull.OrderUID)
+		}
+	}
+	return created, nil
+}
+
+func (r *fakeRepository) UpsertOrder(orderFull *models.OrderFull, policy database.ConflictPolicy) (database.UpsertResult, error) {
+	if r.stored[orderFull.OrderUID] {
+		return database.UpsertUnchanged, nil
+	}
+	r.stored[orderFull.OrderUID] = true
+	return database.UpsertCreated, nil
+}
+
+func newTestImporter(t *testing.T, batchSize int) *Importer {
+	t.Helper()
+	schemas, err := NewSchemaRegistry(ContentTypeJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	validator, err := validation.NewValidator("reject", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger := testLogger()
+	repo := &fakeRepository{stored: make(map[string]bool)}
+	processor := NewOrderProcessor(repo, cache.NewMemoryCache(100, logger), schemas, validator, database.ConflictIgnore, logger)
+	return NewImporter(processor, batchSize, logger)
+}
+
+func orderLine(t *testing.T, orderUID string) string {
+	t.Helper()
+	data, err := json.Marshal(testOrderMessage(orderUID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data) + "\n"
+}
+
+// lineReader отдает строки по одной и записывает, сколько строк прочитано
+type lineReader struct {
+	lines []string
+	read  int
+}
+
+func (r *lineReader) Read(p []byte) (int, error) {
+	if r.read == len(r.lines) {
+		return 0, io.EOF
+	}
+	n := copy(p, r.lines[r.read])
+	r.read++
+	return n, nil
+}
+
+func TestImportEmitsResultsWhileReading(t *testing.T) {
+	tests := []struct {
+		name      string
+		lines     []string
+		batchSize int
+		// Сколько строк прочитано к моменту выдачи результата каждой строки
+		wantRead []int
+	}{
+		{
+			// Невалидные строки без ожидающих заказов отдаются сразу
+			name:      "only invalid lines",
+			lines:     []string{"{\n", "not json\n", "[]\n"},
+			batchSize: 100,
+			wantRead:  []int{1, 2, 3},
+		},
+		{
+			// Невалидные строки после валидного заказа ждут его пачку, но не дольше размера пачки
+			name:      "invalid lines after valid order",
+			lines:     []string{orderLine(t, "a"), "{\n", "{\n", "{\n", "{\n"},
+			batchSize: 3,
+			wantRead:  []int{3, 3, 3, 4, 5},
+		},
+		{
+			name:      "full batch",
+			lines:     []string{orderLine(t, "a"), orderLine(t, "b"), "{\n", orderLine(t, "c")},
+			batchSize: 2,
+			wantRead:  []int{2, 2, 3, 4},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader := &lineReader{lines: tt.lines}
+			var gotRead []int
+			var results []ImportLineResult
+			_, err := newTestImporter(t, tt.batchSize).Import(context.Background(), reader, nil, func(result ImportLineResult) error {
+				gotRead = append(gotRead, reader.read)
+				results = append(results, result)
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("Import() error = %v", err)
+			}
+
+			if len(results) != len(tt.lines) {
+				t.Fatalf("Import() emitted %d results, want %d", len(results), len(tt.lines))
+			}
+			for n, result := range results {
+				if result.Line != n+1 {
+					t.Errorf("result %d line = %d, want %d", n, result.Line, n+1)
+				}
+				if gotRead[n] != tt.wantRead[n] {
+					t.Errorf("line %d emitted after reading %d lines, want %d", result.Line, gotRead[n], tt.wantRead[n])
+				}
+			}
+		})
+	}
+}
+
+func TestImportSummary(t *testing.T) {
+	input := orderLine(t, "a") + "\n" + "{\n" + orderLine(t, "a") + orderLine(t, "b")
+	var results []ImportLineResult
+	summary, err := newTestImporter(t, 10).Import(context.Background(), strings.NewReader(input), nil, func(result ImportLineResult) error {
+		results = append(results, result)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+
+	wantStatus := []string{string(database.UpsertCreated), ImportStatusInvalid, string(database.UpsertUnchanged), string(database.UpsertCreated)}
+	if len(results) != len(wantStatus) {
+		t.Fatalf("Import() emitted %d results, want %d", len(results), len(wantStatus))
+	}
+	for n, result := range results {
+		if result.Status != wantStatus[n] {
+			t.Errorf("line %d status = %q (%s), want %q", result.Line, result.Status, result.Error, wantStatus[n])
+		}
+	}
+	// Пустая строка пропускается, но учитывается в номерах строк
+	if results[1].Line != 3 || len(results[1].Violations) != 1 || results[1].Violations[0].Rule != RuleDecode {
+		t.Errorf("invalid line result = %+v", results[1])
+	}
+	if summary.Lines != 4 || summary.Counts[string(database.UpsertCreated)] != 2 || summary.Counts[ImportStatusInvalid] != 1 {
+		t.Errorf("summary = %+v", summary)
+	}
+}
+
+func TestImportStopsOnEmitError(t *testing.T) {
+	emitErr := errors.New("client gone")
+	calls := 0
+	_, err := newTestImporter(t, 10).Import(context.Background(), strings.NewReader("{\n{\n"), nil, func(ImportLineResult) error {
+		calls++
+		return emitErr
+	})
+	if !errors.Is(err, emitErr) || calls != 1 {
+		t.Errorf("Import() error = %v after %d emits, want %v after 1", err, calls, emitErr)
+	}
+}
@@ -0,0 +1,134 @@
+package kafka
+
+import (
+	"sync"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// offsetTracker отслеживает обработку сообщений по партициям. Сообщения
+// обрабатываются параллельно, но offset партиции отдается на коммит, только
+// когда обработаны все предыдущие сообщения этой партиции
+type offsetTracker struct {
+	mu         sync.Mutex
+	partitions map[int]*partitionOffsets
+	commits    chan<- kafka.Message
+}
+
+type partitionOffsets struct {
+	inflight []kafka.Message // сообщения в порядке получения из Kafka
+	done     map[int64]bool  // обработанные, но еще не закоммиченные offset'ы
+}
+
+func newOffsetTracker(commits chan<- kafka.Message) *offsetTracker {
+	return &offsetTracker{
+		partitions: make(map[int]*partitionOffsets),
+		commits:    commits,
+	}
+}
+
+// track регистрирует полученное сообщение. Вызывается в порядке получения сообщений
+func (t *offsetTracker) track(msg kafka.Message) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	p, ok := t.partitions[msg.Partition]
+	if !ok {
+		p = &partitionOffsets{done: make(map[int64]bool)}
+		t.partitions[msg.Partition] = p
+	}
+
+	// После ребалансировки партиция читается заново с последнего закоммиченного offset'а,
+	// старые сообщения будут получены повторно, поэтому их состояние сбрасывается
+	if n := len(p.inflight); n > 0 && msg.Offset <= p.inflight[n-1].Offset {
+		p.inflight = nil
+		p.done = make(map[int64]bool)
+	}
+
+	// Для коммита достаточно топика, партиции и offset'а, тело сообщения не храним
+	p.inflight = append(p.inflight, kafka.Message{
+		Topic:     msg.Topic,
+		Partition: msg.Partition,
+		Offset:    msg.Offset,
+	})
+}
+
+// markDone отмечает сообщение обработанным и, если это возможно, отдает на коммит
+// наибольший offset, до которого все сообщения партиции обработаны
+func (t *offsetTracker) markDone(msg kafka.Message) {
+	// Отправка идет без блокировки, чтобы медленный коммит не останавливал остальных воркеров.
+	// Поэтому коммиты одной партиции могут прийти не по порядку, и committer отбрасывает
+	// offset'ы не больше уже известного
+	if last, ok := t.complete(msg); ok {
+		t.commits <- last
+	}
+}
+
+// complete отмечает сообщение обработанным и возвращает наибольший offset партиции,
+// до которого все сообщения обработаны, если он сдвинулся
+func (t *offsetTracker) complete(msg kafka.Message) (kafka.Message, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	p, ok := t.partitions[msg.Partition]
+	// Сообщение могло быть сброшено при ребалансировке
+	if !ok || len(p.inflight) == 0 || msg.Offset < p.inflight[0].Offset {
+		return kafka.Message{}, false
+	}
+	p.done[msg.Offset] = true
+
+	var last kafka.Message
+	advanced := false
+	for len(p.inflight) > 0 && p.done[p.inflight[0].Offset] {
+		last = p.inflight[0]
+		delete(p.done, last.Offset)
+		p.inflight = p.inflight[1:]
+		advanced = true
+	}
+	return last, advanced
+}
+
+// commitQueue объединяет offset'ы, ожидающие коммита: для каждой партиции коммитится
+// наибольший offset. Воркеры отправляют offset'ы без общей блокировки, поэтому они могут
+// прийти не по порядку: offset'ы не больше уже закоммиченного отбрасываются, чтобы коммит
+// не откатился назад. Используется одной горутиной committer'а
+type commitQueue struct {
+	last   map[int]int64         // закоммиченный offset партиции
+	latest map[int]kafka.Message // наибольший ожидающий коммита offset партиции
+}
+
+func newCommitQueue() *commitQueue {
+	return &commitQueue{
+		last:   make(map[int]int64),
+		latest: make(map[int]kafka.Message),
+	}
+}
+
+// add добавляет offset в очередь, если он больше закоммиченного и ожидающего коммита
+func (q *commitQueue) add(msg kafka.Message) {
+	if offset, ok := q.last[msg.Partition]; ok && msg.Offset <= offset {
+		return
+	}
+	if prev, ok := q.latest[msg.Partition]; !ok || msg.Offset > prev.Offset {
+		q.latest[msg.Partition] = msg
+	}
+}
+
+// take возвращает offset'ы для коммита по одному на партицию и очищает очередь
+func (q *commitQueue) take() []kafka.Message {
+	batch := make([]kafka.Message, 0, len(q.latest))
+	for _, msg := range q.latest {
+		batch = append(batch, msg)
+	}
+	q.latest = make(map[int]kafka.Message)
+	return batch
+}
+
+// committed запоминает успешно закоммиченные offset'ы
+func (q *commitQueue) committed(batch []kafka.Message) {
+	for _, msg := range batch {
+		if offset, ok := q.last[msg.Partition]; !ok || msg.Offset > offset {
+			q.last[msg.Partition] = msg.Offset
+		}
+	}
+}
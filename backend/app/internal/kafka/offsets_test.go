@@ -0,0 +1,223 @@
+package kafka
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+
+	"github.com/segmentio/kafka-go"
+)
+
+func message(partition int, offset int64) kafka.Message {
+	return kafka.Message{Topic: "orders", Partition: partition, Offset: offset}
+}
+
+func TestOffsetTracker(t *testing.T) {
+	// Шаг - получение сообщения (track) или окончание его обработки (done).
+	// want - offset, отданный на коммит после done, -1 - коммита нет
+	type step struct {
+		track     bool
+		partition int
+		offset    int64
+		want      int64
+	}
+	track := func(partition int, offset int64) step {
+		return step{track: true, partition: partition, offset: offset}
+	}
+	done := func(partition int, offset int64, want int64) step {
+		return step{partition: partition, offset: offset, want: want}
+	}
+
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{
+			name:  "in order",
+			steps: []step{track(0, 5), track(0, 6), track(0, 7), done(0, 5, 5), done(0, 6, 6), done(0, 7, 7)},
+		},
+		{
+			name:  "out of order",
+			steps: []step{track(0, 5), track(0, 6), track(0, 7), done(0, 7, -1), done(0, 6, -1), done(0, 5, 7)},
+		},
+		{
+			name:  "gap in the middle",
+			steps: []step{track(0, 5), track(0, 6), track(0, 7), done(0, 5, 5), done(0, 7, -1), done(0, 6, 7)},
+		},
+		{
+			name: "partitions are independent",
+			steps: []step{
+				track(0, 5), track(1, 5), track(0, 6),
+				done(0, 6, -1), done(1, 5, 5), done(0, 5, 6),
+			},
+		},
+		{
+			name:  "offsets with gaps (compacted topic)",
+			steps: []step{track(0, 5), track(0, 9), done(0, 9, -1), done(0, 5, 9)},
+		},
+		{
+			name:  "duplicate done",
+			steps: []step{track(0, 5), track(0, 6), done(0, 5, 5), done(0, 5, -1), done(0, 6, 6), done(0, 6, -1)},
+		},
+		{
+			name:  "done for untracked partition",
+			steps: []step{track(0, 5), done(1, 5, -1), done(0, 5, 5)},
+		},
+		{
+			// После ребалансировки партиция читается заново с закоммиченного offset'а:
+			// 6 и 7 получены повторно и должны быть обработаны до коммита
+			name: "rewind after rebalance",
+			steps: []step{
+				track(0, 5), track(0, 6), track(0, 7), done(0, 5, 5),
+				track(0, 6), track(0, 7), done(0, 7, -1), done(0, 6, 7),
+			},
+		},
+		{
+			// Сообщение 5, полученное до сброса, дообработано уже после повторного чтения с 6
+			name: "stale done below rewind start",
+			steps: []step{
+				track(0, 5), track(0, 6), track(0, 7),
+				track(0, 6), done(0, 5, -1), track(0, 7), done(0, 6, 6), done(0, 7, 7),
+			},
+		},
+		{
+			// Старая обработка 6 завершилась после сброса: сообщение сохранено, поэтому
+			// его offset можно закоммитить, не дожидаясь повторной обработки
+			name: "stale done after rewind start",
+			steps: []step{
+				track(0, 5), track(0, 6),
+				track(0, 5), done(0, 6, -1), track(0, 6), done(0, 5, 6), done(0, 6, -1),
+			},
+		},
+		{
+			name: "rewind discards done offsets",
+			steps: []step{
+				track(0, 5), track(0, 6), done(0, 6, -1),
+				track(0, 5), track(0, 6), done(0, 5, 5), done(0, 6, 6),
+			},
+		},
+		{
+			// Коммит не удался, сообщение получено повторно после того, как все обработанные
+			// offset'ы были отданы: повторный коммит отбрасывает commitQueue
+			name:  "rewind after drained partition",
+			steps: []step{track(0, 5), done(0, 5, 5), track(0, 5), done(0, 5, 5)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracker := newOffsetTracker(nil)
+			for i, s := range tt.steps {
+				if s.track {
+					tracker.track(message(s.partition, s.offset))
+					continue
+				}
+				last, ok := tracker.complete(message(s.partition, s.offset))
+				got := int64(-1)
+				if ok {
+					got = last.Offset
+					if last.Partition != s.partition || last.Topic != "orders" {
+						t.Errorf("step %d: commit %s/%d, want orders/%d", i, last.Topic, last.Partition, s.partition)
+					}
+				}
+				if got != s.want {
+					t.Errorf("step %d: done(%d:%d) committed %d, want %d", i, s.partition, s.offset, got, s.want)
+				}
+			}
+		})
+	}
+}
+
+func TestOffsetTrackerMarkDoneSendsCommit(t *testing.T) {
+	commits := make(chan kafka.Message, 10)
+	tracker := newOffsetTracker(commits)
+	tracker.track(message(0, 5))
+	tracker.track(message(0, 6))
+
+	tracker.markDone(message(0, 6))
+	if len(commits) != 0 {
+		t.Fatalf("markDone() sent %d commits before offset 5 was done", len(commits))
+	}
+	tracker.markDone(message(0, 5))
+	if got := <-commits; got.Offset != 6 {
+		t.Errorf("markDone() sent offset %d, want 6", got.Offset)
+	}
+}
+
+func TestCommitQueue(t *testing.T) {
+	// Шаг - offset'ы, полученные committer'ом до очередного коммита, и его результат
+	type step struct {
+		add    []kafka.Message
+		want   []string // partition:offset в батче коммита
+		failed bool     // коммит не удался
+	}
+
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{
+			name: "largest offset per partition",
+			steps: []step{
+				{add: []kafka.Message{message(0, 5), message(1, 3), message(0, 7), message(0, 6)}, want: []string{"0:7", "1:3"}},
+			},
+		},
+		{
+			name: "out of order sends",
+			steps: []step{
+				{add: []kafka.Message{message(0, 7)}, want: []string{"0:7"}},
+				{add: []kafka.Message{message(0, 5), message(0, 6)}},
+				{add: []kafka.Message{message(0, 6), message(0, 8)}, want: []string{"0:8"}},
+			},
+		},
+		{
+			name: "same offset is not committed twice",
+			steps: []step{
+				{add: []kafka.Message{message(0, 5)}, want: []string{"0:5"}},
+				{add: []kafka.Message{message(0, 5)}},
+			},
+		},
+		{
+			name: "partitions are independent",
+			steps: []step{
+				{add: []kafka.Message{message(0, 7)}, want: []string{"0:7"}},
+				{add: []kafka.Message{message(0, 6), message(1, 2)}, want: []string{"1:2"}},
+			},
+		},
+		{
+			// Неудавшийся коммит не считается закоммиченным: меньший offset коммитится позже
+			name: "failed commit",
+			steps: []step{
+				{add: []kafka.Message{message(0, 5)}, want: []string{"0:5"}},
+				{add: []kafka.Message{message(0, 9)}, want: []string{"0:9"}, failed: true},
+				{add: []kafka.Message{message(0, 7)}, want: []string{"0:7"}},
+				{add: []kafka.Message{message(0, 6)}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			queue := newCommitQueue()
+			for i, s := range tt.steps {
+				for _, msg := range s.add {
+					queue.add(msg)
+				}
+				batch := queue.take()
+
+				got := make([]string, len(batch))
+				for n, msg := range batch {
+					got[n] = fmt.Sprintf("%d:%d", msg.Partition, msg.Offset)
+				}
+				sort.Strings(got)
+				if fmt.Sprint(got) != fmt.Sprint(s.want) {
+					t.Errorf("step %d: batch = %v, want %v", i, got, s.want)
+				}
+
+				if !s.failed {
+					queue.committed(batch)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,308 @@
+package kafka
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"order-service/internal/cache"
+	"order-service/internal/database"
+	"order-service/internal/models"
+	"order-service/internal/validation"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// OrderProcessor содержит общий путь валидации и сохранения заказов.
+// Используется Kafka consumer'ом, а также повторной обработкой сообщений из DLQ
+type OrderProcessor struct {
+	db             database.OrderRepository
+	cache          cache.OrderCache
+	conflictPolicy database.ConflictPolicy
+	schemas        *SchemaRegistry
+	validator      *validation.Validator
+	logger         *logrus.Logger
+}
+
+// ValidationError означает, что сообщение не удалось распарсить или оно не прошло валидацию
+type ValidationError struct {
+	Err error
+}
+
+func (e *ValidationError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// IsValidationError проверяет, является ли ошибка ошибкой валидации сообщения
+func IsValidationError(err error) bool {
+	var validationErr *ValidationError
+	return errors.As(err, &validationErr)
+}
+
+// NewOrderProcessor создает новый обработчик заказов
+func NewOrderProcessor(db database.OrderRepository, cache cache.OrderCache, schemas *SchemaRegistry, validator *validation.Validator, conflictPolicy database.ConflictPolicy, logger *logrus.Logger) *OrderProcessor {
+	return &OrderProcessor{
+		db:             db,
+		cache:          cache,
+		conflictPolicy: conflictPolicy,
+		schemas:        schemas,
+		validator:      validator,
+		logger:         logger,
+	}
+}
+
+// Process парсит, валидирует и идемпотентно сохраняет заказ
+func (p *OrderProcessor) Process(data []byte, headers map[string]string) (*models.OrderFull, database.UpsertResult, error) {
+	orderFull, err := p.ParseAndValidate(data, headers)
+	if err != nil {
+		return nil, "", err
+	}
+
+	result, err := p.Store(orderFull)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return orderFull, result, nil
+}
+
+// Store идемпотентно сохраняет заказ в базу данных и обновляет кеш.
+// Если заказ с таким UID уже существует, применяется политика конфликтов;
+// при политике conflict возвращается ошибка database.ErrOrderConflict
+func (p *OrderProcessor) Store(orderFull *models.OrderFull) (database.UpsertResult, error) {
+	result, err := p.db.UpsertOrder(orderFull, p.conflictPolicy)
+	if err != nil {
+		return "", fmt.Errorf("failed to save order to database: %w", err)
+	}
+
+	switch result {
+	case database.UpsertCreated, database.UpsertOverwritten:
+		p.cache.Set(orderFull.OrderUID, orderFull)
+		p.logger.WithField("order_uid", orderFull.OrderUID).WithField("result", result).Info("Order processed successfully")
+	default:
+		p.logger.WithField("order_uid", orderFull.OrderUID).WithField("result", result).Info("Order already exists, skipping")
+	}
+
+	return result, nil
+}
+
+// StoreBatch сохраняет пачку заказов одной транзакцией и добавляет новые заказы в кеш.
+// Уже существующие заказы пропускаются, к ним нужно применить Store, чтобы
+// сработала политика конфликтов. Возвращает UID сохраненных заказов
+func (p *OrderProcessor) StoreBatch(orders []*models.OrderFull) ([]string, error) {
+	created, err := p.db.CreateOrders(orders)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save order batch to database: %w", err)
+	}
+
+	createdSet := make(map[string]bool, len(created))
+	for _, orderUID := range created {
+		createdSet[orderUID] = true
+	}
+	for _, orderFull := range orders {
+		if createdSet[orderFull.OrderUID] {
+			p.cache.Set(orderFull.OrderUID, orderFull)
+			delete(createdSet, orderFull.OrderUID)
+		}
+	}
+
+	return created, nil
+}
+
+// EventType определяет тип события по полю event_type.
+// Сообщения без event_type, а также сообщения в бинарных форматах
+// (Protobuf, Avro) считаются созданием заказа
+func (p *OrderProcessor) EventType(data []byte, headers map[string]string) (string, error) {
+	contentType, err := p.schemas.ContentType(headers)
+	if err != nil {
+		return "", &ValidationError{Err: err}
+	}
+	if contentType != ContentTypeJSON {
+		return models.EventOrderCreated, nil
+	}
+
+	var event models.KafkaEvent
+	if err := json.Unmarshal(data, &event); err != nil {
+		return "", &ValidationError{Err: fmt.Errorf("failed to unmarshal JSON: %w", err)}
+	}
+
+	switch event.EventType {
+	case "", models.EventOrderCreated:
+		return models.EventOrderCreated, nil
+	case models.EventOrderStatusChanged:
+		return event.EventType, nil
+	default:
+		return "", &ValidationError{Err: fmt.Errorf("unknown event_type %q", event.EventType)}
+	}
+}
+
+// ParseStatusChange парсит и валидирует событие изменения статуса.
+// Ошибки возвращаются как *ValidationError
+func (p *OrderProcessor) ParseStatusChange(data []byte) (*models.OrderStatusChange, error) {
+	var change models.OrderStatusChange
+	if err := json.Unmarshal(data, &change); err != nil {
+		return nil, &ValidationError{Err: fmt.Errorf("failed to unmarshal JSON: %w", err)}
+	}
+
+	if strings.TrimSpace(change.OrderUID) == "" {
+		return nil, &ValidationError{Err: fmt.Errorf("status change validation failed: order_uid is required")}
+	}
+	if change.Status == nil && len(change.Items) == 0 {
+		return nil, &ValidationError{Err: fmt.Errorf("status change validation failed: status or items are required")}
+	}
+	for _, item := range change.Items {
+		if item.ChrtID == 0 {
+			return nil, &ValidationError{Err: fmt.Errorf("status change validation failed: items chrt_id is required")}
+		}
+	}
+
+	return &change, nil
+}
+
+// ApplyStatusChange применяет изменение статуса к сохраненному заказу и обновляет запись в кеше
+func (p *OrderProcessor) ApplyStatusChange(change *models.OrderStatusChange) error {
+	if err := p.db.UpdateOrderStatus(change); err != nil {
+		return fmt.Errorf("failed to update order status: %w", err)
+	}
+
+	p.refreshCache(change.OrderUID)
+
+	p.logger.WithField("order_uid", change.OrderUID).Info("Order status change applied successfully")
+	return nil
+}
+
+// refreshCache перечитывает заказ из базы данных. Если это не удалось, запись удаляется,
+// чтобы кеш не отдавал устаревшие данные: заказ загрузится из БД при следующем запросе
+func (p *OrderProcessor) refreshCache(orderUID string) {
+	orderFull, err := p.db.GetOrderByUID(orderUID)
+	if err != nil || orderFull == nil {
+		p.cache.Delete(orderUID)
+		if err != nil {
+			p.logger.WithError(err).WithField("order_uid", orderUID).Warn("Failed to reload order, cache entry invalidated")
+		}
+		return
+	}
+	p.cache.Set(orderUID, orderFull)
+}
+
+// ParseAndValidate декодирует сообщение по его версии схемы и конвертирует его в модель.
+// headers - заголовки сообщения Kafka (могут содержать schema-version).
+// Ошибки парсинга и валидации, в том числе неизвестная версия схемы, возвращаются как *ValidationError
+func (p *OrderProcessor) ParseAndValidate(data []byte, headers map[string]string) (*models.OrderFull, error) {
+	kafkaMsg, err := p.schemas.Decode(data, headers)
+	if err != nil {
+		return nil, &ValidationError{Err: fmt.Errorf("failed to decode message: %w", err)}
+	}
+
+	// Конвертируем в наши модели
+	orderFull, err := p.convertKafkaToModel(kafkaMsg)
+	if err != nil {
+		return nil, &ValidationError{Err: fmt.Errorf("failed to convert message: %w", err)}
+	}
+
+	// Проверяем бизнес-правила, в ошибке возвращаются все нарушения (validation.Errors).
+	// Нарушения правил с политикой warn или correct не отклоняют заказ и сохраняются вместе с ним
+	warnings, err := p.validator.Validate(orderFull)
+	if err != nil {
+		return nil, &ValidationError{Err: fmt.Errorf("message validation failed: %w", err)}
+	}
+	if len(warnings) > 0 {
+		orderFull.ValidationWarnings = warnings
+		for _, warning := range warnings {
+			p.logger.WithFields(logrus.Fields{
+				"order_uid": orderFull.OrderUID,
+				"rule":      warning.Rule,
+				"field":     warning.Field,
+				"action":    warning.Action,
+				"message":   warning.Message,
+			}).Warn("Order accepted with validation warning")
+		}
+	}
+
+	return orderFull, nil
+}
+
+// convertKafkaToModel конвертирует Kafka сообщение в наши модели
+func (p *OrderProcessor) convertKafkaToModel(msg *models.KafkaOrderMessage) (*models.OrderFull, error) {
+	// Парсим дату. Нераспознанная дата остается нулевой, что она делает с заказом,
+	// решает политика правила date_created
+	dateCreated, err := time.Parse(time.RFC3339, msg.DateCreated)
+	if err != nil {
+		p.logger.WithError(err).WithField("date", msg.DateCreated).Warn("Failed to parse date")
+		dateCreated = time.Time{}
+	}
+
+	// Основной заказ
+	order := models.Order{
+		OrderUID:          msg.OrderUID,
+		TrackNumber:       msg.TrackNumber,
+		Entry:             msg.Entry,
+		Locale:            msg.Locale,
+		InternalSignature: msg.InternalSignature,
+		CustomerID:        msg.CustomerID,
+		DeliveryService:   msg.DeliveryService,
+		Shardkey:          msg.Shardkey,
+		SmID:              msg.SmID,
+		DateCreated:       dateCreated,
+		OofShard:          msg.OofShard,
+	}
+
+	// Доставка
+	delivery := &models.Delivery{
+		OrderUID: msg.OrderUID,
+		Name:     msg.Delivery.Name,
+		Phone:    msg.Delivery.Phone,
+		Zip:      msg.Delivery.Zip,
+		City:     msg.Delivery.City,
+		Address:  msg.Delivery.Address,
+		Region:   msg.Delivery.Region,
+		Email:    msg.Delivery.Email,
+	}
+
+	// Платеж
+	payment := &models.Payment{
+		OrderUID:     msg.OrderUID,
+		Transaction:  msg.Payment.Transaction,
+		RequestID:    msg.Payment.RequestID,
+		Currency:     msg.Payment.Currency,
+		Provider:     msg.Payment.Provider,
+		Amount:       models.NewMoney(msg.Payment.Amount, msg.Payment.Currency),
+		PaymentDt:    msg.Payment.PaymentDt,
+		Bank:         msg.Payment.Bank,
+		DeliveryCost: models.NewMoney(msg.Payment.DeliveryCost, msg.Payment.Currency),
+		GoodsTotal:   models.NewMoney(msg.Payment.GoodsTotal, msg.Payment.Currency),
+		CustomFee:    models.NewMoney(msg.Payment.CustomFee, msg.Payment.Currency),
+	}
+
+	// Товары
+	var items []models.OrderItem
+	for _, kafkaItem := range msg.Items {
+		item := models.OrderItem{
+			OrderUID:    msg.OrderUID,
+			ChrtID:      kafkaItem.ChrtID,
+			TrackNumber: kafkaItem.TrackNumber,
+			Price:       models.NewMoney(kafkaItem.Price, msg.Payment.Currency),
+			Rid:         kafkaItem.Rid,
+			Name:        kafkaItem.Name,
+			Sale:        kafkaItem.Sale,
+			Size:        kafkaItem.Size,
+			TotalPrice:  models.NewMoney(kafkaItem.TotalPrice, msg.Payment.Currency),
+			NmID:        kafkaItem.NmID,
+			Brand:       kafkaItem.Brand,
+			Status:      kafkaItem.Status,
+		}
+		items = append(items, item)
+	}
+
+	return &models.OrderFull{
+		Order:    order,
+		Delivery: delivery,
+		Payment:  payment,
+		Items:    items,
+	}, nil
+}
@@ -0,0 +1,222 @@
+package kafka
+
+import (
+	"fmt"
+	"order-service/internal/models"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// decodeOrderProtobuf декодирует сообщение orders.v1.Order (schemas/order_v1.proto).
+// Поля читаются по номерам без сгенерированного кода. Неизвестные поля, как требует
+// семантика protobuf, пропускаются: их значение уже прочитано protoFields
+func decodeOrderProtobuf(data []byte) (*models.KafkaOrderMessage, error) {
+	var msg models.KafkaOrderMessage
+	err := protoFields(data, func(f protoField) error {
+		switch f.num {
+		case 1:
+			return f.string(&msg.OrderUID)
+		case 2:
+			return f.string(&msg.TrackNumber)
+		case 3:
+			return f.string(&msg.Entry)
+		case 4:
+			return f.message(func(b []byte) error { return decodeDeliveryProtobuf(b, &msg.Delivery) })
+		case 5:
+			return f.message(func(b []byte) error { return decodePaymentProtobuf(b, &msg.Payment) })
+		case 6:
+			return f.message(func(b []byte) error {
+				var item models.KafkaOrderItem
+				if err := decodeItemProtobuf(b, &item); err != nil {
+					return err
+				}
+				msg.Items = append(msg.Items, item)
+				return nil
+			})
+		case 7:
+			return f.string(&msg.Locale)
+		case 8:
+			return f.string(&msg.InternalSignature)
+		case 9:
+			return f.string(&msg.CustomerID)
+		case 10:
+			return f.string(&msg.DeliveryService)
+		case 11:
+			return f.string(&msg.Shardkey)
+		case 12:
+			return f.int(&msg.SmID)
+		case 13:
+			return f.string(&msg.DateCreated)
+		case 14:
+			return f.string(&msg.OofShard)
+		case 15:
+			return f.int(&msg.SchemaVersion)
+		}
+		// Неизвестное поле пропускается
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal protobuf: %w", err)
+	}
+	return &msg, nil
+}
+
+func decodeDeliveryProtobuf(data []byte, d *models.KafkaDelivery) error {
+	return protoFields(data, func(f protoField) error {
+		switch f.num {
+		case 1:
+			return f.string(&d.Name)
+		case 2:
+			return f.string(&d.Phone)
+		case 3:
+			return f.string(&d.Zip)
+		case 4:
+			return f.string(&d.City)
+		case 5:
+			return f.string(&d.Address)
+		case 6:
+			return f.string(&d.Region)
+		case 7:
+			return f.string(&d.Email)
+		}
+		return nil
+	})
+}
+
+func decodePaymentProtobuf(data []byte, p *models.KafkaPayment) error {
+	return protoFields(data, func(f protoField) error {
+		switch f.num {
+		case 1:
+			return f.string(&p.Transaction)
+		case 2:
+			return f.string(&p.RequestID)
+		case 3:
+			return f.string(&p.Currency)
+		case 4:
+			return f.string(&p.Provider)
+		case 5:
+			return f.int64(&p.Amount)
+		case 6:
+			return f.int64(&p.PaymentDt)
+		case 7:
+			return f.string(&p.Bank)
+		case 8:
+			return f.int64(&p.DeliveryCost)
+		case 9:
+			return f.int64(&p.GoodsTotal)
+		case 10:
+			return f.int64(&p.CustomFee)
+		}
+		return nil
+	})
+}
+
+func decodeItemProtobuf(data []byte, item *models.KafkaOrderItem) error {
+	return protoFields(data, func(f protoField) error {
+		switch f.num {
+		case 1:
+			return f.int64(&item.ChrtID)
+		case 2:
+			return f.string(&item.TrackNumber)
+		case 3:
+			return f.int64(&item.Price)
+		case 4:
+			return f.string(&item.Rid)
+		case 5:
+			return f.string(&item.Name)
+		case 6:
+			return f.int(&item.Sale)
+		case 7:
+			return f.string(&item.Size)
+		case 8:
+			return f.int64(&item.TotalPrice)
+		case 9:
+			return f.int64(&item.NmID)
+		case 10:
+			return f.string(&item.Brand)
+		case 11:
+			return f.int(&item.Status)
+		}
+		return nil
+	})
+}
+
+// protoField - одно поле сообщения Protobuf: varint-значение или байты length-delimited поля
+type protoField struct {
+	num    protowire.Number
+	typ    protowire.Type
+	varint uint64
+	bytes  []byte
+}
+
+// protoFields перебирает поля сообщения Protobuf в порядке следования
+func protoFields(data []byte, fn func(protoField) error) error {
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		data = data[n:]
+
+		field := protoField{num: num, typ: typ}
+		switch typ {
+		case protowire.VarintType:
+			field.varint, n = protowire.ConsumeVarint(data)
+		case protowire.BytesType:
+			field.bytes, n = protowire.ConsumeBytes(data)
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, data)
+		}
+		if n < 0 {
+			return fmt.Errorf("field %d: %w", num, protowire.ParseError(n))
+		}
+		data = data[n:]
+
+		if err := fn(field); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f protoField) expect(typ protowire.Type) error {
+	if f.typ != typ {
+		return fmt.Errorf("field %d: unexpected wire type %d", f.num, f.typ)
+	}
+	return nil
+}
+
+func (f protoField) string(dst *string) error {
+	if err := f.expect(protowire.BytesType); err != nil {
+		return err
+	}
+	*dst = string(f.bytes)
+	return nil
+}
+
+// int читает int32/int64 поле. Отрицательные значения кодируются в varint с расширением знака
+func (f protoField) int(dst *int) error {
+	if err := f.expect(protowire.VarintType); err != nil {
+		return err
+	}
+	*dst = int(int64(f.varint))
+	return nil
+}
+
+func (f protoField) int64(dst *int64) error {
+	if err := f.expect(protowire.VarintType); err != nil {
+		return err
+	}
+	*dst = int64(f.varint)
+	return nil
+}
+
+func (f protoField) message(decode func([]byte) error) error {
+	if err := f.expect(protowire.BytesType); err != nil {
+		return err
+	}
+	if err := decode(f.bytes); err != nil {
+		return fmt.Errorf("field %d: %w", f.num, err)
+	}
+	return nil
+}
@@ -0,0 +1,256 @@
+package kafka
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"reflect"
+	"regexp"
+	"strconv"
+	"testing"
+
+	"order-service/internal/models"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// loadTestOrder читает заказ testdata/order_v1.json, в котором заполнены все поля схемы.
+// testdata/order_v1.pb и order_v1.avro - тот же заказ, закодированный kafka-producer'ом
+// (backend/database, go test -update)
+func loadTestOrder(t *testing.T) *models.KafkaOrderMessage {
+	t.Helper()
+	data, err := os.ReadFile("testdata/order_v1.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var msg models.KafkaOrderMessage
+	if err := json.Unmarshal(data, &msg); err != nil {
+		t.Fatal(err)
+	}
+	return &msg
+}
+
+func readTestdata(t *testing.T, name string) []byte {
+	t.Helper()
+	data, err := os.ReadFile("testdata/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func assertOrderMessage(t *testing.T, got, want *models.KafkaOrderMessage) {
+	t.Helper()
+	if !reflect.DeepEqual(got, want) {
+		gotJSON, _ := json.Marshal(got)
+		wantJSON, _ := json.Marshal(want)
+		t.Errorf("decoded order =\n%s\nwant\n%s", gotJSON, wantJSON)
+	}
+}
+
+// protoSchemaField - поле сообщения из schemas/order_v1.proto
+type protoSchemaField struct {
+	typ      string
+	name     string
+	num      protowire.Number
+	repeated bool
+}
+
+var (
+	protoMessageRe = regexp.MustCompile(`message (\w+) \{([^}]*)\}`)
+	protoFieldRe   = regexp.MustCompile(`(repeated )?(\w+) (\w+) = (\d+);`)
+)
+
+// parseProtoSchema разбирает сообщения .proto-файла: имя сообщения -> поля
+func parseProtoSchema(t *testing.T, path string) map[string][]protoSchemaField {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	messages := make(map[string][]protoSchemaField)
+	for _, message := range protoMessageRe.FindAllStringSubmatch(string(data), -1) {
+		for _, field := range protoFieldRe.FindAllStringSubmatch(message[2], -1) {
+			num, _ := strconv.Atoi(field[4])
+			messages[message[1]] = append(messages[message[1]], protoSchemaField{
+				typ: field[2], name: field[3], num: protowire.Number(num), repeated: field[1] != "",
+			})
+		}
+	}
+	if len(messages["Order"]) == 0 {
+		t.Fatalf("no Order message in %s", path)
+	}
+	return messages
+}
+
+// encodeBySchema кодирует JSON-представление заказа по .proto-схеме независимо от
+// декодера и kafka-producer'а. Каждое поле JSON должно быть в схеме, а каждое поле схемы -
+// непустым хотя бы в одном сообщении, иначе тест не проверяет его номер и тип
+func encodeBySchema(t *testing.T, schema map[string][]protoSchemaField, message string, value map[string]interface{}, seen map[string]bool) []byte {
+	t.Helper()
+	fields := make(map[string]protoSchemaField)
+	for _, field := range schema[message] {
+		fields[field.name] = field
+	}
+	for name := range value {
+		if _, ok := fields[name]; !ok {
+			t.Errorf("JSON field %s.%s is missing from order_v1.proto", message, name)
+		}
+	}
+
+	var b []byte
+	for _, field := range schema[message] {
+		fieldValue, ok := value[field.name]
+		if !ok {
+			continue
+		}
+		values := []interface{}{fieldValue}
+		if field.repeated {
+			values = fieldValue.([]interface{})
+		}
+		for _, v := range values {
+			switch field.typ {
+			case "string":
+				if v.(string) == "" {
+					continue
+				}
+				b = protowire.AppendTag(b, field.num, protowire.BytesType)
+				b = protowire.AppendString(b, v.(string))
+			case "int32", "int64":
+				n, err := v.(json.Number).Int64()
+				if err != nil {
+					t.Fatalf("%s.%s: %v", message, field.name, err)
+				}
+				if n == 0 {
+					continue
+				}
+				b = protowire.AppendTag(b, field.num, protowire.VarintType)
+				b = protowire.AppendVarint(b, uint64(n))
+			default:
+				if _, ok := schema[field.typ]; !ok {
+					t.Fatalf("%s.%s: unsupported type %s", message, field.name, field.typ)
+				}
+				b = protowire.AppendTag(b, field.num, protowire.BytesType)
+				b = protowire.AppendBytes(b, encodeBySchema(t, schema, field.typ, v.(map[string]interface{}), seen))
+			}
+			seen[message+"."+field.name] = true
+		}
+	}
+	return b
+}
+
+func TestDecodeOrderProtobufProducerOutput(t *testing.T) {
+	msg, err := decodeOrderProtobuf(readTestdata(t, "order_v1.pb"))
+	if err != nil {
+		t.Fatalf("decodeOrderProtobuf() error = %v", err)
+	}
+	assertOrderMessage(t, msg, loadTestOrder(t))
+}
+
+func TestDecodeOrderProtobufMatchesSchema(t *testing.T) {
+	schema := parseProtoSchema(t, "schemas/order_v1.proto")
+
+	decoder := json.NewDecoder(bytes.NewReader(readTestdata(t, "order_v1.json")))
+	decoder.UseNumber()
+	var value map[string]interface{}
+	if err := decoder.Decode(&value); err != nil {
+		t.Fatal(err)
+	}
+
+	seen := make(map[string]bool)
+	data := encodeBySchema(t, schema, "Order", value, seen)
+	for message, fields := range schema {
+		for _, field := range fields {
+			if !seen[message+"."+field.name] {
+				t.Errorf("field %s.%s is empty in testdata/order_v1.json and not covered", message, field.name)
+			}
+		}
+	}
+
+	msg, err := decodeOrderProtobuf(data)
+	if err != nil {
+		t.Fatalf("decodeOrderProtobuf() error = %v", err)
+	}
+	assertOrderMessage(t, msg, loadTestOrder(t))
+}
+
+// appendUnknownFields добавляет поля с номерами, которых нет в схеме, всех типов кодирования
+func appendUnknownFields(b []byte) []byte {
+	b = protowire.AppendTag(b, 100, protowire.VarintType)
+	b = protowire.AppendVarint(b, 42)
+	b = protowire.AppendTag(b, 101, protowire.BytesType)
+	b = protowire.AppendString(b, "new field")
+	b = protowire.AppendTag(b, 102, protowire.Fixed32Type)
+	b = protowire.AppendFixed32(b, 7)
+	b = protowire.AppendTag(b, 103, protowire.Fixed64Type)
+	b = protowire.AppendFixed64(b, 7)
+	b = protowire.AppendTag(b, 104, protowire.StartGroupType)
+	b = protowire.AppendTag(b, 1, protowire.VarintType)
+	b = protowire.AppendVarint(b, 1)
+	b = protowire.AppendTag(b, 104, protowire.EndGroupType)
+	return b
+}
+
+func TestDecodeOrderProtobufSkipsUnknownFields(t *testing.T) {
+	var delivery []byte
+	delivery = appendUnknownFields(delivery)
+	delivery = protowire.AppendTag(delivery, 1, protowire.BytesType)
+	delivery = protowire.AppendString(delivery, "Test Testov")
+
+	var b []byte
+	b = appendUnknownFields(b)
+	b = protowire.AppendTag(b, 1, protowire.BytesType)
+	b = protowire.AppendString(b, "b563feb7b2b84b6test")
+	b = protowire.AppendTag(b, 4, protowire.BytesType)
+	b = protowire.AppendBytes(b, delivery)
+	b = appendUnknownFields(b)
+
+	msg, err := decodeOrderProtobuf(b)
+	if err != nil {
+		t.Fatalf("decodeOrderProtobuf() error = %v", err)
+	}
+	if msg.OrderUID != "b563feb7b2b84b6test" || msg.Delivery.Name != "Test Testov" {
+		t.Errorf("decodeOrderProtobuf() = %+v", msg)
+	}
+
+	// Пропуск неизвестных полей не отменяет проверку известных
+	full := append(readTestdata(t, "order_v1.pb"), appendUnknownFields(nil)...)
+	msg, err = decodeOrderProtobuf(full)
+	if err != nil {
+		t.Fatalf("decodeOrderProtobuf() error = %v", err)
+	}
+	assertOrderMessage(t, msg, loadTestOrder(t))
+}
+
+func TestDecodeOrderProtobufErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{
+			name: "wrong wire type",
+			data: protowire.AppendVarint(protowire.AppendTag(nil, 1, protowire.VarintType), 1),
+		},
+		{
+			name: "wrong wire type in nested message",
+			data: protowire.AppendBytes(protowire.AppendTag(nil, 5, protowire.BytesType),
+				protowire.AppendString(protowire.AppendTag(nil, 5, protowire.BytesType), "100")),
+		},
+		{
+			name: "truncated",
+			data: readTestdata(t, "order_v1.pb")[:10],
+		},
+		{
+			name: "truncated unknown field",
+			data: protowire.AppendTag(nil, 100, protowire.Fixed64Type),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := decodeOrderProtobuf(tt.data); err == nil {
+				t.Error("decodeOrderProtobuf() succeeded, want error")
+			}
+		})
+	}
+}
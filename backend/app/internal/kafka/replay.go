@@ -0,0 +1,225 @@
+package kafka
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"order-service/internal/models"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Статусы результата повторной обработки. Для заказов статус совпадает
+// с результатом database.UpsertResult
+const (
+	ReplayStatusValid         = "valid"          // dry run: сообщение прошло валидацию, но не сохранялось
+	ReplayStatusStatusApplied = "status_applied" // событие изменения статуса применено к заказу
+)
+
+// ReplayRequest описывает параметры повторной обработки сообщения из DLQ
+type ReplayRequest struct {
+	Patch   json.RawMessage `json:"patch,omitempty"`   // JSON Merge Patch (RFC 7386), применяемый к исходному сообщению
+	Payload json.RawMessage `json:"payload,omitempty"` // Полная замена тела сообщения
+	DryRun  bool            `json:"dry_run,omitempty"` // Только парсинг и валидация, без сохранения
+}
+
+// ReplayResult описывает результат повторной обработки
+type ReplayResult struct {
+	Partition int               `json:"partition"`
+	Offset    int64             `json:"offset"`
+	Status    string            `json:"status"`
+	OrderUID  string            `json:"order_uid"`
+	Order     *models.OrderFull `json:"order,omitempty"`
+}
+
+// Replayer позволяет просматривать сообщения из DLQ и повторно отправлять их
+// через тот же путь валидации и сохранения, что использует consumer
+type Replayer struct {
+	reader    *DeadLetterReader
+	processor *OrderProcessor
+	logger    *logrus.Logger
+}
+
+// NewReplayer создает новый Replayer
+func NewReplayer(reader *DeadLetterReader, processor *OrderProcessor, logger *logrus.Logger) *Replayer {
+	return &Replayer{
+		reader:    reader,
+		processor: processor,
+		logger:    logger,
+	}
+}
+
+// List возвращает последние сообщения из DLQ
+func (r *Replayer) List(ctx context.Context, limit int) ([]DeadLetter, error) {
+	return r.reader.List(ctx, limit)
+}
+
+// Get возвращает сообщение из DLQ по партиции и offset
+func (r *Replayer) Get(ctx context.Context, partition int, offset int64) (*DeadLetter, error) {
+	return r.reader.Get(ctx, partition, offset)
+}
+
+// Replay повторно обрабатывает сообщение из DLQ, при необходимости применяя к нему исправления
+func (r *Replayer) Replay(ctx context.Context, partition int, offset int64, req ReplayRequest) (*ReplayResult, error) {
+	letter, err := r.reader.Get(ctx, partition, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	payload, err := buildReplayPayload(letter.Value, req)
+	if err != nil {
+		return nil, &ValidationError{Err: err}
+	}
+	headers := replayHeaders(letter.Headers, req)
+
+	result := &ReplayResult{
+		Partition: partition,
+		Offset:    offset,
+	}
+
+	logger := r.logger.WithFields(logrus.Fields{
+		"dlq_partition": partition,
+		"dlq_offset":    offset,
+		"dry_run":       req.DryRun,
+	})
+
+	eventType, err := r.processor.EventType(payload, headers)
+	if err != nil {
+		return nil, err
+	}
+	if eventType == models.EventOrderStatusChanged {
+		return r.replayStatusChange(payload, req, result, logger)
+	}
+
+	if req.DryRun {
+		orderFull, err := r.processor.ParseAndValidate(payload, headers)
+		if err != nil {
+			return nil, err
+		}
+		result.Status = ReplayStatusValid
+		result.OrderUID = orderFull.OrderUID
+		result.Order = orderFull
+		logger.WithField("order_uid", orderFull.OrderUID).Info("Dead letter validated")
+		return result, nil
+	}
+
+	orderFull, upsertResult, err := r.processor.Process(payload, headers)
+	if err != nil {
+		return nil, err
+	}
+
+	result.OrderUID = orderFull.OrderUID
+	result.Order = orderFull
+	result.Status = string(upsertResult)
+
+	logger.WithFields(logrus.Fields{
+		"order_uid": orderFull.OrderUID,
+		"status":    result.Status,
+	}).Info("Dead letter replayed")
+
+	return result, nil
+}
+
+// replayStatusChange повторно применяет событие изменения статуса
+func (r *Replayer) replayStatusChange(payload []byte, req ReplayRequest, result *ReplayResult, logger *logrus.Entry) (*ReplayResult, error) {
+	change, err := r.processor.ParseStatusChange(payload)
+	if err != nil {
+		return nil, err
+	}
+	result.OrderUID = change.OrderUID
+
+	if req.DryRun {
+		result.Status = ReplayStatusValid
+		logger.WithField("order_uid", change.OrderUID).Info("Dead letter validated")
+		return result, nil
+	}
+
+	if err := r.processor.ApplyStatusChange(change); err != nil {
+		return nil, err
+	}
+	result.Status = ReplayStatusStatusApplied
+
+	logger.WithFields(logrus.Fields{
+		"order_uid": change.OrderUID,
+		"status":    result.Status,
+	}).Info("Dead letter replayed")
+
+	return result, nil
+}
+
+// buildReplayPayload возвращает тело сообщения с учетом замены или патча
+func buildReplayPayload(original []byte, req ReplayRequest) ([]byte, error) {
+	if len(req.Payload) > 0 && len(req.Patch) > 0 {
+		return nil, fmt.Errorf("payload and patch cannot be used together")
+	}
+	if len(req.Payload) > 0 {
+		return req.Payload, nil
+	}
+	if len(req.Patch) > 0 {
+		return applyMergePatch(original, req.Patch)
+	}
+	return original, nil
+}
+
+// replayHeaders возвращает заголовки для повторной обработки. Замена и патч
+// всегда дают JSON, поэтому content-type исходного сообщения в этом случае не используется
+func replayHeaders(original map[string]string, req ReplayRequest) map[string]string {
+	headers := make(map[string]string, len(original)+1)
+	for key, value := range original {
+		headers[key] = value
+	}
+	if len(req.Payload) > 0 || len(req.Patch) > 0 {
+		headers[HeaderContentType] = ContentTypeJSON
+	}
+	return headers
+}
+
+// applyMergePatch применяет JSON Merge Patch (RFC 7386) к документу
+func applyMergePatch(original, patch []byte) ([]byte, error) {
+	target, err := decodeJSONValue(original)
+	if err != nil {
+		return nil, fmt.Errorf("original message is not valid JSON: %w", err)
+	}
+
+	patchValue, err := decodeJSONValue(patch)
+	if err != nil {
+		return nil, fmt.Errorf("patch is not valid JSON: %w", err)
+	}
+
+	return json.Marshal(mergePatch(target, patchValue))
+}
+
+func mergePatch(target, patch interface{}) interface{} {
+	patchObject, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+
+	targetObject, ok := target.(map[string]interface{})
+	if !ok {
+		targetObject = make(map[string]interface{})
+	}
+
+	for key, value := range patchObject {
+		if value == nil {
+			delete(targetObject, key)
+			continue
+		}
+		targetObject[key] = mergePatch(targetObject[key], value)
+	}
+
+	return targetObject
+}
+
+// decodeJSONValue декодирует JSON, сохраняя числа без потери точности
+func decodeJSONValue(data []byte) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
@@ -0,0 +1,48 @@
+package kafka
+
+import (
+	"context"
+	"math/rand"
+	"order-service/internal/database"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// retryPolicy описывает повторы с экспоненциальным backoff для временных ошибок БД
+type retryPolicy struct {
+	maxAttempts    int
+	initialBackoff time.Duration
+	maxBackoff     time.Duration
+}
+
+// do выполняет операцию, повторяя ее при временных ошибках.
+// Постоянные ошибки возвращаются сразу, временные - после исчерпания попыток
+func (r retryPolicy) do(ctx context.Context, logger *logrus.Entry, op func() error) error {
+	backoff := r.initialBackoff
+
+	for attempt := 1; ; attempt++ {
+		err := op()
+		if err == nil || !database.IsTransientError(err) || attempt >= r.maxAttempts {
+			return err
+		}
+
+		// Добавляем случайный разброс, чтобы воркеры не повторяли запросы синхронно
+		delay := backoff + time.Duration(rand.Int63n(int64(backoff)/2+1))
+		logger.WithError(err).WithFields(logrus.Fields{
+			"attempt": attempt,
+			"delay":   delay,
+		}).Warn("Transient database error, retrying")
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+
+		backoff *= 2
+		if backoff > r.maxBackoff {
+			backoff = r.maxBackoff
+		}
+	}
+}
@@ -0,0 +1,202 @@
+package kafka
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"order-service/internal/models"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// Заголовки Kafka, описывающие формат сообщения о заказе
+const (
+	HeaderSchemaVersion = "schema-version"
+	HeaderContentType   = "content-type"
+)
+
+// Поддерживаемые форматы тела сообщения
+const (
+	ContentTypeJSON     = "application/json"
+	ContentTypeProtobuf = "application/x-protobuf"
+	ContentTypeAvro     = "application/avro"
+)
+
+// DefaultSchemaVersion - версия схемы сообщений, в которых версия не указана
+const DefaultSchemaVersion = 1
+
+var (
+	// ErrUnknownSchemaVersion возвращается для версии схемы, для которой нет декодера
+	ErrUnknownSchemaVersion = errors.New("unknown schema version")
+	// ErrUnsupportedContentType возвращается для неизвестного формата сообщения
+	ErrUnsupportedContentType = errors.New("unsupported content type")
+)
+
+// OrderDecoder декодирует тело сообщения одного формата и версии схемы в KafkaOrderMessage
+type OrderDecoder func(data []byte) (*models.KafkaOrderMessage, error)
+
+type schemaKey struct {
+	contentType string
+	version     int
+}
+
+// SchemaRegistry хранит декодеры сообщений о заказах по формату и версии схемы.
+// Формат определяется заголовком content-type (если его нет - форматом по умолчанию
+// из конфигурации). Чтобы изменить формат заказа, нужно зарегистрировать декодеры
+// новой версии, приводящие ее к KafkaOrderMessage, и публиковать сообщения с новой версией
+type SchemaRegistry struct {
+	defaultContentType string
+	decoders           map[schemaKey]OrderDecoder
+}
+
+// NewSchemaRegistry создает реестр со всеми поддерживаемыми форматами и версиями схемы
+func NewSchemaRegistry(defaultContentType string) (*SchemaRegistry, error) {
+	contentType, err := ParseContentType(defaultContentType)
+	if err != nil {
+		return nil, err
+	}
+
+	avroDecoder, err := newAvroOrderDecoder(orderAvroSchemaV1)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &SchemaRegistry{
+		defaultContentType: contentType,
+		decoders:           make(map[schemaKey]OrderDecoder),
+	}
+	r.Register(ContentTypeJSON, 1, decodeOrderJSON)
+	r.Register(ContentTypeProtobuf, 1, decodeOrderProtobuf)
+	r.Register(ContentTypeAvro, 1, avroDecoder)
+	return r, nil
+}
+
+// ParseContentType приводит значение content-type (или короткое имя формата из конфигурации:
+// json, protobuf, avro) к одному из поддерживаемых форматов
+func ParseContentType(value string) (string, error) {
+	mediaType := strings.ToLower(strings.TrimSpace(strings.SplitN(value, ";", 2)[0]))
+	switch mediaType {
+	case "json", ContentTypeJSON:
+		return ContentTypeJSON, nil
+	case "protobuf", "proto", ContentTypeProtobuf, "application/protobuf", "application/vnd.google.protobuf":
+		return ContentTypeProtobuf, nil
+	case "avro", ContentTypeAvro, "avro/binary", "application/vnd.apache.avro+binary":
+		return ContentTypeAvro, nil
+	default:
+		return "", fmt.Errorf("%w: %q (expected json, protobuf or avro)", ErrUnsupportedContentType, value)
+	}
+}
+
+// Register регистрирует декодер для формата и версии схемы
+func (r *SchemaRegistry) Register(contentType string, version int, decoder OrderDecoder) {
+	r.decoders[schemaKey{contentType: contentType, version: version}] = decoder
+}
+
+// Versions возвращает поддерживаемые версии схемы формата по возрастанию
+func (r *SchemaRegistry) Versions(contentType string) []int {
+	versions := make([]int, 0, len(r.decoders))
+	for key := range r.decoders {
+		if key.contentType == contentType {
+			versions = append(versions, key.version)
+		}
+	}
+	sort.Ints(versions)
+	return versions
+}
+
+// ContentType возвращает формат сообщения по заголовку content-type или формат по умолчанию
+func (r *SchemaRegistry) ContentType(headers map[string]string) (string, error) {
+	value, ok := headers[HeaderContentType]
+	if !ok {
+		return r.defaultContentType, nil
+	}
+	return ParseContentType(value)
+}
+
+// Decode определяет формат и версию схемы сообщения и декодирует его соответствующим декодером
+func (r *SchemaRegistry) Decode(data []byte, headers map[string]string) (*models.KafkaOrderMessage, error) {
+	contentType, err := r.ContentType(headers)
+	if err != nil {
+		return nil, err
+	}
+
+	version, err := schemaVersion(contentType, data, headers)
+	if err != nil {
+		return nil, err
+	}
+
+	decoder, ok := r.decoders[schemaKey{contentType: contentType, version: version}]
+	if !ok {
+		return nil, fmt.Errorf("%w: %d for %s (supported: %v)",
+			ErrUnknownSchemaVersion, version, contentType, r.Versions(contentType))
+	}
+
+	msg, err := decoder(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s schema version %d: %w", contentType, version, err)
+	}
+
+	// В бинарных форматах версия из тела доступна только после декодирования
+	if msg.SchemaVersion != 0 && msg.SchemaVersion != version {
+		return nil, fmt.Errorf("schema_version %d in message body does not match schema version %d",
+			msg.SchemaVersion, version)
+	}
+	return msg, nil
+}
+
+// schemaVersion возвращает версию схемы из заголовка schema-version, а для JSON -
+// также из поля schema_version. Если указаны оба, они должны совпадать;
+// если не указан ни один, используется DefaultSchemaVersion
+func schemaVersion(contentType string, data []byte, headers map[string]string) (int, error) {
+	var bodyVersion *int
+	if contentType == ContentTypeJSON {
+		var envelope struct {
+			SchemaVersion *int `json:"schema_version"`
+		}
+		if err := json.Unmarshal(data, &envelope); err != nil {
+			return 0, fmt.Errorf("failed to unmarshal JSON: %w", err)
+		}
+		bodyVersion = envelope.SchemaVersion
+	}
+
+	headerValue, hasHeader := headers[HeaderSchemaVersion]
+	if !hasHeader {
+		if bodyVersion == nil {
+			return DefaultSchemaVersion, nil
+		}
+		return *bodyVersion, nil
+	}
+
+	version, err := strconv.Atoi(headerValue)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s header %q", HeaderSchemaVersion, headerValue)
+	}
+	if bodyVersion != nil && *bodyVersion != version {
+		return 0, fmt.Errorf("schema_version field %d does not match %s header %d",
+			*bodyVersion, HeaderSchemaVersion, version)
+	}
+	return version, nil
+}
+
+// decodeOrderJSON декодирует исходный JSON-формат заказа (версия схемы 1).
+// Неизвестные поля игнорируются: producer может добавлять поля, не ломая потребителей
+// старой версии. Несовместимые изменения формата выпускаются с новым schema_version
+func decodeOrderJSON(data []byte) (*models.KafkaOrderMessage, error) {
+	var msg models.KafkaOrderMessage
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal JSON: %w", err)
+	}
+	return &msg, nil
+}
+
+// headerMap преобразует заголовки сообщения Kafka в map
+func headerMap(headers []kafka.Header) map[string]string {
+	result := make(map[string]string, len(headers))
+	for _, header := range headers {
+		result[header.Key] = string(header.Value)
+	}
+	return result
+}
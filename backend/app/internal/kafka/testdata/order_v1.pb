@@ -0,0 +1,5 @@
+
+b563feb7b2b84b6testWBILMTESTTRACKWBIL"[
+Test Testov+97200000002639809"Kiryat Mozkin*Ploshad Mira 152Kraiot:test@gmail.com*A
+b563feb7b2b84b6testreq-42USD"wbpay(%0:alpha@HPd2XҰWBILMTESTTRACK"ab4219087a764ae0btest*Mascaras0:0@HRVivienne SaboX2UWBILMTESTTRACK"cd5320198b875bf1ctest*Lipstick:M@HR
+MaybellineX:enBsig-1JtestRmeestZ9`cj2021-11-26T06:22:19Zr1x
\ No newline at end of file
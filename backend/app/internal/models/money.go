@@ -0,0 +1,158 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+
+	"order-service/internal/currency"
+)
+
+var (
+	// ErrCurrencyMismatch возвращается при сложении сумм в разных валютах
+	ErrCurrencyMismatch = errors.New("currency mismatch")
+	// ErrAmountOverflow возвращается, если результат не помещается в int64
+	ErrAmountOverflow = errors.New("amount overflows int64")
+	// ErrInvalidSale возвращается для скидки вне диапазона 0-100%
+	ErrInvalidSale = errors.New("sale must be between 0 and 100")
+)
+
+// Money - денежная сумма в минимальных единицах валюты (копейках, центах).
+// В JSON и БД сумма хранится числом без валюты, как и раньше: валюта заказа задается
+// полем payment.currency и проставляется во все суммы заказа методом OrderFull.SetCurrency
+type Money struct {
+	amount   int64
+	currency string
+}
+
+// NewMoney создает сумму amount минимальных единиц валюты currency
+func NewMoney(amount int64, currency string) Money {
+	return Money{amount: amount, currency: currency}
+}
+
+// Amount возвращает сумму в минимальных единицах валюты
+func (m Money) Amount() int64 {
+	return m.amount
+}
+
+// Currency возвращает код валюты суммы (пустой, если валюта еще не проставлена)
+func (m Money) Currency() string {
+	return m.currency
+}
+
+// Sign возвращает -1, 0 или 1 в зависимости от знака суммы
+func (m Money) Sign() int {
+	switch {
+	case m.amount < 0:
+		return -1
+	case m.amount > 0:
+		return 1
+	}
+	return 0
+}
+
+// Add складывает суммы одной валюты
+func (m Money) Add(other Money) (Money, error) {
+	if m.currency != other.currency {
+		return Money{}, fmt.Errorf("%w: %s and %s", ErrCurrencyMismatch, m.currency, other.currency)
+	}
+	if (other.amount > 0 && m.amount > math.MaxInt64-other.amount) ||
+		(other.amount < 0 && m.amount < math.MinInt64-other.amount) {
+		return Money{}, fmt.Errorf("%w: %d + %d", ErrAmountOverflow, m.amount, other.amount)
+	}
+	return Money{amount: m.amount + other.amount, currency: m.currency}, nil
+}
+
+// Sum складывает суммы в валюте currency. Без слагаемых возвращает ноль в этой валюте
+func Sum(currency string, values ...Money) (Money, error) {
+	total := NewMoney(0, currency)
+	for _, value := range values {
+		var err error
+		if total, err = total.Add(value); err != nil {
+			return Money{}, err
+		}
+	}
+	return total, nil
+}
+
+// ApplySale возвращает сумму со скидкой sale процентов, округленную вниз до минимальной единицы.
+// Результат по модулю не больше исходной суммы, поэтому переполнение невозможно
+func (m Money) ApplySale(sale int) (Money, error) {
+	if sale < 0 || sale > 100 {
+		return Money{}, fmt.Errorf("%w, got %d", ErrInvalidSale, sale)
+	}
+	// Div - евклидово деление: при положительном делителе округляет вниз и для отрицательных сумм
+	discounted := new(big.Int).Mul(big.NewInt(m.amount), big.NewInt(int64(100-sale)))
+	discounted.Div(discounted, big.NewInt(100))
+	return Money{amount: discounted.Int64(), currency: m.currency}, nil
+}
+
+// SaleIsExact сообщает, делится ли сумма со скидкой sale процентов нацело,
+// то есть не требует округления до минимальной единицы
+func (m Money) SaleIsExact(sale int) bool {
+	return m.amount%100*int64(100-sale)%100 == 0
+}
+
+// String форматирует сумму в основных единицах валюты, например "18.17 USD".
+// Сумма в неизвестной или не проставленной валюте выводится в минимальных единицах
+func (m Money) String() string {
+	exponent, err := currency.Exponent(m.currency)
+	if err != nil {
+		if m.currency == "" {
+			return strconv.FormatInt(m.amount, 10)
+		}
+		return fmt.Sprintf("%d %s", m.amount, m.currency)
+	}
+	value := new(big.Rat).SetFrac(big.NewInt(m.amount), new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(exponent)), nil))
+	return value.FloatString(exponent) + " " + m.currency
+}
+
+// MarshalJSON кодирует сумму числом минимальных единиц
+func (m Money) MarshalJSON() ([]byte, error) {
+	return strconv.AppendInt(nil, m.amount, 10), nil
+}
+
+// UnmarshalJSON читает сумму из целого числа. Валюта не меняется
+func (m *Money) UnmarshalJSON(data []byte) error {
+	var amount int64
+	if err := json.Unmarshal(data, &amount); err != nil {
+		return fmt.Errorf("amount must be an integer number of minor currency units: %w", err)
+	}
+	m.amount = amount
+	return nil
+}
+
+// Value сохраняет сумму в БД числом
+func (m Money) Value() (driver.Value, error) {
+	return m.amount, nil
+}
+
+// Scan читает сумму из числовой колонки. NULL читается как ноль
+func (m *Money) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		m.amount = 0
+	case int64:
+		m.amount = v
+	case []byte:
+		return m.scanString(string(v))
+	case string:
+		return m.scanString(v)
+	default:
+		return fmt.Errorf("cannot scan %T into Money", src)
+	}
+	return nil
+}
+
+func (m *Money) scanString(s string) error {
+	amount, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return fmt.Errorf("cannot scan %q into Money: %w", s, err)
+	}
+	m.amount = amount
+	return nil
+}
@@ -0,0 +1,164 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestMoneyAdd(t *testing.T) {
+	tests := []struct {
+		name    string
+		a, b    Money
+		want    Money
+		wantErr error
+	}{
+		{name: "same currency", a: NewMoney(1817, "USD"), b: NewMoney(1500, "USD"), want: NewMoney(3317, "USD")},
+		{name: "negative", a: NewMoney(100, "RUB"), b: NewMoney(-250, "RUB"), want: NewMoney(-150, "RUB")},
+		{name: "currency mismatch", a: NewMoney(100, "RUB"), b: NewMoney(100, "USD"), wantErr: ErrCurrencyMismatch},
+		{name: "overflow", a: NewMoney(math.MaxInt64, "RUB"), b: NewMoney(1, "RUB"), wantErr: ErrAmountOverflow},
+		{name: "negative overflow", a: NewMoney(math.MinInt64, "RUB"), b: NewMoney(-1, "RUB"), wantErr: ErrAmountOverflow},
+		{name: "max without overflow", a: NewMoney(math.MaxInt64-1, "RUB"), b: NewMoney(1, "RUB"), want: NewMoney(math.MaxInt64, "RUB")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.a.Add(tt.b)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Add() error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && got != tt.want {
+				t.Errorf("Add() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSum(t *testing.T) {
+	got, err := Sum("USD", NewMoney(1000, "USD"), NewMoney(250, "USD"), NewMoney(0, "USD"))
+	if err != nil {
+		t.Fatalf("Sum() error = %v", err)
+	}
+	if want := NewMoney(1250, "USD"); got != want {
+		t.Errorf("Sum() = %v, want %v", got, want)
+	}
+
+	if got, err := Sum("EUR"); err != nil || got != NewMoney(0, "EUR") {
+		t.Errorf("Sum() without values = %v, %v, want 0 EUR", got, err)
+	}
+	if _, err := Sum("USD", NewMoney(1, "USD"), NewMoney(1, "EUR")); !errors.Is(err, ErrCurrencyMismatch) {
+		t.Errorf("Sum() error = %v, want %v", err, ErrCurrencyMismatch)
+	}
+}
+
+func TestMoneyApplySale(t *testing.T) {
+	tests := []struct {
+		name      string
+		price     int64
+		sale      int
+		want      int64
+		wantExact bool
+	}{
+		{name: "no sale", price: 453, sale: 0, want: 453, wantExact: true},
+		{name: "full sale", price: 453, sale: 100, want: 0, wantExact: true},
+		{name: "exact", price: 200, sale: 30, want: 140, wantExact: true},
+		// 453 * 0.7 = 317.1
+		{name: "rounded down", price: 453, sale: 30, want: 317},
+		// 999 * 0.5 = 499.5: половина тоже округляется вниз
+		{name: "half rounded down", price: 999, sale: 50, want: 499},
+		// 1 * 0.01 = 0.01
+		{name: "less than minor unit", price: 1, sale: 99, want: 0},
+		// -453 * 0.7 = -317.1 округляется вниз, а не к нулю
+		{name: "negative rounded down", price: -453, sale: 30, want: -318},
+		// Промежуточное произведение не помещается в int64
+		{name: "large amount", price: math.MaxInt64, sale: 1, want: 9131138316486228048},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			price := NewMoney(tt.price, "RUB")
+			got, err := price.ApplySale(tt.sale)
+			if err != nil {
+				t.Fatalf("ApplySale() error = %v", err)
+			}
+			if got != NewMoney(tt.want, "RUB") {
+				t.Errorf("ApplySale(%d) = %v, want %d RUB", tt.sale, got, tt.want)
+			}
+			if exact := price.SaleIsExact(tt.sale); exact != tt.wantExact {
+				t.Errorf("SaleIsExact(%d) = %v, want %v", tt.sale, exact, tt.wantExact)
+			}
+		})
+	}
+}
+
+func TestMoneyApplySaleOutOfRange(t *testing.T) {
+	for _, sale := range []int{-1, 101} {
+		if _, err := NewMoney(100, "RUB").ApplySale(sale); !errors.Is(err, ErrInvalidSale) {
+			t.Errorf("ApplySale(%d) error = %v, want %v", sale, err, ErrInvalidSale)
+		}
+	}
+}
+
+func TestMoneyString(t *testing.T) {
+	tests := []struct {
+		money Money
+		want  string
+	}{
+		{NewMoney(1817, "USD"), "18.17 USD"},
+		{NewMoney(-5, "RUB"), "-0.05 RUB"},
+		{NewMoney(1500, "JPY"), "1500 JPY"},
+		{NewMoney(1500, "KWD"), "1.500 KWD"},
+		{NewMoney(1500, "XYZ"), "1500 XYZ"},
+		{NewMoney(1500, ""), "1500"},
+	}
+	for _, tt := range tests {
+		if got := tt.money.String(); got != tt.want {
+			t.Errorf("String() = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func TestPaymentJSONWireFormat(t *testing.T) {
+	data := []byte(`{"currency":"USD","amount":1817,"delivery_cost":1500,"goods_total":317,"custom_fee":0}`)
+
+	var payment Payment
+	if err := json.Unmarshal(data, &payment); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if payment.Amount.Amount() != 1817 || payment.GoodsTotal.Amount() != 317 {
+		t.Fatalf("Unmarshal() amount = %d, goods_total = %d", payment.Amount.Amount(), payment.GoodsTotal.Amount())
+	}
+
+	encoded, err := json.Marshal(payment)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(encoded, &fields); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	// Суммы остаются числами, как до появления Money
+	if fields["amount"] != float64(1817) || fields["delivery_cost"] != float64(1500) {
+		t.Errorf("Marshal() = %s, want plain numbers", encoded)
+	}
+
+	if err := json.Unmarshal([]byte(`{"amount":18.17}`), &payment); err == nil {
+		t.Error("Unmarshal() of fractional amount succeeded, want error")
+	}
+}
+
+func TestOrderFullSetCurrency(t *testing.T) {
+	order := &OrderFull{
+		Payment: &Payment{Amount: NewMoney(1000, ""), GoodsTotal: NewMoney(900, "")},
+		Items:   []OrderItem{{Price: NewMoney(1000, ""), TotalPrice: NewMoney(900, ""), Sale: 10}},
+	}
+	order.SetCurrency("EUR")
+
+	if order.Payment.Currency != "EUR" || order.Payment.Amount != NewMoney(1000, "EUR") {
+		t.Errorf("payment = %+v, want EUR amounts", order.Payment)
+	}
+	if order.Items[0].TotalPrice != NewMoney(900, "EUR") {
+		t.Errorf("item total_price = %v, want 9.00 EUR", order.Items[0].TotalPrice)
+	}
+}
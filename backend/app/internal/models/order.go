@@ -1,6 +1,9 @@
 package models
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"time"
 )
 
@@ -18,6 +21,17 @@ type Order struct {
 	OofShard          string    `json:"oof_shard" db:"oof_shard"`
 	CreatedAt         time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt         time.Time `json:"updated_at" db:"updated_at"`
+
+	// Нарушения правил валидации, с которыми заказ был принят (политики warn и correct)
+	ValidationWarnings []ValidationWarning `json:"validation_warnings,omitempty" db:"validation_warnings"`
+}
+
+// ValidationWarning описывает нарушение правила валидации, с которым заказ был принят
+type ValidationWarning struct {
+	Rule    string `json:"rule"`
+	Field   string `json:"field"`
+	Message string `json:"message"`
+	Action  string `json:"action"` // warn - принят как есть, correct - исправлен автоматически
 }
 
 type Delivery struct {
@@ -40,12 +54,12 @@ type Payment struct {
 	RequestID    string    `json:"request_id" db:"request_id"`
 	Currency     string    `json:"currency" db:"currency"`
 	Provider     string    `json:"provider" db:"provider"`
-	Amount       int       `json:"amount" db:"amount"`
+	Amount       Money     `json:"amount" db:"amount"`
 	PaymentDt    int64     `json:"payment_dt" db:"payment_dt"`
 	Bank         string    `json:"bank" db:"bank"`
-	DeliveryCost int       `json:"delivery_cost" db:"delivery_cost"`
-	GoodsTotal   int       `json:"goods_total" db:"goods_total"`
-	CustomFee    int       `json:"custom_fee" db:"custom_fee"`
+	DeliveryCost Money     `json:"delivery_cost" db:"delivery_cost"`
+	GoodsTotal   Money     `json:"goods_total" db:"goods_total"`
+	CustomFee    Money     `json:"custom_fee" db:"custom_fee"`
 	CreatedAt    time.Time `json:"created_at" db:"created_at"`
 }
 
@@ -54,12 +68,12 @@ type OrderItem struct {
 	OrderUID    string    `json:"order_uid" db:"order_uid"`
 	ChrtID      int64     `json:"chrt_id" db:"chrt_id"`
 	TrackNumber string    `json:"track_number" db:"track_number"`
-	Price       int       `json:"price" db:"price"`
+	Price       Money     `json:"price" db:"price"`
 	Rid         string    `json:"rid" db:"rid"`
 	Name        string    `json:"name" db:"name"`
 	Sale        int       `json:"sale" db:"sale"`
 	Size        string    `json:"size" db:"size"`
-	TotalPrice  int       `json:"total_price" db:"total_price"`
+	TotalPrice  Money     `json:"total_price" db:"total_price"`
 	NmID        int64     `json:"nm_id" db:"nm_id"`
 	Brand       string    `json:"brand" db:"brand"`
 	Status      int       `json:"status" db:"status"`
@@ -76,6 +90,8 @@ type OrderFull struct {
 
 // KafkaOrderMessage представляет сообщение из Kafka в том формате, как оно приходит
 type KafkaOrderMessage struct {
+	EventType         string                `json:"event_type,omitempty"`
+	SchemaVersion     int                   `json:"schema_version,omitempty"`
 	OrderUID          string                `json:"order_uid"`
 	TrackNumber       string                `json:"track_number"`
 	Entry             string                `json:"entry"`
@@ -107,24 +123,114 @@ type KafkaPayment struct {
 	RequestID    string `json:"request_id"`
 	Currency     string `json:"currency"`
 	Provider     string `json:"provider"`
-	Amount       int    `json:"amount"`
+	Amount       int64  `json:"amount"`
 	PaymentDt    int64  `json:"payment_dt"`
 	Bank         string `json:"bank"`
-	DeliveryCost int    `json:"delivery_cost"`
-	GoodsTotal   int    `json:"goods_total"`
-	CustomFee    int    `json:"custom_fee"`
+	DeliveryCost int64  `json:"delivery_cost"`
+	GoodsTotal   int64  `json:"goods_total"`
+	CustomFee    int64  `json:"custom_fee"`
 }
 
 type KafkaOrderItem struct {
 	ChrtID      int64  `json:"chrt_id"`
 	TrackNumber string `json:"track_number"`
-	Price       int    `json:"price"`
+	Price       int64  `json:"price"`
 	Rid         string `json:"rid"`
 	Name        string `json:"name"`
 	Sale        int    `json:"sale"`
 	Size        string `json:"size"`
-	TotalPrice  int    `json:"total_price"`
+	TotalPrice  int64  `json:"total_price"`
 	NmID        int64  `json:"nm_id"`
 	Brand       string `json:"brand"`
 	Status      int    `json:"status"`
 }
+
+// SetCurrency проставляет валюту платежа во все суммы заказа: в JSON и БД суммы хранятся без валюты
+func (o *OrderFull) SetCurrency(code string) {
+	if p := o.Payment; p != nil {
+		p.Currency = code
+		p.Amount = NewMoney(p.Amount.Amount(), code)
+		p.DeliveryCost = NewMoney(p.DeliveryCost.Amount(), code)
+		p.GoodsTotal = NewMoney(p.GoodsTotal.Amount(), code)
+		p.CustomFee = NewMoney(p.CustomFee.Amount(), code)
+	}
+	for i := range o.Items {
+		item := &o.Items[i]
+		item.Price = NewMoney(item.Price.Amount(), code)
+		item.TotalPrice = NewMoney(item.TotalPrice.Amount(), code)
+	}
+}
+
+// ContentHash возвращает SHA-256 бизнес-данных заказа без служебных полей БД
+// (id, created_at, updated_at) и предупреждений валидации. По хешу определяется, изменилось ли содержимое
+// заказа при его повторной доставке
+func (o *OrderFull) ContentHash() string {
+	content := *o
+	content.CreatedAt = time.Time{}
+	content.UpdatedAt = time.Time{}
+	// Предупреждения зависят от настроек валидации, а не от содержимого заказа
+	content.ValidationWarnings = nil
+	// PostgreSQL хранит время с точностью до микросекунд и возвращает его в локальной зоне
+	content.DateCreated = o.DateCreated.UTC().Truncate(time.Microsecond)
+
+	if o.Delivery != nil {
+		delivery := *o.Delivery
+		delivery.ID = 0
+		delivery.CreatedAt = time.Time{}
+		content.Delivery = &delivery
+	}
+	if o.Payment != nil {
+		payment := *o.Payment
+		payment.ID = 0
+		payment.CreatedAt = time.Time{}
+		content.Payment = &payment
+	}
+	content.Items = make([]OrderItem, len(o.Items))
+	for i, item := range o.Items {
+		item.ID = 0
+		item.CreatedAt = time.Time{}
+		content.Items[i] = item
+	}
+
+	data, _ := json.Marshal(content)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// Типы событий в топике заказов. Сообщения без event_type считаются созданием заказа
+const (
+	EventOrderCreated       = "order_created"
+	EventOrderStatusChanged = "order_status_changed"
+)
+
+// KafkaEvent содержит общие поля всех сообщений топика, по ним определяется тип события
+type KafkaEvent struct {
+	EventType string `json:"event_type"`
+	OrderUID  string `json:"order_uid"`
+}
+
+// OrderStatusChange представляет событие order_status_changed от системы логистики.
+// Status применяется ко всем товарам заказа, Items - к отдельным товарам по chrt_id
+type OrderStatusChange struct {
+	EventType string             `json:"event_type"`
+	OrderUID  string             `json:"order_uid"`
+	Status    *int               `json:"status,omitempty"`
+	Items     []ItemStatusChange `json:"items,omitempty"`
+	ChangedAt time.Time          `json:"changed_at"`
+}
+
+type ItemStatusChange struct {
+	ChrtID int64 `json:"chrt_id"`
+	Status int   `json:"status"`
+}
+
+// OrderStatusHistory представляет запись истории изменения статуса товара заказа
+type OrderStatusHistory struct {
+	ID        int64     `json:"id" db:"id"`
+	OrderUID  string    `json:"order_uid" db:"order_uid"`
+	ChrtID    int64     `json:"chrt_id" db:"chrt_id"`
+	OldStatus *int      `json:"old_status" db:"old_status"`
+	NewStatus int       `json:"new_status" db:"new_status"`
+	Source    string    `json:"source" db:"source"`
+	ChangedAt time.Time `json:"changed_at" db:"changed_at"`
+}
@@ -0,0 +1,321 @@
+package validation
+
+import (
+	"fmt"
+	"order-service/internal/currency"
+	"order-service/internal/models"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Имена правил валидации
+const (
+	RuleRequiredFields     = "required_fields"
+	RuleGoodsTotal         = "goods_total"
+	RulePaymentAmount      = "payment_amount"
+	RuleItemTotalPrice     = "item_total_price"
+	RuleItemTrackNumber    = "item_track_number"
+	RuleDeliveryEmail      = "delivery_email"
+	RuleDeliveryPhone      = "delivery_phone"
+	RuleDeliveryZip        = "delivery_zip"
+	RulePaymentCurrency    = "payment_currency"
+	RuleNonNegativeAmounts = "non_negative_amounts"
+	RuleDateCreated        = "date_created"
+)
+
+var (
+	emailPattern = regexp.MustCompile(`^[^\s@]+@[^\s@]+\.[^\s@]+$`)
+	phonePattern = regexp.MustCompile(`^\+?[0-9]{10,15}$`)
+	zipPattern   = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9 -]{1,9}$`)
+
+	// Символы форматирования, которые удаляются из телефона при автоисправлении
+	phoneFormatting = strings.NewReplacer(" ", "", "-", "", "(", "", ")", "")
+)
+
+// DefaultRules возвращает все бизнес-правила заказа в порядке проверки. Суммы товаров
+// проверяются раньше итогов платежа, чтобы автоисправление итогов учитывало исправленные товары
+func DefaultRules() []Rule {
+	return []Rule{
+		{Name: RuleRequiredFields, Check: checkRequiredFields, Strict: true},
+		{Name: RuleNonNegativeAmounts, Check: checkNonNegativeAmounts},
+		{Name: RuleDateCreated, Check: checkDateCreated, Correct: correctDateCreated},
+		{Name: RuleItemTotalPrice, Check: checkItemTotalPrice, Correct: correctItemTotalPrice},
+		{Name: RuleItemTrackNumber, Check: checkItemTrackNumber, Correct: correctItemTrackNumber},
+		{Name: RuleGoodsTotal, Check: checkGoodsTotal, Correct: correctGoodsTotal},
+		{Name: RulePaymentAmount, Check: checkPaymentAmount, Correct: correctPaymentAmount},
+		{Name: RuleDeliveryEmail, Check: checkDeliveryEmail, Correct: correctDeliveryEmail},
+		{Name: RuleDeliveryPhone, Check: checkDeliveryPhone, Correct: correctDeliveryPhone},
+		{Name: RuleDeliveryZip, Check: checkDeliveryZip, Correct: correctDeliveryZip},
+		{Name: RulePaymentCurrency, Check: checkPaymentCurrency, Correct: correctPaymentCurrency},
+	}
+}
+
+// checkRequiredFields проверяет обязательные поля заказа, доставки и платежа.
+// Без них заказ нельзя сохранить, поэтому правило всегда отклоняет заказ
+func checkRequiredFields(order *models.OrderFull) []Violation {
+	var violations []Violation
+	required := func(field, value string) {
+		if strings.TrimSpace(value) == "" {
+			violations = append(violations, Violation{Rule: RuleRequiredFields, Field: field, Message: "is required"})
+		}
+	}
+
+	required("order_uid", order.OrderUID)
+	required("track_number", order.TrackNumber)
+	required("customer_id", order.CustomerID)
+	if len(order.Items) == 0 {
+		violations = append(violations, Violation{Rule: RuleRequiredFields, Field: "items", Message: "are required"})
+	}
+
+	if order.Delivery == nil {
+		violations = append(violations, Violation{Rule: RuleRequiredFields, Field: "delivery", Message: "is required"})
+	} else {
+		required("delivery.name", order.Delivery.Name)
+	}
+
+	if order.Payment == nil {
+		violations = append(violations, Violation{Rule: RuleRequiredFields, Field: "payment", Message: "is required"})
+	} else {
+		required("payment.currency", order.Payment.Currency)
+		if order.Payment.Amount.Sign() <= 0 {
+			violations = append(violations, Violation{Rule: RuleRequiredFields, Field: "payment.amount", Message: "must be positive"})
+		}
+	}
+
+	return violations
+}
+
+// checkNonNegativeAmounts проверяет, что суммы и цены не отрицательные
+func checkNonNegativeAmounts(order *models.OrderFull) []Violation {
+	var violations []Violation
+	nonNegative := func(field string, value models.Money) {
+		if value.Sign() < 0 {
+			violations = append(violations, Violation{Rule: RuleNonNegativeAmounts, Field: field,
+				Message: fmt.Sprintf("must not be negative, got %d", value.Amount())})
+		}
+	}
+
+	if p := order.Payment; p != nil {
+		nonNegative("payment.delivery_cost", p.DeliveryCost)
+		nonNegative("payment.goods_total", p.GoodsTotal)
+		nonNegative("payment.custom_fee", p.CustomFee)
+	}
+	for i, item := range order.Items {
+		nonNegative(fmt.Sprintf("items[%d].price", i), item.Price)
+		nonNegative(fmt.Sprintf("items[%d].total_price", i), item.TotalPrice)
+		if item.Sale < 0 || item.Sale > 100 {
+			violations = append(violations, Violation{Rule: RuleNonNegativeAmounts, Field: fmt.Sprintf("items[%d].sale", i),
+				Message: fmt.Sprintf("must be between 0 and 100, got %d", item.Sale)})
+		}
+	}
+	return violations
+}
+
+// checkDateCreated проверяет, что дата создания заказа указана и корректна.
+// Нераспознанная дата при конвертации сообщения остается нулевой
+func checkDateCreated(order *models.OrderFull) []Violation {
+	if !order.DateCreated.IsZero() {
+		return nil
+	}
+	return []Violation{{Rule: RuleDateCreated, Field: "date_created", Message: "is missing or not a valid RFC 3339 time"}}
+}
+
+// correctDateCreated подставляет время приема заказа
+func correctDateCreated(order *models.OrderFull) {
+	order.DateCreated = time.Now()
+}
+
+// checkGoodsTotal проверяет, что goods_total равен сумме total_price товаров
+func checkGoodsTotal(order *models.OrderFull) []Violation {
+	if order.Payment == nil || len(order.Items) == 0 {
+		return nil
+	}
+
+	sum, err := ItemsTotal(order)
+	if err != nil {
+		return []Violation{{Rule: RuleGoodsTotal, Field: "payment.goods_total",
+			Message: fmt.Sprintf("cannot sum items total_price: %v", err)}}
+	}
+	if order.Payment.GoodsTotal != sum {
+		return []Violation{{Rule: RuleGoodsTotal, Field: "payment.goods_total",
+			Message: fmt.Sprintf("must equal sum of items total_price %d, got %d", sum.Amount(), order.Payment.GoodsTotal.Amount())}}
+	}
+	return nil
+}
+
+// checkPaymentAmount проверяет, что amount = goods_total + delivery_cost + custom_fee
+func checkPaymentAmount(order *models.OrderFull) []Violation {
+	p := order.Payment
+	if p == nil {
+		return nil
+	}
+
+	expected, err := models.Sum(p.Currency, p.GoodsTotal, p.DeliveryCost, p.CustomFee)
+	if err != nil {
+		return []Violation{{Rule: RulePaymentAmount, Field: "payment.amount",
+			Message: fmt.Sprintf("cannot sum goods_total + delivery_cost + custom_fee: %v", err)}}
+	}
+	if p.Amount != expected {
+		return []Violation{{Rule: RulePaymentAmount, Field: "payment.amount",
+			Message: fmt.Sprintf("must equal goods_total + delivery_cost + custom_fee = %d, got %d", expected.Amount(), p.Amount.Amount())}}
+	}
+	return nil
+}
+
+// checkItemTotalPrice проверяет, что total_price равен цене со скидкой
+// (допускается округление до целой денежной единицы в любую сторону).
+// Товары со скидкой вне 0-100% пропускаются: их отмечает правило non_negative_amounts
+func checkItemTotalPrice(order *models.OrderFull) []Violation {
+	var violations []Violation
+	for i, item := range order.Items {
+		expected, err := DiscountedPrice(item)
+		if err != nil {
+			continue
+		}
+		if !totalPriceMatches(item, expected) {
+			violations = append(violations, Violation{Rule: RuleItemTotalPrice, Field: fmt.Sprintf("items[%d].total_price", i),
+				Message: fmt.Sprintf("must equal price %d with sale %d%% = %d, got %d",
+					item.Price.Amount(), item.Sale, expected.Amount(), item.TotalPrice.Amount())})
+		}
+	}
+	return violations
+}
+
+// checkItemTrackNumber проверяет, что трек-номер товара совпадает с трек-номером заказа
+func checkItemTrackNumber(order *models.OrderFull) []Violation {
+	var violations []Violation
+	for i, item := range order.Items {
+		if item.TrackNumber != order.TrackNumber {
+			violations = append(violations, Violation{Rule: RuleItemTrackNumber, Field: fmt.Sprintf("items[%d].track_number", i),
+				Message: fmt.Sprintf("must match order track_number %q, got %q", order.TrackNumber, item.TrackNumber)})
+		}
+	}
+	return violations
+}
+
+func checkDeliveryEmail(order *models.OrderFull) []Violation {
+	if order.Delivery == nil || order.Delivery.Email == "" || emailPattern.MatchString(order.Delivery.Email) {
+		return nil
+	}
+	return []Violation{{Rule: RuleDeliveryEmail, Field: "delivery.email",
+		Message: fmt.Sprintf("invalid email %q", order.Delivery.Email)}}
+}
+
+// checkDeliveryPhone проверяет, что телефон получателя указан и имеет корректный формат
+func checkDeliveryPhone(order *models.OrderFull) []Violation {
+	if order.Delivery == nil || phonePattern.MatchString(order.Delivery.Phone) {
+		return nil
+	}
+	if strings.TrimSpace(order.Delivery.Phone) == "" {
+		return []Violation{{Rule: RuleDeliveryPhone, Field: "delivery.phone", Message: "is required"}}
+	}
+	return []Violation{{Rule: RuleDeliveryPhone, Field: "delivery.phone",
+		Message: fmt.Sprintf("invalid phone %q, expected 10-15 digits with optional leading +", order.Delivery.Phone)}}
+}
+
+func checkDeliveryZip(order *models.OrderFull) []Violation {
+	if order.Delivery == nil || order.Delivery.Zip == "" || zipPattern.MatchString(order.Delivery.Zip) {
+		return nil
+	}
+	return []Violation{{Rule: RuleDeliveryZip, Field: "delivery.zip",
+		Message: fmt.Sprintf("invalid zip %q", order.Delivery.Zip)}}
+}
+
+// checkPaymentCurrency проверяет, что валюта - действующий код ISO 4217
+func checkPaymentCurrency(order *models.OrderFull) []Violation {
+	if order.Payment == nil || order.Payment.Currency == "" || currency.Known(order.Payment.Currency) {
+		return nil
+	}
+	return []Violation{{Rule: RulePaymentCurrency, Field: "payment.currency",
+		Message: fmt.Sprintf("unknown ISO 4217 currency code %q", order.Payment.Currency)}}
+}
+
+// correctItemTotalPrice пересчитывает total_price товаров по цене и скидке
+func correctItemTotalPrice(order *models.OrderFull) {
+	for i := range order.Items {
+		expected, err := DiscountedPrice(order.Items[i])
+		if err == nil && !totalPriceMatches(order.Items[i], expected) {
+			order.Items[i].TotalPrice = expected
+		}
+	}
+}
+
+// correctItemTrackNumber копирует трек-номер заказа в товары
+func correctItemTrackNumber(order *models.OrderFull) {
+	for i := range order.Items {
+		order.Items[i].TrackNumber = order.TrackNumber
+	}
+}
+
+// correctGoodsTotal пересчитывает goods_total по товарам
+func correctGoodsTotal(order *models.OrderFull) {
+	if order.Payment == nil || len(order.Items) == 0 {
+		return
+	}
+	if sum, err := ItemsTotal(order); err == nil {
+		order.Payment.GoodsTotal = sum
+	}
+}
+
+// correctPaymentAmount пересчитывает amount из goods_total, delivery_cost и custom_fee
+func correctPaymentAmount(order *models.OrderFull) {
+	if p := order.Payment; p != nil {
+		if amount, err := models.Sum(p.Currency, p.GoodsTotal, p.DeliveryCost, p.CustomFee); err == nil {
+			p.Amount = amount
+		}
+	}
+}
+
+// correctDeliveryEmail убирает пробелы вокруг email
+func correctDeliveryEmail(order *models.OrderFull) {
+	if order.Delivery != nil {
+		order.Delivery.Email = strings.TrimSpace(order.Delivery.Email)
+	}
+}
+
+// correctDeliveryPhone убирает из телефона пробелы, дефисы и скобки
+func correctDeliveryPhone(order *models.OrderFull) {
+	if order.Delivery != nil {
+		order.Delivery.Phone = phoneFormatting.Replace(strings.TrimSpace(order.Delivery.Phone))
+	}
+}
+
+// correctDeliveryZip убирает пробелы вокруг индекса
+func correctDeliveryZip(order *models.OrderFull) {
+	if order.Delivery != nil {
+		order.Delivery.Zip = strings.TrimSpace(order.Delivery.Zip)
+	}
+}
+
+// correctPaymentCurrency приводит код валюты к верхнему регистру
+func correctPaymentCurrency(order *models.OrderFull) {
+	if order.Payment != nil {
+		order.SetCurrency(currency.Normalize(order.Payment.Currency))
+	}
+}
+
+// ItemsTotal возвращает сумму total_price товаров заказа в валюте платежа
+func ItemsTotal(order *models.OrderFull) (models.Money, error) {
+	var code string
+	if order.Payment != nil {
+		code = order.Payment.Currency
+	}
+	totals := make([]models.Money, len(order.Items))
+	for i, item := range order.Items {
+		totals[i] = item.TotalPrice
+	}
+	return models.Sum(code, totals...)
+}
+
+// DiscountedPrice возвращает цену товара со скидкой, округленную вниз
+func DiscountedPrice(item models.OrderItem) (models.Money, error) {
+	return item.Price.ApplySale(item.Sale)
+}
+
+// totalPriceMatches сравнивает total_price с ценой со скидкой expected, округленной вниз.
+// Если цена со скидкой дробная, total_price может быть округлен и вверх
+func totalPriceMatches(item models.OrderItem, expected models.Money) bool {
+	diff := item.TotalPrice.Amount() - expected.Amount()
+	return diff == 0 || diff == 1 && !item.Price.SaleIsExact(item.Sale)
+}
@@ -0,0 +1,89 @@
+package validation
+
+import (
+	"math"
+	"testing"
+
+	"order-service/internal/models"
+)
+
+func orderWithItem(price int64, sale int, totalPrice int64) *models.OrderFull {
+	order := &models.OrderFull{
+		Payment: &models.Payment{},
+		Items:   []models.OrderItem{{Price: models.NewMoney(price, ""), Sale: sale, TotalPrice: models.NewMoney(totalPrice, "")}},
+	}
+	order.SetCurrency("RUB")
+	return order
+}
+
+func TestCheckItemTotalPriceRounding(t *testing.T) {
+	tests := []struct {
+		name       string
+		price      int64
+		sale       int
+		totalPrice int64
+		valid      bool
+	}{
+		{name: "exact", price: 200, sale: 30, totalPrice: 140, valid: true},
+		{name: "exact rounded up", price: 200, sale: 30, totalPrice: 141},
+		{name: "fraction rounded down", price: 453, sale: 30, totalPrice: 317, valid: true},
+		{name: "fraction rounded up", price: 453, sale: 30, totalPrice: 318, valid: true},
+		{name: "off by two", price: 453, sale: 30, totalPrice: 319},
+		{name: "below rounding", price: 453, sale: 30, totalPrice: 316},
+		{name: "half rounded up", price: 999, sale: 50, totalPrice: 500, valid: true},
+		{name: "no sale", price: 453, sale: 0, totalPrice: 453, valid: true},
+		{name: "full sale", price: 453, sale: 100, totalPrice: 0, valid: true},
+		// Скидку вне диапазона отмечает правило non_negative_amounts
+		{name: "invalid sale skipped", price: 453, sale: 150, totalPrice: 1, valid: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			violations := checkItemTotalPrice(orderWithItem(tt.price, tt.sale, tt.totalPrice))
+			if valid := len(violations) == 0; valid != tt.valid {
+				t.Errorf("checkItemTotalPrice() = %v, want valid = %v", violations, tt.valid)
+			}
+		})
+	}
+}
+
+func TestCorrectItemTotalPrice(t *testing.T) {
+	order := orderWithItem(453, 30, 1000)
+	correctItemTotalPrice(order)
+	if got := order.Items[0].TotalPrice; got != models.NewMoney(317, "RUB") {
+		t.Errorf("total_price = %v, want 3.17 RUB", got)
+	}
+
+	// Допустимое округление вверх не исправляется
+	order = orderWithItem(453, 30, 318)
+	correctItemTotalPrice(order)
+	if got := order.Items[0].TotalPrice.Amount(); got != 318 {
+		t.Errorf("total_price = %d, want 318", got)
+	}
+}
+
+func TestGoodsTotalOverflow(t *testing.T) {
+	order := orderWithItem(math.MaxInt64, 0, math.MaxInt64)
+	order.Items = append(order.Items, order.Items[0])
+
+	if violations := checkGoodsTotal(order); len(violations) != 1 {
+		t.Fatalf("checkGoodsTotal() = %v, want overflow violation", violations)
+	}
+	correctGoodsTotal(order)
+	if got := order.Payment.GoodsTotal.Amount(); got != 0 {
+		t.Errorf("goods_total = %d, want unchanged 0", got)
+	}
+}
+
+func TestCorrectPaymentCurrencyUpdatesAmounts(t *testing.T) {
+	order := orderWithItem(453, 30, 317)
+	order.SetCurrency(" usd ")
+	correctPaymentCurrency(order)
+
+	if order.Payment.Currency != "USD" || order.Items[0].Price.Currency() != "USD" {
+		t.Errorf("currency = %q, item price currency = %q, want USD", order.Payment.Currency, order.Items[0].Price.Currency())
+	}
+	if violations := checkGoodsTotal(order); len(violations) != 1 || violations[0].Message != "must equal sum of items total_price 317, got 0" {
+		t.Errorf("checkGoodsTotal() = %v", violations)
+	}
+}
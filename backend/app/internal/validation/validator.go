@@ -0,0 +1,178 @@
+package validation
+
+import (
+	"fmt"
+	"order-service/internal/models"
+	"sort"
+	"strings"
+)
+
+// Policy определяет, что делать с заказом, нарушающим правило
+type Policy string
+
+const (
+	PolicyReject  Policy = "reject"  // Отклонить заказ
+	PolicyWarn    Policy = "warn"    // Принять заказ как есть и сохранить предупреждение
+	PolicyCorrect Policy = "correct" // Исправить заказ автоматически и сохранить предупреждение
+)
+
+// ParsePolicy разбирает политику из строки конфигурации
+func ParsePolicy(value string) (Policy, error) {
+	switch policy := Policy(strings.TrimSpace(value)); policy {
+	case PolicyReject, PolicyWarn, PolicyCorrect:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("unknown validation policy %q (expected reject, warn or correct)", value)
+	}
+}
+
+// Violation описывает нарушение одного правила валидации
+type Violation struct {
+	Rule    string `json:"rule"`    // Имя правила
+	Field   string `json:"field"`   // Путь к полю в JSON, например items[0].total_price
+	Message string `json:"message"` // Описание нарушения
+}
+
+// Errors - все нарушения, из-за которых заказ отклонен
+type Errors []Violation
+
+func (e Errors) Error() string {
+	messages := make([]string, len(e))
+	for i, v := range e {
+		messages[i] = fmt.Sprintf("%s: %s", v.Field, v.Message)
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Rule - правило валидации заказа. Check возвращает все нарушения правила,
+// Correct (если правило его поддерживает) исправляет заказ.
+// Strict-правила всегда отклоняют заказ независимо от настроек
+type Rule struct {
+	Name    string
+	Check   func(order *models.OrderFull) []Violation
+	Correct func(order *models.OrderFull)
+	Strict  bool
+}
+
+// Validator проверяет заказ набором правил и возвращает все нарушения сразу.
+// Для каждого правила задана политика: reject, warn или correct
+type Validator struct {
+	rules    []Rule
+	policies map[string]Policy
+}
+
+// NewValidator создает валидатор со всеми бизнес-правилами заказа.
+// defaultPolicy применяется к правилам, которых нет в rulePolicies.
+// Политика correct для правила без автоисправления считается ошибкой конфигурации
+func NewValidator(defaultPolicy string, rulePolicies map[string]string) (*Validator, error) {
+	fallback, err := ParsePolicy(defaultPolicy)
+	if err != nil {
+		return nil, err
+	}
+
+	rules := DefaultRules()
+	known := make(map[string]Rule, len(rules))
+	for _, rule := range rules {
+		known[rule.Name] = rule
+	}
+
+	policies := make(map[string]Policy, len(rules))
+	for _, rule := range rules {
+		policies[rule.Name] = fallback
+		if rule.Strict || (fallback == PolicyCorrect && rule.Correct == nil) {
+			// Политика по умолчанию не ослабляет strict-правила и правила без автоисправления
+			policies[rule.Name] = PolicyReject
+		}
+	}
+
+	for name, value := range rulePolicies {
+		rule, ok := known[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown validation rule %q (known: %s)", name, strings.Join(ruleNames(rules), ", "))
+		}
+		policy, err := ParsePolicy(value)
+		if err != nil {
+			return nil, fmt.Errorf("rule %s: %w", name, err)
+		}
+		if rule.Strict && policy != PolicyReject {
+			return nil, fmt.Errorf("rule %s always rejects the order", name)
+		}
+		if policy == PolicyCorrect && rule.Correct == nil {
+			return nil, fmt.Errorf("rule %s does not support auto-correction", name)
+		}
+		policies[name] = policy
+	}
+
+	return &Validator{rules: rules, policies: policies}, nil
+}
+
+// Rules возвращает правила валидатора
+func (v *Validator) Rules() []Rule {
+	return v.rules
+}
+
+// Policies возвращает политику каждого правила
+func (v *Validator) Policies() map[string]Policy {
+	policies := make(map[string]Policy, len(v.policies))
+	for name, policy := range v.policies {
+		policies[name] = policy
+	}
+	return policies
+}
+
+// Validate проверяет заказ всеми правилами, применяя их политики. Правила с политикой correct
+// исправляют заказ на месте. Возвращает предупреждения для принятых нарушений и Errors,
+// если заказ нужно отклонить
+func (v *Validator) Validate(order *models.OrderFull) ([]models.ValidationWarning, error) {
+	var warnings []models.ValidationWarning
+	var violations Errors
+
+	for _, rule := range v.rules {
+		found := rule.Check(order)
+		if len(found) == 0 {
+			continue
+		}
+
+		switch v.policies[rule.Name] {
+		case PolicyWarn:
+			warnings = append(warnings, toWarnings(found, string(PolicyWarn))...)
+		case PolicyCorrect:
+			rule.Correct(order)
+			// То, что не удалось исправить, отклоняет заказ
+			remaining := rule.Check(order)
+			violations = append(violations, remaining...)
+			if len(remaining) == 0 {
+				warnings = append(warnings, toWarnings(found, string(PolicyCorrect))...)
+			}
+		default:
+			violations = append(violations, found...)
+		}
+	}
+
+	if len(violations) > 0 {
+		return warnings, violations
+	}
+	return warnings, nil
+}
+
+func toWarnings(violations []Violation, action string) []models.ValidationWarning {
+	warnings := make([]models.ValidationWarning, len(violations))
+	for i, v := range violations {
+		warnings[i] = models.ValidationWarning{
+			Rule:    v.Rule,
+			Field:   v.Field,
+			Message: v.Message,
+			Action:  action,
+		}
+	}
+	return warnings
+}
+
+func ruleNames(rules []Rule) []string {
+	names := make([]string, len(rules))
+	for i, rule := range rules {
+		names[i] = rule.Name
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,195 @@
+package validation
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"order-service/internal/models"
+)
+
+func TestNewValidatorPolicies(t *testing.T) {
+	tests := []struct {
+		name          string
+		defaultPolicy string
+		rulePolicies  map[string]string
+		want          map[string]Policy
+	}{
+		{
+			name:          "default reject",
+			defaultPolicy: "reject",
+			want: map[string]Policy{
+				RuleRequiredFields:     PolicyReject,
+				RuleNonNegativeAmounts: PolicyReject,
+				RuleItemTotalPrice:     PolicyReject,
+			},
+		},
+		{
+			name:          "default warn keeps strict rules",
+			defaultPolicy: "warn",
+			want: map[string]Policy{
+				RuleRequiredFields:     PolicyReject,
+				RuleNonNegativeAmounts: PolicyWarn,
+				RuleItemTotalPrice:     PolicyWarn,
+			},
+		},
+		{
+			// Правила без автоисправления не получают correct по умолчанию
+			name:          "default correct falls back to reject",
+			defaultPolicy: "correct",
+			want: map[string]Policy{
+				RuleRequiredFields:     PolicyReject,
+				RuleNonNegativeAmounts: PolicyReject,
+				RuleItemTotalPrice:     PolicyCorrect,
+				RuleDeliveryEmail:      PolicyCorrect,
+			},
+		},
+		{
+			name:          "rule overrides",
+			defaultPolicy: "correct",
+			rulePolicies: map[string]string{
+				RuleRequiredFields:     "reject",
+				RuleNonNegativeAmounts: "warn",
+				RuleItemTotalPrice:     "reject",
+				RuleDeliveryEmail:      " warn ",
+			},
+			want: map[string]Policy{
+				RuleRequiredFields:     PolicyReject,
+				RuleNonNegativeAmounts: PolicyWarn,
+				RuleItemTotalPrice:     PolicyReject,
+				RuleDeliveryEmail:      PolicyWarn,
+				RuleDeliveryPhone:      PolicyCorrect,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, err := NewValidator(tt.defaultPolicy, tt.rulePolicies)
+			if err != nil {
+				t.Fatalf("NewValidator() error = %v", err)
+			}
+			policies := v.Policies()
+			if len(policies) != len(DefaultRules()) {
+				t.Errorf("policies for %d rules, want %d", len(policies), len(DefaultRules()))
+			}
+			for name, want := range tt.want {
+				if got := policies[name]; got != want {
+					t.Errorf("policy of %s = %q, want %q", name, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestNewValidatorErrors(t *testing.T) {
+	tests := []struct {
+		name          string
+		defaultPolicy string
+		rulePolicies  map[string]string
+		wantErr       string
+	}{
+		{name: "invalid default", defaultPolicy: "drop", wantErr: `unknown validation policy "drop"`},
+		{name: "unknown rule", defaultPolicy: "reject", rulePolicies: map[string]string{"no_such_rule": "warn"}, wantErr: `unknown validation rule "no_such_rule"`},
+		{name: "invalid rule policy", defaultPolicy: "reject", rulePolicies: map[string]string{RuleDeliveryEmail: "drop"}, wantErr: "rule delivery_email: unknown validation policy"},
+		{name: "strict warn", defaultPolicy: "reject", rulePolicies: map[string]string{RuleRequiredFields: "warn"}, wantErr: "rule required_fields always rejects the order"},
+		{name: "strict correct", defaultPolicy: "reject", rulePolicies: map[string]string{RuleRequiredFields: "correct"}, wantErr: "rule required_fields always rejects the order"},
+		{name: "correct without Correct", defaultPolicy: "reject", rulePolicies: map[string]string{RuleNonNegativeAmounts: "correct"}, wantErr: "rule non_negative_amounts does not support auto-correction"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewValidator(tt.defaultPolicy, tt.rulePolicies)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("NewValidator() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// trackNumberRule - тестовое правило: track_number не должен быть пустым,
+// автоисправление подставляет fix (пустой fix ничего не исправляет)
+func trackNumberRule(name, fix string) Rule {
+	return Rule{
+		Name: name,
+		Check: func(order *models.OrderFull) []Violation {
+			if order.TrackNumber != "" {
+				return nil
+			}
+			return []Violation{{Rule: name, Field: "track_number", Message: "is required"}}
+		},
+		Correct: func(order *models.OrderFull) {
+			order.TrackNumber = fix
+		},
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name         string
+		policy       Policy
+		fix          string
+		wantWarnings []string // Action предупреждений
+		wantRejected bool
+		wantTrack    string
+	}{
+		{name: "reject", policy: PolicyReject, wantRejected: true},
+		{name: "warn", policy: PolicyWarn, wantWarnings: []string{"warn"}},
+		{name: "correct", policy: PolicyCorrect, fix: "WBILMTESTTRACK", wantWarnings: []string{"correct"}, wantTrack: "WBILMTESTTRACK"},
+		{name: "correct fails", policy: PolicyCorrect, wantRejected: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := &Validator{
+				rules:    []Rule{trackNumberRule("track", tt.fix)},
+				policies: map[string]Policy{"track": tt.policy},
+			}
+			order := &models.OrderFull{}
+
+			warnings, err := v.Validate(order)
+			var violations Errors
+			if rejected := errors.As(err, &violations); rejected != tt.wantRejected {
+				t.Fatalf("Validate() error = %v, want rejected = %v", err, tt.wantRejected)
+			}
+			if tt.wantRejected && (len(violations) != 1 || violations[0].Field != "track_number") {
+				t.Errorf("violations = %v, want track_number", violations)
+			}
+			if len(warnings) != len(tt.wantWarnings) {
+				t.Fatalf("warnings = %v, want actions %v", warnings, tt.wantWarnings)
+			}
+			for i, action := range tt.wantWarnings {
+				if w := warnings[i]; w.Action != action || w.Rule != "track" || w.Field != "track_number" {
+					t.Errorf("warnings[%d] = %+v, want action %s", i, w, action)
+				}
+			}
+			if order.TrackNumber != tt.wantTrack {
+				t.Errorf("track_number = %q, want %q", order.TrackNumber, tt.wantTrack)
+			}
+		})
+	}
+}
+
+func TestValidateCollectsAllRules(t *testing.T) {
+	v := &Validator{
+		rules: []Rule{
+			trackNumberRule("first", ""),
+			trackNumberRule("second", ""),
+			trackNumberRule("third", ""),
+		},
+		policies: map[string]Policy{"first": PolicyReject, "second": PolicyWarn, "third": PolicyReject},
+	}
+
+	warnings, err := v.Validate(&models.OrderFull{})
+	var violations Errors
+	if !errors.As(err, &violations) {
+		t.Fatalf("Validate() error = %v, want Errors", err)
+	}
+	// Отклонение по одному правилу не останавливает проверку остальных
+	if len(violations) != 2 || violations[0].Rule != "first" || violations[1].Rule != "third" {
+		t.Errorf("violations = %v, want first and third", violations)
+	}
+	if len(warnings) != 1 || warnings[0].Rule != "second" {
+		t.Errorf("warnings = %v, want second", warnings)
+	}
+}
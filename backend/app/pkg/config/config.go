@@ -4,19 +4,27 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 )
 
 // Config содержит все настройки приложения
 type Config struct {
-	Server   ServerConfig   `yaml:"server"`
-	Database DatabaseConfig `yaml:"database"`
-	Kafka    KafkaConfig    `yaml:"kafka"`
-	Cache    CacheConfig    `yaml:"cache"`
+	Server     ServerConfig     `yaml:"server"`
+	Database   DatabaseConfig   `yaml:"database"`
+	Kafka      KafkaConfig      `yaml:"kafka"`
+	Cache      CacheConfig      `yaml:"cache"`
+	Ingest     IngestConfig     `yaml:"ingest"`
+	Validation ValidationConfig `yaml:"validation"`
+	Currency   CurrencyConfig   `yaml:"currency"`
 }
 
 type ServerConfig struct {
 	Port string `yaml:"port"`
 	Host string `yaml:"host"`
+
+	// Адрес отдельного сервера административного API (DLQ). Пустой адрес отключает его
+	AdminAddr string `yaml:"admin_addr"`
 }
 
 type DatabaseConfig struct {
@@ -29,21 +37,64 @@ type DatabaseConfig struct {
 }
 
 type KafkaConfig struct {
-	Brokers []string `yaml:"brokers"`
-	Topic   string   `yaml:"topic"`
-	GroupID string   `yaml:"group_id"`
+	Brokers  []string `yaml:"brokers"`
+	Topic    string   `yaml:"topic"`
+	GroupID  string   `yaml:"group_id"`
+	DLQTopic string   `yaml:"dlq_topic"`
+	Workers  int      `yaml:"workers"` // Число параллельных воркеров обработки сообщений
+
+	// Формат сообщений без заголовка content-type: json, protobuf или avro
+	ContentType string `yaml:"content_type"`
+
+	// Накопление заказов для пакетной вставки в БД
+	BatchSize    int           `yaml:"batch_size"`
+	BatchTimeout time.Duration `yaml:"batch_timeout"`
+
+	// Повторы при временных ошибках БД (экспоненциальный backoff)
+	RetryMaxAttempts    int           `yaml:"retry_max_attempts"`
+	RetryInitialBackoff time.Duration `yaml:"retry_initial_backoff"`
+	RetryMaxBackoff     time.Duration `yaml:"retry_max_backoff"`
 }
 
 type CacheConfig struct {
 	MaxSize int `yaml:"max_size"`
 }
 
+// IngestConfig содержит настройки приема заказов (общие для Kafka и HTTP)
+type IngestConfig struct {
+	// Что делать с повторно доставленным заказом, содержимое которого отличается
+	// от сохраненного: ignore, overwrite или conflict
+	ConflictPolicy string `yaml:"conflict_policy"`
+}
+
+// ValidationConfig задает политику для нарушений правил валидации: reject - отклонить заказ,
+// warn - принять с предупреждением, correct - исправить автоматически с предупреждением
+type ValidationConfig struct {
+	DefaultPolicy string            `yaml:"default_policy"`
+	Rules         map[string]string `yaml:"rules"` // Политики отдельных правил: имя правила -> политика
+}
+
+// CurrencyConfig задает источник курсов валют для отчетов в валюте reporting_currency
+type CurrencyConfig struct {
+	Base            string        `yaml:"base"`             // Валюта, к которой заданы курсы
+	RatesFile       string        `yaml:"rates_file"`       // CSV currency,rate,effective_from; пусто - таблица exchange_rates
+	RefreshInterval time.Duration `yaml:"refresh_interval"` // Как часто перечитывать курсы
+}
+
 // LoadConfig загружает конфигурацию из переменных окружения с дефолтными значениями
-func LoadConfig() *Config {
+func LoadConfig() (*Config, error) {
+	// Нераспознанная дата создания по умолчанию заменяется временем приема заказа
+	validationRules, err := getEnvAsMap("VALIDATION_RULES", map[string]string{"date_created": "correct"})
+	if err != nil {
+		return nil, err
+	}
+
 	return &Config{
 		Server: ServerConfig{
 			Port: getEnv("SERVER_PORT", "8081"),
 			Host: getEnv("SERVER_HOST", "0.0.0.0"),
+			// По умолчанию административный API доступен только локально
+			AdminAddr: getEnvAllowEmpty("ADMIN_ADDR", "127.0.0.1:8082"),
 		},
 		Database: DatabaseConfig{
 			Host:     getEnv("DB_HOST", "localhost"),
@@ -54,14 +105,53 @@ func LoadConfig() *Config {
 			SSLMode:  getEnv("DB_SSLMODE", "disable"),
 		},
 		Kafka: KafkaConfig{
-			Brokers: []string{getEnv("KAFKA_BROKERS", "localhost:9092")},
-			Topic:   getEnv("KAFKA_TOPIC", "orders"),
-			GroupID: getEnv("KAFKA_GROUP_ID", "order-service-group"),
+			Brokers:  []string{getEnv("KAFKA_BROKERS", "localhost:9092")},
+			Topic:    getEnv("KAFKA_TOPIC", "orders"),
+			GroupID:  getEnv("KAFKA_GROUP_ID", "order-service-group"),
+			DLQTopic: getEnv("KAFKA_DLQ_TOPIC", "orders-dlq"),
+			Workers:  getEnvAsInt("KAFKA_WORKERS", 4),
+
+			ContentType: getEnv("KAFKA_CONTENT_TYPE", "json"),
+
+			BatchSize:    getEnvAsInt("KAFKA_BATCH_SIZE", 100),
+			BatchTimeout: getEnvAsDuration("KAFKA_BATCH_TIMEOUT", 500*time.Millisecond),
+
+			RetryMaxAttempts:    getEnvAsInt("KAFKA_RETRY_MAX_ATTEMPTS", 5),
+			RetryInitialBackoff: getEnvAsDuration("KAFKA_RETRY_INITIAL_BACKOFF", 200*time.Millisecond),
+			RetryMaxBackoff:     getEnvAsDuration("KAFKA_RETRY_MAX_BACKOFF", 10*time.Second),
 		},
 		Cache: CacheConfig{
 			MaxSize: getEnvAsInt("CACHE_MAX_SIZE", 1000),
 		},
+		Ingest: IngestConfig{
+			ConflictPolicy: getEnv("ORDER_CONFLICT_POLICY", "ignore"),
+		},
+		Validation: ValidationConfig{
+			DefaultPolicy: getEnv("VALIDATION_DEFAULT_POLICY", "reject"),
+			Rules:         validationRules,
+		},
+		Currency: CurrencyConfig{
+			Base:            getEnv("EXCHANGE_RATES_BASE", "RUB"),
+			RatesFile:       getEnv("EXCHANGE_RATES_FILE", ""),
+			RefreshInterval: getEnvAsDuration("EXCHANGE_RATES_REFRESH_INTERVAL", time.Hour),
+		},
+	}, nil
+}
+
+// ValidateRetry проверяет настройки повторов записи в БД: с нулевой или отрицательной
+// задержкой повторы шли бы без паузы, а расчет случайного разброса завершился бы паникой
+func (c *KafkaConfig) ValidateRetry() error {
+	if c.RetryMaxAttempts < 1 {
+		return fmt.Errorf("KAFKA_RETRY_MAX_ATTEMPTS must be at least 1, got %d", c.RetryMaxAttempts)
+	}
+	if c.RetryInitialBackoff <= 0 {
+		return fmt.Errorf("KAFKA_RETRY_INITIAL_BACKOFF must be positive, got %s", c.RetryInitialBackoff)
 	}
+	if c.RetryMaxBackoff < c.RetryInitialBackoff {
+		return fmt.Errorf("KAFKA_RETRY_MAX_BACKOFF (%s) must not be less than KAFKA_RETRY_INITIAL_BACKOFF (%s)",
+			c.RetryMaxBackoff, c.RetryInitialBackoff)
+	}
+	return nil
 }
 
 // GetDSN возвращает строку подключения к PostgreSQL
@@ -77,6 +167,15 @@ func getEnv(key, defaultValue string) string {
 	return defaultValue
 }
 
+// getEnvAllowEmpty в отличие от getEnv возвращает пустое значение заданной переменной,
+// а не значение по умолчанию
+func getEnvAllowEmpty(key, defaultValue string) string {
+	if value, ok := os.LookupEnv(key); ok {
+		return value
+	}
+	return defaultValue
+}
+
 func getEnvAsInt(key string, defaultValue int) int {
 	if value := os.Getenv(key); value != "" {
 		if intValue, err := strconv.Atoi(value); err == nil {
@@ -85,3 +184,36 @@ func getEnvAsInt(key string, defaultValue int) int {
 	}
 	return defaultValue
 }
+
+func getEnvAsDuration(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if duration, err := time.ParseDuration(value); err == nil {
+			return duration
+		}
+	}
+	return defaultValue
+}
+
+// getEnvAsMap разбирает значение вида "key1=value1,key2=value2" и накладывает его
+// на значения по умолчанию: ключи, которых нет в переменной, сохраняют дефолт
+func getEnvAsMap(key string, defaultValue map[string]string) (map[string]string, error) {
+	result := make(map[string]string, len(defaultValue))
+	for name, val := range defaultValue {
+		result[name] = val
+	}
+
+	value := os.Getenv(key)
+	if value == "" {
+		return result, nil
+	}
+
+	for _, pair := range strings.Split(value, ",") {
+		name, val, ok := strings.Cut(pair, "=")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("%s: invalid pair %q (expected key=value)", key, strings.TrimSpace(pair))
+		}
+		result[name] = strings.TrimSpace(val)
+	}
+	return result, nil
+}
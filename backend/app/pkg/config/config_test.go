@@ -0,0 +1,62 @@
+package config
+
+import "testing"
+
+func TestGetEnvAsMapMergesDefaults(t *testing.T) {
+	defaults := map[string]string{"date_created": "correct"}
+	t.Setenv("TEST_RULES", " delivery_email = warn ,date_created=reject,payment_currency=correct")
+
+	got, err := getEnvAsMap("TEST_RULES", defaults)
+	if err != nil {
+		t.Fatalf("getEnvAsMap() error = %v", err)
+	}
+	want := map[string]string{"date_created": "reject", "delivery_email": "warn", "payment_currency": "correct"}
+	if len(got) != len(want) {
+		t.Fatalf("getEnvAsMap() = %v, want %v", got, want)
+	}
+	for name, value := range want {
+		if got[name] != value {
+			t.Errorf("getEnvAsMap()[%s] = %q, want %q", name, got[name], value)
+		}
+	}
+	if defaults["date_created"] != "correct" {
+		t.Error("getEnvAsMap() modified the default map")
+	}
+
+	// Правило, не указанное в переменной, сохраняет значение по умолчанию
+	t.Setenv("TEST_RULES", "delivery_email=warn")
+	got, err = getEnvAsMap("TEST_RULES", defaults)
+	if err != nil {
+		t.Fatalf("getEnvAsMap() error = %v", err)
+	}
+	if got["date_created"] != "correct" || got["delivery_email"] != "warn" {
+		t.Errorf("getEnvAsMap() = %v, want date_created kept", got)
+	}
+}
+
+func TestGetEnvAsMapRejectsMalformedPairs(t *testing.T) {
+	for _, value := range []string{"delivery_email", "date_created=correct,,", "=warn"} {
+		t.Setenv("TEST_RULES", value)
+		if got, err := getEnvAsMap("TEST_RULES", nil); err == nil {
+			t.Errorf("getEnvAsMap(%q) = %v, want error", value, got)
+		}
+	}
+}
+
+func TestLoadConfigInvalidValidationRules(t *testing.T) {
+	t.Setenv("VALIDATION_RULES", "delivery_email:warn")
+	if _, err := LoadConfig(); err == nil {
+		t.Error("LoadConfig() succeeded, want error")
+	}
+}
+
+func TestLoadConfigAdminAddr(t *testing.T) {
+	t.Setenv("ADMIN_ADDR", "")
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	if cfg.Server.AdminAddr != "" {
+		t.Errorf("AdminAddr = %q, want empty to disable the admin API", cfg.Server.AdminAddr)
+	}
+}
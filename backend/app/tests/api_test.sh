@@ -19,12 +19,17 @@ check_response() {
     local url=$1
     local expected_status=${2:-200}
     local description=$3
+    local body=$4 # Если указано, отправляется POST с этим телом
     
     echo -e "${YELLOW} Тестирование: $description${NC}"
     echo "URL: $url"
     
     # Выполняем запрос и сохраняем результат
-    response=$(curl -s -w "\n%{http_code}" "$url" 2>/dev/null)
+    if [ -n "$body" ]; then
+        response=$(curl -s -w "\n%{http_code}" -X POST -H "Content-Type: application/json" -d "$body" "$url" 2>/dev/null)
+    else
+        response=$(curl -s -w "\n%{http_code}" "$url" 2>/dev/null)
+    fi
     http_code=$(echo "$response" | tail -n1)
     # Извлекаем только JSON API ответ (содержит поле success, а не level)
     json_body=$(echo "$response" | sed '$d' | grep '^{' | grep -v '"level"' | tail -n1)
@@ -82,6 +87,8 @@ check_response "$API_BASE/orders/nonexistent_order" 404 "Получение не
 
 # Тест 4: Получение списка заказов
 check_response "$API_BASE/orders?limit=5" 200 "Получение списка заказов"
+check_response "$API_BASE/orders?limit=5&customer_id=test&from=2021-01-01" 200 "Список заказов с фильтрами"
+check_response "$API_BASE/orders?cursor=not-a-cursor" 400 "Некорректный курсор"
 
 # Тест 5: Статистика кеша
 check_response "$API_BASE/cache/stats" 200 "Статистика кеша"
@@ -89,6 +96,46 @@ check_response "$API_BASE/cache/stats" 200 "Статистика кеша"
 # Тест 6: Некорректный endpoint
 check_response "$API_BASE/invalid_endpoint" 404 "Некорректный endpoint"
 
+# Тест 7: Проверка заказа без сохранения (все нарушения в data.errors)
+INVALID_ORDER='{"order_uid":"validate_test","track_number":"WBILMTESTTRACK","customer_id":"test","date_created":"2021-11-26T06:22:19Z",
+"delivery":{"name":"Test","phone":"123","email":"not-an-email"},
+"payment":{"currency":"XXY","amount":100,"goods_total":50},
+"items":[{"chrt_id":1,"track_number":"OTHER","price":100,"sale":0,"total_price":100}]}'
+check_response "$API_BASE/orders/validate" 422 "Проверка невалидного заказа" "$INVALID_ORDER"
+check_response "$API_BASE/orders/validate" 422 "Проверка некорректного JSON" '{"order_uid":'
+
+# Тест 8: Создание заказа по HTTP (201 - новый заказ, 200 - повторная отправка)
+HTTP_ORDER_ID="http_test_$(date +%s)"
+HTTP_ORDER='{"order_uid":"'$HTTP_ORDER_ID'","track_number":"WBILMTESTTRACK","entry":"WBIL","customer_id":"test","date_created":"2021-11-26T06:22:19Z",
+"delivery":{"name":"Test Testov","phone":"+9720000000","zip":"2639809","city":"Kiryat Mozkin","address":"Ploshad Mira 15","region":"Kraiot","email":"test@gmail.com"},
+"payment":{"transaction":"'$HTTP_ORDER_ID'","currency":"USD","provider":"wbpay","amount":1817,"payment_dt":1637907727,"bank":"alpha","delivery_cost":1500,"goods_total":317,"custom_fee":0},
+"items":[{"chrt_id":9934930,"track_number":"WBILMTESTTRACK","price":453,"rid":"ab4219087a764ae0btest","name":"Mascaras","sale":30,"size":"0","total_price":317,"nm_id":2389212,"brand":"Vivienne Sabo","status":202}]}'
+check_response "$API_BASE/orders" 201 "Создание заказа" "$HTTP_ORDER"
+check_response "$API_BASE/orders" 200 "Повторная отправка того же заказа" "$HTTP_ORDER"
+check_response "$API_BASE/orders/$HTTP_ORDER_ID" 200 "Получение созданного заказа"
+
+# Тест 9: Поиск заказов по трек-номеру, клиенту и транзакции платежа
+check_response "$API_BASE/orders/by-track/WBILMTESTTRACK" 200 "Заказы по трек-номеру"
+check_response "$API_BASE/orders/by-track/NONEXISTENT_TRACK" 404 "Несуществующий трек-номер"
+check_response "$API_BASE/customers/test/orders?limit=5" 200 "Заказы клиента"
+check_response "$API_BASE/payments/$HTTP_ORDER_ID" 200 "Заказ по транзакции платежа"
+check_response "$API_BASE/payments/nonexistent_transaction" 404 "Несуществующая транзакция"
+
+# Тест 10: Полнотекстовый поиск
+check_response "$API_BASE/orders/search?q=Vivienne+Sabo&limit=5" 200 "Поиск заказов"
+check_response "$API_BASE/orders/search" 400 "Поиск без запроса"
+
+# Тест 11: Отчеты по продажам
+check_response "$API_BASE/analytics/revenue?interval=month&from=2021-01-01" 200 "Выручка по месяцам"
+check_response "$API_BASE/analytics/revenue?interval=year" 400 "Некорректный интервал"
+check_response "$API_BASE/analytics/delivery-services" 200 "Заказы по службам доставки"
+check_response "$API_BASE/analytics/top-brands?by=revenue&currency=USD" 200 "Бренды-лидеры"
+check_response "$API_BASE/analytics/top-products?limit=5" 200 "Товары-лидеры"
+check_response "$API_BASE/analytics/basket" 200 "Средний размер корзины"
+check_response "$API_BASE/analytics/discounts" 200 "Распределение скидок"
+check_response "$API_BASE/analytics/revenue?interval=month&reporting_currency=RUB" 200 "Выручка в валюте отчета"
+check_response "$API_BASE/analytics/revenue?reporting_currency=XYZ" 400 "Неизвестная валюта отчета"
+
 # Тест производительности
 echo -e "${YELLOW} Тест производительности (10 запросов)${NC}"
 echo "========================================"
@@ -131,7 +178,14 @@ echo -e "${GREEN} Тестирование API завершено!${NC}"
 echo ""
 echo -e "${BLUE} Краткая справка по API:${NC}"
 echo "• GET /api/v1/orders/{order_uid} - получить заказ по ID"
-echo "• GET /api/v1/orders?limit=N - получить список заказов"
+echo "• GET /api/v1/orders?limit=N&cursor=... - получить страницу списка заказов"
+echo "• GET /api/v1/orders/search?q=... - полнотекстовый поиск заказов"
+echo "• GET /api/v1/orders/by-track/{track_number} - заказы по трек-номеру"
+echo "• GET /api/v1/customers/{customer_id}/orders - заказы клиента"
+echo "• GET /api/v1/payments/{transaction} - заказ по транзакции платежа"
+echo "• POST /api/v1/orders - создать заказ"
+echo "• POST /api/v1/orders/validate - проверить заказ без сохранения"
+echo "• GET /api/v1/analytics/{report} - отчеты по продажам"
 echo "• GET /api/v1/cache/stats - статистика кеша"
 echo "• GET /api/v1/health - проверка здоровья сервиса"
 echo ""
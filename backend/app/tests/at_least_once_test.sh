@@ -0,0 +1,189 @@
+#!/bin/bash
+
+# Интеграционный тест доставки at-least-once.
+#
+# Заказы публикуются в локальный Kafka-совместимый брокер (Redpanda в Docker),
+# сервис принудительно завершается (kill -9) посреди обработки и запускается снова.
+# После перезапуска в БД должны оказаться все опубликованные заказы.
+#
+# Требования: docker, go; PostgreSQL из backend/database/docker-compose.yml запущен.
+# Запуск: make integration-test (или ./tests/at_least_once_test.sh из папки app)
+
+set -euo pipefail
+
+# Цвета для вывода
+RED='\033[0;31m'
+GREEN='\033[0;32m'
+YELLOW='\033[1;33m'
+BLUE='\033[0;34m'
+NC='\033[0m' # No Color
+
+SCRIPT_DIR=$(cd "$(dirname "$0")" && pwd)
+APP_DIR=$(dirname "$SCRIPT_DIR")
+
+ORDERS_COUNT=${ORDERS_COUNT:-2000}
+BROKER_IMAGE=${BROKER_IMAGE:-redpandadata/redpanda:v23.3.5}
+BROKER_CONTAINER="order_service_test_broker"
+BROKER_PORT=${BROKER_PORT:-19092}
+PG_CONTAINER=${PG_CONTAINER:-order_service_postgres}
+SERVER_PORT=${TEST_SERVER_PORT:-18081}
+TIMEOUT=${TIMEOUT:-180}
+
+RUN_ID=$(date +%s)
+TOPIC="orders-at-least-once-$RUN_ID"
+GROUP_ID="order-service-test-$RUN_ID"
+PREFIX="alo_${RUN_ID}_"
+SERVER_BIN="$APP_DIR/bin/order-service-integration"
+CTL_BIN="$APP_DIR/bin/ordersctl-integration"
+# День date_created тестовых заказов: после удаления заказов агрегаты аналитики за него пересчитываются
+ORDER_DAY="2021-11-26"
+SERVER_LOG="/tmp/order-service-at-least-once-$RUN_ID.log"
+SERVER_PID=""
+
+cleanup() {
+    if [ -n "$SERVER_PID" ]; then
+        kill -9 "$SERVER_PID" 2>/dev/null || true
+    fi
+    docker rm -f "$BROKER_CONTAINER" > /dev/null 2>&1 || true
+    psql_exec "DELETE FROM orders WHERE order_uid LIKE '${PREFIX}%'" > /dev/null 2>&1 || true
+    # Удаление заказа не меняет дневные агрегаты (миграция 008), убираем из них тестовые заказы
+    if [ -x "$CTL_BIN" ]; then
+        DB_HOST=localhost DB_PORT=5433 DB_USER=postgres DB_PASSWORD=postgres \
+            "$CTL_BIN" rollups backfill -from "$ORDER_DAY" -to "$ORDER_DAY" > /dev/null 2>&1 || true
+    fi
+}
+trap cleanup EXIT
+
+psql_exec() {
+    docker exec "$PG_CONTAINER" psql -U postgres -d order_service_db -tAc "$1"
+}
+
+count_orders() {
+    psql_exec "SELECT COUNT(*) FROM orders WHERE order_uid LIKE '${PREFIX}%'" | tr -d '[:space:]'
+}
+
+rpk() {
+    docker exec -i "$BROKER_CONTAINER" rpk "$@" -X brokers="localhost:$BROKER_PORT"
+}
+
+# Административный API отключен (ADMIN_ADDR пустой): его порт по умолчанию может быть занят
+# запущенным рядом dev-сервисом
+start_server() {
+    DB_HOST=localhost DB_PORT=5433 DB_USER=postgres DB_PASSWORD=postgres ADMIN_ADDR= \
+    KAFKA_BROKERS="localhost:$BROKER_PORT" KAFKA_TOPIC="$TOPIC" KAFKA_GROUP_ID="$GROUP_ID" \
+    KAFKA_DLQ_TOPIC="$TOPIC-dlq" SERVER_PORT="$SERVER_PORT" \
+        "$SERVER_BIN" >> "$SERVER_LOG" 2>&1 &
+    SERVER_PID=$!
+}
+
+# order_message выводит "ключ JSON" для rpk topic produce -f '%k %v\n'
+order_message() {
+    local uid="${PREFIX}$1"
+    local track="WBILTEST$1"
+    printf '%s {"order_uid":"%s","track_number":"%s","entry":"WBIL",' "$uid" "$uid" "$track"
+    printf '"delivery":{"name":"Test Testov","phone":"+79001234567","zip":"123456","city":"Moscow","address":"Lenina 1","region":"Moscow","email":"test@example.com"},'
+    printf '"payment":{"transaction":"%s","request_id":"","currency":"RUB","provider":"wbpay","amount":1100,"payment_dt":1637907727,"bank":"alpha","delivery_cost":100,"goods_total":1000,"custom_fee":0},' "$uid"
+    printf '"items":[{"chrt_id":9934930,"track_number":"%s","price":1000,"rid":"rid_%s","name":"Mascaras","sale":0,"size":"0","total_price":1000,"nm_id":2389212,"brand":"Vivienne Sabo","status":202}],' "$track" "$uid"
+    printf '"locale":"en","internal_signature":"","customer_id":"test","delivery_service":"meest","shardkey":"9","sm_id":99,"date_created":"%sT06:22:19Z","oof_shard":"1"}\n' "$ORDER_DAY"
+}
+
+echo -e "${BLUE} Интеграционный тест at-least-once доставки${NC}"
+echo "=================================="
+echo ""
+
+# Проверяем доступность PostgreSQL
+if ! psql_exec "SELECT 1" > /dev/null 2>&1; then
+    echo -e "${RED} PostgreSQL недоступен. Запустите инфраструктуру: cd ../database && docker-compose up -d${NC}"
+    exit 1
+fi
+
+# 1. Поднимаем локальный брокер
+echo -e "${YELLOW} Запуск брокера ($BROKER_IMAGE)...${NC}"
+docker rm -f "$BROKER_CONTAINER" > /dev/null 2>&1 || true
+docker run -d --name "$BROKER_CONTAINER" -p "$BROKER_PORT:$BROKER_PORT" "$BROKER_IMAGE" \
+    redpanda start --overprovisioned --smp 1 --memory 512M --reserve-memory 0M --node-id 0 --check=false \
+    --kafka-addr "PLAINTEXT://0.0.0.0:$BROKER_PORT" \
+    --advertise-kafka-addr "PLAINTEXT://localhost:$BROKER_PORT" > /dev/null
+
+for _ in $(seq 1 30); do
+    if rpk cluster health 2>/dev/null | grep -q "Healthy:.*true"; then
+        break
+    fi
+    sleep 1
+done
+rpk topic create "$TOPIC" -p 3 > /dev/null
+echo -e "${GREEN} Брокер запущен, топик $TOPIC создан${NC}"
+
+# 2. Публикуем заказы
+echo -e "${YELLOW} Публикация $ORDERS_COUNT заказов...${NC}"
+for i in $(seq 1 "$ORDERS_COUNT"); do
+    order_message "$i"
+done | rpk topic produce "$TOPIC" -f '%k %v\n' > /dev/null
+echo -e "${GREEN} Заказы опубликованы${NC}"
+
+# 3. Собираем и запускаем сервис
+echo -e "${YELLOW} Сборка сервиса...${NC}"
+(cd "$APP_DIR" && go build -o "$SERVER_BIN" ./cmd/server && go build -o "$CTL_BIN" ./cmd/ordersctl)
+
+start_server
+echo -e "${YELLOW} Сервис запущен (pid $SERVER_PID), ждем начала обработки...${NC}"
+
+# 4. Убиваем процесс посреди обработки
+deadline=$((SECONDS + TIMEOUT))
+while true; do
+    processed=$(count_orders)
+    if [ "$processed" -gt 0 ]; then
+        break
+    fi
+    if [ "$SECONDS" -ge "$deadline" ]; then
+        echo -e "${RED} Сервис не начал обработку за $TIMEOUT секунд, лог: $SERVER_LOG${NC}"
+        exit 1
+    fi
+    sleep 0.1
+done
+
+kill -9 "$SERVER_PID"
+wait "$SERVER_PID" 2>/dev/null || true
+SERVER_PID=""
+processed=$(count_orders)
+echo -e "${BLUE} Процесс убит (kill -9) после сохранения $processed из $ORDERS_COUNT заказов${NC}"
+
+if [ "$processed" -ge "$ORDERS_COUNT" ]; then
+    echo -e "${RED} Все заказы обработаны до остановки, увеличьте ORDERS_COUNT${NC}"
+    exit 1
+fi
+
+# 5. Перезапускаем и ждем обработки оставшихся заказов
+start_server
+echo -e "${YELLOW} Сервис перезапущен (pid $SERVER_PID), ждем обработки всех заказов...${NC}"
+
+deadline=$((SECONDS + TIMEOUT))
+while true; do
+    processed=$(count_orders)
+    if [ "$processed" -ge "$ORDERS_COUNT" ]; then
+        break
+    fi
+    if [ "$SECONDS" -ge "$deadline" ]; then
+        break
+    fi
+    sleep 1
+done
+
+# 6. Проверяем результат
+missing=$((ORDERS_COUNT - processed))
+echo ""
+echo "Опубликовано: $ORDERS_COUNT"
+echo "Сохранено:    $processed"
+
+if [ "$missing" -ne 0 ]; then
+    echo -e "${RED} Потеряно заказов: $missing, лог сервиса: $SERVER_LOG${NC}"
+    exit 1
+fi
+
+items=$(psql_exec "SELECT COUNT(*) FROM order_items WHERE order_uid LIKE '${PREFIX}%'" | tr -d '[:space:]')
+if [ "$items" -ne "$ORDERS_COUNT" ]; then
+    echo -e "${RED} Ожидалось $ORDERS_COUNT товаров, найдено $items${NC}"
+    exit 1
+fi
+
+echo -e "${GREEN} Все заказы сохранены после аварийного перезапуска${NC}"
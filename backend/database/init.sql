@@ -21,3 +21,27 @@ ALTER DEFAULT PRIVILEGES IN SCHEMA public GRANT ALL ON FUNCTIONS TO "user";
 
 -- Вставка тестовых данных
 \i /docker-entrypoint-initdb.d/migrations/002_insert_test_data.sql
+
+-- Хеш содержимого заказа для идемпотентной записи
+\i /docker-entrypoint-initdb.d/migrations/003_add_order_content_hash.sql
+
+-- История статусов товаров
+\i /docker-entrypoint-initdb.d/migrations/004_create_order_status_history.sql
+
+-- Предупреждения валидации заказов
+\i /docker-entrypoint-initdb.d/migrations/005_add_order_validation_warnings.sql
+
+-- Индекс для пагинации списка заказов
+\i /docker-entrypoint-initdb.d/migrations/006_add_orders_created_at_index.sql
+
+-- Полнотекстовый поиск заказов
+\i /docker-entrypoint-initdb.d/migrations/007_add_order_search.sql
+
+-- Дневные агрегаты для аналитики
+\i /docker-entrypoint-initdb.d/migrations/008_create_daily_rollups.sql
+
+-- Курсы валют для отчетов
+\i /docker-entrypoint-initdb.d/migrations/009_create_exchange_rates.sql
+
+-- Денежные суммы в BIGINT
+\i /docker-entrypoint-initdb.d/migrations/010_widen_money_columns.sql
@@ -3,16 +3,22 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"flag"
 	"fmt"
 	"log"
 	"math/rand"
+	"os"
+	"strconv"
 	"time"
 
+	"github.com/linkedin/goavro/v2"
 	"github.com/segmentio/kafka-go"
+	"google.golang.org/protobuf/encoding/protowire"
 )
 
 // KafkaOrderMessage структура сообщения заказа для Kafka
 type KafkaOrderMessage struct {
+	SchemaVersion     int                   `json:"schema_version"`
 	OrderUID          string                `json:"order_uid"`
 	TrackNumber       string                `json:"track_number"`
 	Entry             string                `json:"entry"`
@@ -66,7 +72,33 @@ type KafkaOrderItem struct {
 	Status      int    `json:"status"`
 }
 
+// OrderStatusChanged событие изменения статуса заказа от системы логистики
+type OrderStatusChanged struct {
+	EventType string             `json:"event_type"`
+	OrderUID  string             `json:"order_uid"`
+	Status    *int               `json:"status,omitempty"`
+	Items     []ItemStatusChange `json:"items,omitempty"`
+	ChangedAt string             `json:"changed_at"`
+}
+
+type ItemStatusChange struct {
+	ChrtID int64 `json:"chrt_id"`
+	Status int   `json:"status"`
+}
+
+// schemaVersion - версия схемы сообщений о заказах, которую понимает consumer
+const schemaVersion = 1
+
 func main() {
+	format := flag.String("format", "json", "формат сообщений о заказах: json, protobuf или avro")
+	avroSchema := flag.String("avro-schema", "../app/internal/kafka/schemas/order_v1.avsc", "путь к Avro-схеме заказа")
+	flag.Parse()
+
+	encoder, err := newOrderEncoder(*format, *avroSchema)
+	if err != nil {
+		log.Fatalf("Ошибка настройки формата %s: %v", *format, err)
+	}
+
 	// Настройка Kafka writer
 	writer := kafka.NewWriter(kafka.WriterConfig{
 		Brokers:      []string{"localhost:9092"},
@@ -83,14 +115,14 @@ func main() {
 	brands := []string{"Nike", "Adidas", "Puma", "Reebok", "New Balance"}
 	products := []string{"Кроссовки", "Футболка", "Шорты", "Куртка", "Джинсы"}
 	
-	log.Println("Kafka Producer запущен")
+	log.Printf("Kafka Producer запущен (формат: %s)", encoder.contentType)
 	log.Println("Отправка тестовых сообщений каждые 5 секунд...")
 	log.Println("Нажмите Ctrl+C для остановки")
 
 	// Отправляем стандартный тестовый заказ
 	log.Println("Отправка тестового заказа...")
 	testOrder := createTestOrder()
-	if err := sendOrder(writer, testOrder); err != nil {
+	if err := sendOrder(writer, encoder, testOrder); err != nil {
 		log.Printf("Ошибка отправки тестового заказа: %v", err)
 	} else {
 		log.Printf("Тестовый заказ отправлен: %s", testOrder.OrderUID)
@@ -101,12 +133,20 @@ func main() {
 		time.Sleep(5 * time.Second)
 		
 		order := generateRandomOrder(names, cities, brands, products)
-		if err := sendOrder(writer, order); err != nil {
+		if err := sendOrder(writer, encoder, order); err != nil {
 			log.Printf("Ошибка отправки заказа %s: %v", order.OrderUID, err)
 		} else {
 			log.Printf("Заказ отправлен: %s (клиент: %s, товаров: %d)", 
 				order.OrderUID, order.CustomerID, len(order.Items))
 		}
+
+		// Следом за заказом отправляем изменение статуса, как это делает система логистики
+		change := generateStatusChange(order)
+		if err := sendStatusChange(writer, change); err != nil {
+			log.Printf("Ошибка отправки статуса заказа %s: %v", change.OrderUID, err)
+		} else {
+			log.Printf("Статус заказа отправлен: %s (статус: %d)", change.OrderUID, *change.Status)
+		}
 	}
 }
 
@@ -178,22 +218,6 @@ func generateRandomOrder(names, cities, brands, products []string) KafkaOrderMes
 		Email:   fmt.Sprintf("user%d@test.com", rand.Intn(1000)),
 	}
 
-	// Случайный платеж
-	goodsTotal := rand.Intn(10000) + 1000
-	deliveryCost := rand.Intn(500) + 200
-	payment := KafkaPayment{
-		Transaction:  orderUID,
-		RequestID:    "",
-		Currency:     "RUB",
-		Provider:     "wbpay",
-		Amount:       goodsTotal + deliveryCost,
-		PaymentDt:    time.Now().Unix(),
-		Bank:         "sberbank",
-		DeliveryCost: deliveryCost,
-		GoodsTotal:   goodsTotal,
-		CustomFee:    0,
-	}
-
 	// Случайные товары (1-3 товара)
 	itemCount := rand.Intn(3) + 1
 	items := make([]KafkaOrderItem, itemCount)
@@ -218,6 +242,25 @@ func generateRandomOrder(names, cities, brands, products []string) KafkaOrderMes
 		}
 	}
 
+	// Случайный платеж, стоимость товаров равна сумме их итоговых цен
+	goodsTotal := 0
+	for _, item := range items {
+		goodsTotal += item.TotalPrice
+	}
+	deliveryCost := rand.Intn(500) + 200
+	payment := KafkaPayment{
+		Transaction:  orderUID,
+		RequestID:    "",
+		Currency:     "RUB",
+		Provider:     "wbpay",
+		Amount:       goodsTotal + deliveryCost,
+		PaymentDt:    time.Now().Unix(),
+		Bank:         "sberbank",
+		DeliveryCost: deliveryCost,
+		GoodsTotal:   goodsTotal,
+		CustomFee:    0,
+	}
+
 	return KafkaOrderMessage{
 		OrderUID:          orderUID,
 		TrackNumber:       trackNumber,
@@ -236,15 +279,186 @@ func generateRandomOrder(names, cities, brands, products []string) KafkaOrderMes
 	}
 }
 
-func sendOrder(writer *kafka.Writer, order KafkaOrderMessage) error {
-	data, err := json.Marshal(order)
+func sendOrder(writer *kafka.Writer, encoder *orderEncoder, order KafkaOrderMessage) error {
+	order.SchemaVersion = schemaVersion
+	data, err := encoder.encode(order)
 	if err != nil {
-		return fmt.Errorf("failed to marshal order: %w", err)
+		return fmt.Errorf("failed to encode order: %w", err)
 	}
 
 	message := kafka.Message{
 		Key:   []byte(order.OrderUID),
 		Value: data,
+		Headers: []kafka.Header{
+			{Key: "content-type", Value: []byte(encoder.contentType)},
+			{Key: "schema-version", Value: []byte(strconv.Itoa(schemaVersion))},
+		},
+	}
+
+	return writer.WriteMessages(context.Background(), message)
+}
+
+// orderEncoder кодирует заказ в один из форматов, которые понимает consumer
+type orderEncoder struct {
+	contentType string
+	encode      func(order KafkaOrderMessage) ([]byte, error)
+}
+
+func newOrderEncoder(format, avroSchemaPath string) (*orderEncoder, error) {
+	switch format {
+	case "json":
+		return &orderEncoder{
+			contentType: "application/json",
+			encode: func(order KafkaOrderMessage) ([]byte, error) {
+				return json.Marshal(order)
+			},
+		}, nil
+
+	case "protobuf":
+		return &orderEncoder{
+			contentType: "application/x-protobuf",
+			encode: func(order KafkaOrderMessage) ([]byte, error) {
+				return encodeOrderProtobuf(order), nil
+			},
+		}, nil
+
+	case "avro":
+		schema, err := os.ReadFile(avroSchemaPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read avro schema: %w", err)
+		}
+		codec, err := goavro.NewCodec(string(schema))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse avro schema: %w", err)
+		}
+		return &orderEncoder{
+			contentType: "application/avro",
+			encode: func(order KafkaOrderMessage) ([]byte, error) {
+				// JSON-представление заказа совпадает с текстовой формой Avro-схемы
+				textual, err := json.Marshal(order)
+				if err != nil {
+					return nil, err
+				}
+				native, _, err := codec.NativeFromTextual(textual)
+				if err != nil {
+					return nil, err
+				}
+				return codec.BinaryFromNative(nil, native)
+			},
+		}, nil
+
+	default:
+		return nil, fmt.Errorf("unknown format %q", format)
+	}
+}
+
+// encodeOrderProtobuf кодирует заказ в сообщение orders.v1.Order
+// (app/internal/kafka/schemas/order_v1.proto). Как принято в proto3,
+// поля со значениями по умолчанию не записываются
+func encodeOrderProtobuf(order KafkaOrderMessage) []byte {
+	var b []byte
+	b = appendProtoString(b, 1, order.OrderUID)
+	b = appendProtoString(b, 2, order.TrackNumber)
+	b = appendProtoString(b, 3, order.Entry)
+
+	var delivery []byte
+	delivery = appendProtoString(delivery, 1, order.Delivery.Name)
+	delivery = appendProtoString(delivery, 2, order.Delivery.Phone)
+	delivery = appendProtoString(delivery, 3, order.Delivery.Zip)
+	delivery = appendProtoString(delivery, 4, order.Delivery.City)
+	delivery = appendProtoString(delivery, 5, order.Delivery.Address)
+	delivery = appendProtoString(delivery, 6, order.Delivery.Region)
+	delivery = appendProtoString(delivery, 7, order.Delivery.Email)
+	b = appendProtoMessage(b, 4, delivery)
+
+	var payment []byte
+	payment = appendProtoString(payment, 1, order.Payment.Transaction)
+	payment = appendProtoString(payment, 2, order.Payment.RequestID)
+	payment = appendProtoString(payment, 3, order.Payment.Currency)
+	payment = appendProtoString(payment, 4, order.Payment.Provider)
+	payment = appendProtoInt(payment, 5, int64(order.Payment.Amount))
+	payment = appendProtoInt(payment, 6, order.Payment.PaymentDt)
+	payment = appendProtoString(payment, 7, order.Payment.Bank)
+	payment = appendProtoInt(payment, 8, int64(order.Payment.DeliveryCost))
+	payment = appendProtoInt(payment, 9, int64(order.Payment.GoodsTotal))
+	payment = appendProtoInt(payment, 10, int64(order.Payment.CustomFee))
+	b = appendProtoMessage(b, 5, payment)
+
+	for _, item := range order.Items {
+		var encoded []byte
+		encoded = appendProtoInt(encoded, 1, item.ChrtID)
+		encoded = appendProtoString(encoded, 2, item.TrackNumber)
+		encoded = appendProtoInt(encoded, 3, int64(item.Price))
+		encoded = appendProtoString(encoded, 4, item.Rid)
+		encoded = appendProtoString(encoded, 5, item.Name)
+		encoded = appendProtoInt(encoded, 6, int64(item.Sale))
+		encoded = appendProtoString(encoded, 7, item.Size)
+		encoded = appendProtoInt(encoded, 8, int64(item.TotalPrice))
+		encoded = appendProtoInt(encoded, 9, item.NmID)
+		encoded = appendProtoString(encoded, 10, item.Brand)
+		encoded = appendProtoInt(encoded, 11, int64(item.Status))
+		b = appendProtoMessage(b, 6, encoded)
+	}
+
+	b = appendProtoString(b, 7, order.Locale)
+	b = appendProtoString(b, 8, order.InternalSignature)
+	b = appendProtoString(b, 9, order.CustomerID)
+	b = appendProtoString(b, 10, order.DeliveryService)
+	b = appendProtoString(b, 11, order.Shardkey)
+	b = appendProtoInt(b, 12, int64(order.SmID))
+	b = appendProtoString(b, 13, order.DateCreated)
+	b = appendProtoString(b, 14, order.OofShard)
+	b = appendProtoInt(b, 15, int64(order.SchemaVersion))
+	return b
+}
+
+func appendProtoString(b []byte, num protowire.Number, value string) []byte {
+	if value == "" {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendString(b, value)
+}
+
+func appendProtoInt(b []byte, num protowire.Number, value int64) []byte {
+	if value == 0 {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.VarintType)
+	return protowire.AppendVarint(b, uint64(value))
+}
+
+func appendProtoMessage(b []byte, num protowire.Number, message []byte) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, message)
+}
+
+func generateStatusChange(order KafkaOrderMessage) OrderStatusChanged {
+	statuses := []int{202, 203, 204, 205}
+	status := statuses[rand.Intn(len(statuses))]
+
+	return OrderStatusChanged{
+		EventType: "order_status_changed",
+		OrderUID:  order.OrderUID,
+		Status:    &status,
+		ChangedAt: time.Now().Format(time.RFC3339),
+	}
+}
+
+func sendStatusChange(writer *kafka.Writer, change OrderStatusChanged) error {
+	data, err := json.Marshal(change)
+	if err != nil {
+		return fmt.Errorf("failed to marshal status change: %w", err)
+	}
+
+	// Ключ совпадает с ключом заказа, поэтому событие попадает в ту же партицию после заказа.
+	// События статуса передаются только в JSON
+	message := kafka.Message{
+		Key:   []byte(change.OrderUID),
+		Value: data,
+		Headers: []kafka.Header{
+			{Key: "content-type", Value: []byte("application/json")},
+		},
 	}
 
 	return writer.WriteMessages(context.Background(), message)
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Бинарные формы заказа, которые декодеры consumer'а проверяют в app/internal/kafka.
+// После изменения кодировщиков или схем они перезаписываются: go test -run TestEncodeOrder -update
+var update = flag.Bool("update", false, "перезаписать testdata consumer'а результатом кодировщиков")
+
+const consumerTestdata = "../app/internal/kafka/testdata"
+
+func loadTestOrder(t *testing.T) KafkaOrderMessage {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(consumerTestdata, "order_v1.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var order KafkaOrderMessage
+	if err := json.Unmarshal(data, &order); err != nil {
+		t.Fatal(err)
+	}
+	return order
+}
+
+func TestEncodeOrderMatchesConsumerTestdata(t *testing.T) {
+	order := loadTestOrder(t)
+	tests := []struct {
+		format string
+		file   string
+	}{
+		{format: "protobuf", file: "order_v1.pb"},
+		{format: "avro", file: "order_v1.avro"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			encoder, err := newOrderEncoder(tt.format, "../app/internal/kafka/schemas/order_v1.avsc")
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := encoder.encode(order)
+			if err != nil {
+				t.Fatalf("encode() error = %v", err)
+			}
+
+			path := filepath.Join(consumerTestdata, tt.file)
+			if *update {
+				if err := os.WriteFile(path, got, 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("encode() differs from %s, which the consumer decodes; rerun with -update and check app/internal/kafka tests", tt.file)
+			}
+		})
+	}
+}
@@ -0,0 +1,7 @@
+-- Миграция для идемпотентной записи заказов
+-- Версия: 003
+-- Описание: Хеш содержимого заказа для обнаружения повторной доставки с другими данными
+
+ALTER TABLE orders ADD COLUMN content_hash VARCHAR(64);
+
+COMMENT ON COLUMN orders.content_hash IS 'SHA-256 бизнес-данных заказа на момент записи (NULL для заказов, сохраненных до миграции)';
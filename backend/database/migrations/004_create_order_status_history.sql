@@ -0,0 +1,25 @@
+-- Миграция для истории статусов
+-- Версия: 004
+-- Описание: История изменений статусов товаров заказа
+
+CREATE TABLE order_status_history (
+    id BIGSERIAL PRIMARY KEY,                     -- Автоинкрементный ID
+    order_uid VARCHAR(255) NOT NULL REFERENCES orders(order_uid) ON DELETE CASCADE,
+    chrt_id BIGINT NOT NULL,                      -- ID характеристики товара
+    old_status INTEGER,                           -- Предыдущий статус (NULL при создании заказа)
+    new_status INTEGER NOT NULL,                  -- Новый статус
+    source VARCHAR(50) NOT NULL,                  -- Источник изменения: created, overwrite, status_event
+    changed_at TIMESTAMP WITH TIME ZONE NOT NULL, -- Время изменения
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP -- Время записи в БД
+);
+
+CREATE INDEX idx_order_status_history_order_uid ON order_status_history(order_uid, changed_at);
+
+-- Начальные статусы заказов, сохраненных до появления истории
+INSERT INTO order_status_history (order_uid, chrt_id, old_status, new_status, source, changed_at)
+SELECT i.order_uid, i.chrt_id, NULL, i.status, 'created', o.date_created
+FROM order_items i
+JOIN orders o ON o.order_uid = i.order_uid
+ORDER BY i.id;
+
+COMMENT ON TABLE order_status_history IS 'История изменений статусов товаров заказа';
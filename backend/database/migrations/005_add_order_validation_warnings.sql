@@ -0,0 +1,7 @@
+-- Миграция для предупреждений валидации
+-- Версия: 005
+-- Описание: Нарушения правил валидации, с которыми заказ был принят (политики warn и correct)
+
+ALTER TABLE orders ADD COLUMN validation_warnings JSONB;
+
+COMMENT ON COLUMN orders.validation_warnings IS 'Предупреждения валидации: [{rule, field, message, action}] (NULL, если нарушений не было)';
@@ -0,0 +1,5 @@
+-- Миграция для пагинации списка заказов
+-- Версия: 006
+-- Описание: Индекс для keyset-пагинации GET /api/v1/orders по (created_at, order_uid)
+
+CREATE INDEX idx_orders_created_at_order_uid ON orders(created_at DESC, order_uid DESC);
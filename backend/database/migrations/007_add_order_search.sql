@@ -0,0 +1,87 @@
+-- Миграция для полнотекстового поиска заказов
+-- Версия: 007
+-- Описание: Поисковый документ заказа (товары, получатель, адрес, email) и GIN индекс для GET /api/v1/orders/search.
+-- Используется конфигурация 'simple' без стемминга: в документе в основном имена, бренды и города
+-- на разных языках, которые словари русского и английского языков только искажают
+
+ALTER TABLE orders ADD COLUMN search_vector TSVECTOR;
+
+-- Поисковый документ заказа. Веса: A - название и бренд товара, имя получателя;
+-- B - город и адрес; C - email
+CREATE FUNCTION order_search_vector(uid VARCHAR) RETURNS TSVECTOR AS $$
+    SELECT
+        setweight(to_tsvector('simple', COALESCE(
+            (SELECT string_agg(concat_ws(' ', i.name, i.brand), ' ') FROM order_items i WHERE i.order_uid = uid), '')), 'A') ||
+        setweight(to_tsvector('simple', COALESCE(
+            (SELECT d.name FROM deliveries d WHERE d.order_uid = uid LIMIT 1), '')), 'A') ||
+        setweight(to_tsvector('simple', COALESCE(
+            (SELECT concat_ws(' ', d.city, d.address) FROM deliveries d WHERE d.order_uid = uid LIMIT 1), '')), 'B') ||
+        setweight(to_tsvector('simple', COALESCE(
+            (SELECT d.email FROM deliveries d WHERE d.order_uid = uid LIMIT 1), '')), 'C')
+$$ LANGUAGE SQL STABLE;
+
+-- Пересчитывает документы заказов, товары или доставка которых добавлены или удалены.
+-- Триггер уровня оператора: многострочная вставка товаров пачки заказов (CreateOrders) пересчитывает
+-- документ каждого заказа один раз, а не на каждый товар
+CREATE FUNCTION refresh_order_search_vectors() RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP = 'INSERT' THEN
+        UPDATE orders SET search_vector = order_search_vector(order_uid)
+        WHERE order_uid IN (SELECT DISTINCT order_uid FROM changed_rows);
+    ELSE
+        UPDATE orders SET search_vector = order_search_vector(order_uid)
+        WHERE order_uid IN (SELECT DISTINCT order_uid FROM removed_rows);
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Пересчитывает документ заказа при изменении текстовых полей товара или доставки. Такие изменения
+-- единичны, поэтому достаточно построчного триггера; столбцы UPDATE OF и таблицы переходов
+-- в одном триггере PostgreSQL не допускает
+CREATE FUNCTION refresh_order_search_vector() RETURNS TRIGGER AS $$
+BEGIN
+    UPDATE orders SET search_vector = order_search_vector(OLD.order_uid) WHERE order_uid = OLD.order_uid;
+    IF NEW.order_uid IS DISTINCT FROM OLD.order_uid THEN
+        UPDATE orders SET search_vector = order_search_vector(NEW.order_uid) WHERE order_uid = NEW.order_uid;
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Таблицы переходов нельзя задать для нескольких событий сразу, поэтому INSERT и DELETE - отдельные триггеры
+CREATE TRIGGER trg_order_items_search_vector_insert
+    AFTER INSERT ON order_items
+    REFERENCING NEW TABLE AS changed_rows
+    FOR EACH STATEMENT EXECUTE FUNCTION refresh_order_search_vectors();
+
+CREATE TRIGGER trg_order_items_search_vector_delete
+    AFTER DELETE ON order_items
+    REFERENCING OLD TABLE AS removed_rows
+    FOR EACH STATEMENT EXECUTE FUNCTION refresh_order_search_vectors();
+
+-- Изменение статуса товара не затрагивает документ, поэтому UPDATE отслеживается только для текстовых полей
+CREATE TRIGGER trg_order_items_search_vector_update
+    AFTER UPDATE OF order_uid, name, brand ON order_items
+    FOR EACH ROW EXECUTE FUNCTION refresh_order_search_vector();
+
+CREATE TRIGGER trg_deliveries_search_vector_insert
+    AFTER INSERT ON deliveries
+    REFERENCING NEW TABLE AS changed_rows
+    FOR EACH STATEMENT EXECUTE FUNCTION refresh_order_search_vectors();
+
+CREATE TRIGGER trg_deliveries_search_vector_delete
+    AFTER DELETE ON deliveries
+    REFERENCING OLD TABLE AS removed_rows
+    FOR EACH STATEMENT EXECUTE FUNCTION refresh_order_search_vectors();
+
+CREATE TRIGGER trg_deliveries_search_vector_update
+    AFTER UPDATE OF order_uid, name, city, address, email ON deliveries
+    FOR EACH ROW EXECUTE FUNCTION refresh_order_search_vector();
+
+-- Документы заказов, сохраненных до появления поиска
+UPDATE orders SET search_vector = order_search_vector(order_uid);
+
+CREATE INDEX idx_orders_search_vector ON orders USING GIN (search_vector);
+
+COMMENT ON COLUMN orders.search_vector IS 'Поисковый документ заказа, поддерживается триггерами на order_items и deliveries';
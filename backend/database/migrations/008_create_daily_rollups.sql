@@ -0,0 +1,109 @@
+-- Миграция для агрегатов аналитики
+-- Версия: 008
+-- Описание: Дневные агрегаты продаж для GET /api/v1/analytics/*. Обновляются в той же транзакции,
+-- что и запись заказа; суммы в минимальных единицах валюты платежа, день - дата date_created в UTC.
+-- Миграция заполняет агрегаты по уже сохраненным заказам (в том числе тестовым из 002);
+-- позже их можно пересчитать командой: ordersctl rollups backfill
+
+-- Заказы: день × служба доставки × регион × валюта
+CREATE TABLE order_daily_rollups (
+    day DATE NOT NULL,
+    delivery_service VARCHAR(100) NOT NULL,
+    region VARCHAR(100) NOT NULL,                 -- deliveries.region, пустая строка если не указан
+    currency VARCHAR(10) NOT NULL,
+    orders BIGINT NOT NULL DEFAULT 0,             -- Число заказов
+    items BIGINT NOT NULL DEFAULT 0,              -- Число товаров в заказах
+    amount BIGINT NOT NULL DEFAULT 0,             -- Сумма payments.amount
+    goods_total BIGINT NOT NULL DEFAULT 0,        -- Сумма payments.goods_total
+    delivery_cost BIGINT NOT NULL DEFAULT 0,      -- Сумма payments.delivery_cost
+    PRIMARY KEY (day, delivery_service, region, currency)
+);
+
+-- Товары по брендам: день × служба доставки × регион × бренд × валюта
+CREATE TABLE brand_daily_rollups (
+    day DATE NOT NULL,
+    delivery_service VARCHAR(100) NOT NULL,
+    region VARCHAR(100) NOT NULL,
+    brand VARCHAR(255) NOT NULL,
+    currency VARCHAR(10) NOT NULL,
+    units BIGINT NOT NULL DEFAULT 0,              -- Число проданных единиц (строк order_items)
+    revenue BIGINT NOT NULL DEFAULT 0,            -- Сумма total_price
+    discount BIGINT NOT NULL DEFAULT 0,           -- Сумма price - total_price
+    orders BIGINT NOT NULL DEFAULT 0,             -- Число заказов с товарами бренда
+    PRIMARY KEY (day, delivery_service, region, brand, currency)
+);
+
+-- Товары по артикулам: день × nm_id × валюта
+CREATE TABLE product_daily_rollups (
+    day DATE NOT NULL,
+    nm_id BIGINT NOT NULL,
+    currency VARCHAR(10) NOT NULL,
+    name VARCHAR(255) NOT NULL,                   -- Последнее известное название
+    brand VARCHAR(255) NOT NULL,                  -- Последний известный бренд
+    units BIGINT NOT NULL DEFAULT 0,
+    revenue BIGINT NOT NULL DEFAULT 0,
+    orders BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (day, nm_id, currency)
+);
+
+-- Товары по размеру скидки: день × диапазон скидки × валюта
+CREATE TABLE sale_daily_rollups (
+    day DATE NOT NULL,
+    sale_from INTEGER NOT NULL,                   -- Начало диапазона: 0 (без скидки), 1, 11, ..., 91
+    currency VARCHAR(10) NOT NULL,
+    units BIGINT NOT NULL DEFAULT 0,
+    revenue BIGINT NOT NULL DEFAULT 0,
+    discount BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (day, sale_from, currency)
+);
+
+-- Заполнение по сохраненным заказам: те же запросы, что rollupStatements в
+-- backend/app/internal/database/rollup.go, со знаком 1 и условием TRUE
+INSERT INTO order_daily_rollups (day, delivery_service, region, currency,
+    orders, items, amount, goods_total, delivery_cost)
+SELECT (COALESCE(o.date_created, o.created_at) AT TIME ZONE 'UTC')::date, o.delivery_service,
+    COALESCE(d.region, ''), p.currency,
+    COUNT(*), SUM((SELECT COUNT(*) FROM order_items i WHERE i.order_uid = o.order_uid)),
+    SUM(p.amount), SUM(p.goods_total), SUM(p.delivery_cost)
+FROM orders o
+JOIN payments p ON p.order_uid = o.order_uid
+LEFT JOIN deliveries d ON d.order_uid = o.order_uid
+WHERE TRUE
+GROUP BY 1, 2, 3, 4;
+
+INSERT INTO brand_daily_rollups (day, delivery_service, region, brand, currency,
+    units, revenue, discount, orders)
+SELECT (COALESCE(o.date_created, o.created_at) AT TIME ZONE 'UTC')::date, o.delivery_service,
+    COALESCE(d.region, ''), i.brand, p.currency,
+    COUNT(*), SUM(i.total_price), SUM(i.price - i.total_price), COUNT(DISTINCT o.order_uid)
+FROM orders o
+JOIN payments p ON p.order_uid = o.order_uid
+JOIN order_items i ON i.order_uid = o.order_uid
+LEFT JOIN deliveries d ON d.order_uid = o.order_uid
+WHERE TRUE
+GROUP BY 1, 2, 3, 4, 5;
+
+INSERT INTO product_daily_rollups (day, nm_id, currency, name, brand, units, revenue, orders)
+SELECT (COALESCE(o.date_created, o.created_at) AT TIME ZONE 'UTC')::date, i.nm_id, p.currency,
+    MAX(i.name), MAX(i.brand),
+    COUNT(*), SUM(i.total_price), COUNT(DISTINCT o.order_uid)
+FROM orders o
+JOIN payments p ON p.order_uid = o.order_uid
+JOIN order_items i ON i.order_uid = o.order_uid
+WHERE TRUE
+GROUP BY 1, 2, 3;
+
+INSERT INTO sale_daily_rollups (day, sale_from, currency, units, revenue, discount)
+SELECT (COALESCE(o.date_created, o.created_at) AT TIME ZONE 'UTC')::date,
+    CASE WHEN COALESCE(i.sale, 0) <= 0 THEN 0 ELSE LEAST((i.sale - 1) / 10, 9) * 10 + 1 END,
+    p.currency, COUNT(*), SUM(i.total_price), SUM(i.price - i.total_price)
+FROM orders o
+JOIN payments p ON p.order_uid = o.order_uid
+JOIN order_items i ON i.order_uid = o.order_uid
+WHERE TRUE
+GROUP BY 1, 2, 3;
+
+COMMENT ON TABLE order_daily_rollups IS 'Дневные агрегаты заказов для аналитики';
+COMMENT ON TABLE brand_daily_rollups IS 'Дневные агрегаты продаж по брендам для аналитики';
+COMMENT ON TABLE product_daily_rollups IS 'Дневные агрегаты продаж по артикулам для аналитики';
+COMMENT ON TABLE sale_daily_rollups IS 'Дневные агрегаты продаж по размеру скидки для аналитики';
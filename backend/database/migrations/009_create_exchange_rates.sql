@@ -0,0 +1,19 @@
+-- Миграция для курсов валют
+-- Версия: 009
+-- Описание: Курсы валют к базовой валюте сервиса (EXCHANGE_RATES_BASE, по умолчанию RUB) с датой начала действия.
+-- Используются для перевода сумм отчетов в валюту reporting_currency, если не задан EXCHANGE_RATES_FILE
+
+CREATE TABLE exchange_rates (
+    currency VARCHAR(10) NOT NULL,                -- Код валюты ISO 4217
+    rate NUMERIC(24, 10) NOT NULL CHECK (rate > 0), -- 1 единица валюты = rate единиц базовой валюты
+    effective_from DATE NOT NULL,                 -- Курс действует с этого дня до следующего курса валюты
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (currency, effective_from)
+);
+
+-- Пример курсов для тестовых данных
+INSERT INTO exchange_rates (currency, rate, effective_from) VALUES
+    ('USD', 74.5, '2021-01-01'),
+    ('EUR', 84.0, '2021-01-01');
+
+COMMENT ON TABLE exchange_rates IS 'Курсы валют к базовой валюте сервиса';
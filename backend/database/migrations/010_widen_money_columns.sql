@@ -0,0 +1,14 @@
+-- Миграция для денежных сумм
+-- Версия: 010
+-- Описание: Суммы платежей и цены товаров переводятся в BIGINT, чтобы они вмещали любую сумму models.Money (int64)
+-- в минимальных единицах валюты, в том числе в валютах без дробной части
+
+ALTER TABLE payments
+    ALTER COLUMN amount TYPE BIGINT,
+    ALTER COLUMN delivery_cost TYPE BIGINT,
+    ALTER COLUMN goods_total TYPE BIGINT,
+    ALTER COLUMN custom_fee TYPE BIGINT;
+
+ALTER TABLE order_items
+    ALTER COLUMN price TYPE BIGINT,
+    ALTER COLUMN total_price TYPE BIGINT;